@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -56,28 +58,36 @@ func startServer() {
 		logging.Logger().Info("===> Authelia is running in development mode. <===")
 	}
 
+	storageProvider, err := storage.NewProvider(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	var userProvider authentication.UserProvider
 
 	switch {
 	case config.AuthenticationBackend.File != nil:
-		userProvider = authentication.NewFileUserProvider(config.AuthenticationBackend.File)
-	case config.AuthenticationBackend.Ldap != nil:
-		userProvider = authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap)
-	default:
-		log.Fatalf("Unrecognized authentication backend")
-	}
+		fileUserProvider := authentication.NewFileUserProvider(config.AuthenticationBackend.File)
 
-	var storageProvider storage.Provider
+		if err := fileUserProvider.Watch(); err != nil {
+			logging.Logger().Errorf("Unable to watch the users database, send SIGHUP to reload it: %v", err)
+		}
 
-	switch {
-	case config.Storage.PostgreSQL != nil:
-		storageProvider = storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL)
-	case config.Storage.MySQL != nil:
-		storageProvider = storage.NewMySQLProvider(*config.Storage.MySQL)
-	case config.Storage.Local != nil:
-		storageProvider = storage.NewSQLiteProvider(config.Storage.Local.Path)
+		reloadOnHangup(fileUserProvider)
+
+		userProvider = fileUserProvider
+	case config.AuthenticationBackend.Ldap != nil:
+		ldapUserProvider, err := authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		userProvider = ldapUserProvider
+	case config.AuthenticationBackend.SQL != nil:
+		// The users are read through the connections of the storage provider.
+		userProvider = authentication.NewSQLUserProvider(config.AuthenticationBackend.SQL, storageProvider)
 	default:
-		log.Fatalf("Unrecognized storage backend")
+		log.Fatalf("Unrecognized authentication backend")
 	}
 
 	var notifier notification.Notifier
@@ -103,6 +113,8 @@ func startServer() {
 	sessionProvider := session.NewProvider(config.Session)
 	regulator := regulation.NewRegulator(config.Regulation, storageProvider, clock)
 
+	storage.NewJanitor(*config.Storage.Retention, storageProvider, clock).Start()
+
 	providers := middlewares.Providers{
 		Authorizer:      authorizer,
 		UserProvider:    userProvider,
@@ -114,6 +126,20 @@ func startServer() {
 	server.StartServer(*config, providers)
 }
 
+// reloadOnHangup reloads the users database each time the process receives SIGHUP.
+func reloadOnHangup(provider *authentication.FileUserProvider) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			if err := provider.Reload(); err != nil {
+				logging.Logger().Errorf("Unable to reload the users database, the users previously loaded are kept: %v", err)
+			}
+		}
+	}()
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use: "authelia",
@@ -133,7 +159,7 @@ func main() {
 	}
 
 	rootCmd.AddCommand(versionCmd, commands.HashPasswordCmd,
-		commands.ValidateConfigCmd, commands.CertificatesCmd)
+		commands.ValidateConfigCmd, commands.CertificatesCmd, commands.RegulationCmd, commands.StorageCmd, commands.UsersCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
@@ -1,6 +1,7 @@
 package authentication
 
 import (
+	"crypto/tls"
 	"errors"
 )
 
@@ -23,10 +24,12 @@ const (
 	U2F = "u2f"
 	// Push Method using Duo application to receive push notifications.
 	Push = "mobile_push"
+	// Webauthn Method using security keys through the Webauthn protocol.
+	Webauthn = "webauthn"
 )
 
 // PossibleMethods is the set of all possible 2FA methods.
-var PossibleMethods = []string{TOTP, U2F, Push}
+var PossibleMethods = []string{TOTP, U2F, Push, Webauthn}
 
 // CryptAlgo the crypt representation of an algorithm used in the prefix of the hash.
 type CryptAlgo string
@@ -36,6 +39,16 @@ const (
 	HashingAlgorithmArgon2id CryptAlgo = argon2id
 	// HashingAlgorithmSHA512 SHA512 hash identifier.
 	HashingAlgorithmSHA512 CryptAlgo = "6"
+	// HashingAlgorithmBcrypt bcrypt hash identifier, the $2b$ and $2y$ variants are identified as such as well.
+	HashingAlgorithmBcrypt CryptAlgo = "2a"
+	// HashingAlgorithmScrypt scrypt hash identifier.
+	HashingAlgorithmScrypt CryptAlgo = "scrypt"
+	// HashingAlgorithmPBKDF2SHA1 PBKDF2 with SHA1 hash identifier.
+	HashingAlgorithmPBKDF2SHA1 CryptAlgo = "pbkdf2"
+	// HashingAlgorithmPBKDF2SHA256 PBKDF2 with SHA256 hash identifier.
+	HashingAlgorithmPBKDF2SHA256 CryptAlgo = "pbkdf2-sha256"
+	// HashingAlgorithmPBKDF2SHA512 PBKDF2 with SHA512 hash identifier.
+	HashingAlgorithmPBKDF2SHA512 CryptAlgo = "pbkdf2-sha512"
 )
 
 // These are the default values from the upstream crypt module we use them to for GetInt
@@ -48,12 +61,41 @@ const (
 	HashingDefaultSHA512Iterations    = 5000
 )
 
+// Default values of the bcrypt, scrypt and PBKDF2 hashes generated by the hash-password command.
+const (
+	HashingDefaultBcryptCost        = 12
+	HashingDefaultScryptIterations  = 16
+	HashingDefaultScryptBlockSize   = 8
+	HashingDefaultScryptParallelism = 1
+	HashingDefaultPBKDF2Iterations  = 310000
+)
+
 // HashingPossibleSaltCharacters represents valid hashing runes.
 var HashingPossibleSaltCharacters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/")
 
 // ErrUserNotFound indicates the user wasn't found in the authentication backend.
 var ErrUserNotFound = errors.New("user not found")
 
+// ErrUserAlreadyExists indicates the user already exists in the authentication backend.
+var ErrUserAlreadyExists = errors.New("user already exists")
+
+// ErrUserDisabled indicates the account of the user is disabled, locked or expired in the authentication backend.
+var ErrUserDisabled = errors.New("user account is disabled")
+
+// ErrPasswordExpired indicates the password of the user is correct but has expired, it must be reset before the user
+// can log in.
+var ErrPasswordExpired = errors.New("user password has expired")
+
+var errRehashNotRelevant = errors.New("the hash of the password has changed since it has been checked")
+
+// tlsVersions maps the TLS versions of the configuration to their identifier.
+var tlsVersions = map[string]uint16{
+	"TLS1.0": tls.VersionTLS10,
+	"TLS1.1": tls.VersionTLS11,
+	"TLS1.2": tls.VersionTLS12,
+	"TLS1.3": tls.VersionTLS13,
+}
+
 const argon2id = "argon2id"
 const sha512 = "sha512"
 
@@ -0,0 +1,247 @@
+package authentication
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var errDatabaseNotPatchable = errors.New("The users database cannot be patched")
+
+// marshalDatabase returns the content of the database file holding the users of the database. The users are patched
+// into the current content of the file so that the comments, the order of the keys and the style of the values edited
+// by the administrator are kept. The content is generated from scratch only if the current content cannot be patched.
+func marshalDatabase(current []byte, database *DatabaseModel) ([]byte, error) {
+	if content, err := patchDatabase(current, database); err == nil {
+		return content, nil
+	}
+
+	return yaml.Marshal(database)
+}
+
+func patchDatabase(current []byte, database *DatabaseModel) ([]byte, error) {
+	var document yamlv3.Node
+
+	if err := yamlv3.Unmarshal(current, &document); err != nil {
+		return nil, err
+	}
+
+	if document.Kind != yamlv3.DocumentNode || len(document.Content) != 1 {
+		return nil, errDatabaseNotPatchable
+	}
+
+	users := mappingValue(document.Content[0], "users")
+	if users == nil || users.Kind != yamlv3.MappingNode {
+		return nil, errDatabaseNotPatchable
+	}
+
+	// The users which have been deleted are removed along with their comments.
+	kept := users.Content[:0]
+
+	for i := 0; i+1 < len(users.Content); i += 2 {
+		if _, ok := database.Users[users.Content[i].Value]; ok {
+			kept = append(kept, users.Content[i], users.Content[i+1])
+		}
+	}
+
+	users.Content = kept
+
+	usernames := make([]string, 0, len(database.Users))
+	for username := range database.Users {
+		usernames = append(usernames, username)
+	}
+
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		details, err := encodeNode(database.Users[username])
+		if err != nil {
+			return nil, err
+		}
+
+		if node := mappingValue(users, username); node != nil {
+			if err := mergeNode(node, details); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		users.Content = append(users.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: username}, details)
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := yamlv3.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	// The patched content is checked the same way the file is read, e.g., the alias of a deleted anchor is invalid.
+	if _, err := parseDatabase(buffer.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// encodeNode returns the node representing the value.
+func encodeNode(value interface{}) (*yamlv3.Node, error) {
+	content, err := yamlv3.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yamlv3.Node
+
+	if err := yamlv3.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	return document.Content[0], nil
+}
+
+// mappingValue returns the value of the key in a mapping node, nil if the key is missing.
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// mergeNode updates the values of the destination node which differ from the source node. The keys of a mapping
+// which are missing from the source node are kept, so are the items of a sequence which are still in the source node.
+// The nodes holding an anchor are not updated since the change would apply to their aliases as well.
+func mergeNode(dst, src *yamlv3.Node) error {
+	if nodesEqual(dst, src) {
+		return nil
+	}
+
+	if dst.Anchor != "" {
+		return errDatabaseNotPatchable
+	}
+
+	switch {
+	case dst.Kind == yamlv3.MappingNode && src.Kind == yamlv3.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+
+			if node := mappingValue(dst, key.Value); node != nil {
+				if err := mergeNode(node, value); err != nil {
+					return err
+				}
+			} else if !isEmptyNode(value) {
+				dst.Content = append(dst.Content, key, value)
+			}
+		}
+	case dst.Kind == yamlv3.ScalarNode && src.Kind == yamlv3.ScalarNode:
+		dst.Value, dst.Tag = src.Value, src.Tag
+	case dst.Kind == yamlv3.SequenceNode && src.Kind == yamlv3.SequenceNode:
+		items := make([]*yamlv3.Node, 0, len(src.Content))
+		used := make([]bool, len(dst.Content))
+
+		for _, s := range src.Content {
+			item := s
+
+			for i, d := range dst.Content {
+				if !used[i] && nodesEqual(d, s) {
+					item, used[i] = d, true
+					break
+				}
+			}
+
+			items = append(items, item)
+		}
+
+		dst.Content = items
+	default:
+		// The node is replaced as a whole, e.g., an empty value replaced by a sequence, but its comments are kept.
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	}
+
+	return nil
+}
+
+func nodesEqual(a, b *yamlv3.Node) bool {
+	a, b = resolveAlias(a), resolveAlias(b)
+
+	if isEmptyNode(a) || isEmptyNode(b) {
+		return isEmptyNode(a) && isEmptyNode(b)
+	}
+
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case yamlv3.ScalarNode:
+		return a.Value == b.Value
+	case yamlv3.SequenceNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+
+		for i := range a.Content {
+			if !nodesEqual(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+
+		return true
+	case yamlv3.MappingNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			value := mappingValue(b, a.Content[i].Value)
+			if value == nil || !nodesEqual(a.Content[i+1], value) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// isEmptyNode returns whether the node is null, an empty string or an empty sequence or mapping.
+func isEmptyNode(node *yamlv3.Node) bool {
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yamlv3.ScalarNode:
+		return node.Tag == "!!null" || node.Value == ""
+	case yamlv3.SequenceNode, yamlv3.MappingNode:
+		return len(node.Content) == 0
+	}
+
+	return false
+}
+
+func resolveAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
@@ -1,26 +1,42 @@
 package authentication
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/asaskevich/govalidator"
-	"github.com/simia-tech/crypt"
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v2"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
-	"github.com/authelia/authelia/internal/utils"
 )
 
 // FileUserProvider is a provider reading details from a file.
 type FileUserProvider struct {
 	configuration *schema.FileAuthenticationBackendConfiguration
-	database      *DatabaseModel
-	lock          *sync.Mutex
+
+	// database the users currently in use and content the content of the file they have been read from. The database
+	// is swapped as a whole when the file is reloaded, hence both are guarded by the lock.
+	database *DatabaseModel
+	content  []byte
+	lock     *sync.RWMutex
+
+	// rehashes tracks the passwords being hashed again in the background. rehashing holds the users whose password is
+	// being hashed again and rehashDisabled whether rehashing has been disabled since the file could not be written,
+	// both are guarded by rehashLock.
+	rehashes       sync.WaitGroup
+	rehashLock     sync.Mutex
+	rehashing      map[string]bool
+	rehashDisabled bool
 
 	// TODO: Remove this. This is only here to temporarily fix the username enumeration security flaw in #949.
 	fakeHash string
@@ -50,7 +66,7 @@ func NewFileUserProvider(configuration *schema.FileAuthenticationBackendConfigur
 		os.Exit(1)
 	}
 
-	database, err := readDatabase(configuration.Path)
+	database, content, err := readDatabase(configuration.Path)
 	if err != nil {
 		// Panic since the file does not exist when Authelia is starting.
 		panic(err)
@@ -62,24 +78,13 @@ func NewFileUserProvider(configuration *schema.FileAuthenticationBackendConfigur
 		panic(err)
 	}
 
-	var cryptAlgo CryptAlgo = HashingAlgorithmArgon2id
-	// TODO: Remove this. This is only here to temporarily fix the username enumeration security flaw in #949.
-	// This generates a hash that should be usable to do a fake CheckUserPassword
-	if configuration.Password.Algorithm == sha512 {
-		cryptAlgo = HashingAlgorithmSHA512
-	}
-
-	settings := getCryptSettings(utils.RandomString(configuration.Password.SaltLength, HashingPossibleSaltCharacters),
-		cryptAlgo, configuration.Password.Iterations, configuration.Password.Memory*1024, configuration.Password.Parallelism,
-		configuration.Password.KeyLength)
-	data := crypt.Base64Encoding.EncodeToString([]byte(utils.RandomString(configuration.Password.KeyLength, HashingPossibleSaltCharacters)))
-	fakeHash := fmt.Sprintf("%s$%s", settings, data)
-
 	return &FileUserProvider{
 		configuration: configuration,
 		database:      database,
-		lock:          &sync.Mutex{},
-		fakeHash:      fakeHash,
+		content:       content,
+		lock:          &sync.RWMutex{},
+		rehashing:     make(map[string]bool),
+		fakeHash:      newFakeHash(configuration.Password),
 	}
 }
 
@@ -138,15 +143,24 @@ func generateDatabaseFromTemplate(path string) error {
 	return nil
 }
 
-func readDatabase(path string) (*DatabaseModel, error) {
+func readDatabase(path string) (*DatabaseModel, []byte, error) {
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("Unable to read database from file %s: %s", path, err)
+		return nil, nil, fmt.Errorf("Unable to read database from file %s: %s", path, err)
 	}
 
+	db, err := parseDatabase(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, content, nil
+}
+
+func parseDatabase(content []byte) (*DatabaseModel, error) {
 	db := DatabaseModel{}
 
-	err = yaml.Unmarshal(content, &db)
+	err := yaml.Unmarshal(content, &db)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to parse database: %s", err)
 	}
@@ -163,14 +177,104 @@ func readDatabase(path string) (*DatabaseModel, error) {
 	return &db, nil
 }
 
+// Reload reads the database file again and swaps in the users it holds once they have been validated. The users
+// in use are kept if the file is invalid.
+func (p *FileUserProvider) Reload() error {
+	database, content, err := readDatabase(p.configuration.Path)
+	if err != nil {
+		return err
+	}
+
+	p.lock.RLock()
+	unchanged := bytes.Equal(content, p.content)
+	p.lock.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	if err := checkPasswordHashes(database); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	p.database = database
+	p.content = content
+	p.lock.Unlock()
+
+	logging.Logger().Infof("Reloaded the users database from %s", p.configuration.Path)
+
+	return nil
+}
+
+// Watch reloads the database whenever its file changes. The directory of the file is watched rather than the file
+// itself so that the file is still watched once replaced, as done by most editors and by Kubernetes for the mounted
+// config maps and secrets.
+func (p *FileUserProvider) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	path, err := filepath.Abs(p.configuration.Path)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("Unable to watch the directory of the database file %s: %v", path, err)
+	}
+
+	go p.watch(watcher)
+
+	return nil
+}
+
+func (p *FileUserProvider) watch(watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	logger := logging.Logger()
+
+	for {
+		select {
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Any change of the directory is considered since the file may be replaced through a symbolic link,
+			// the database is only swapped if the content of the file has actually changed.
+			if err := p.Reload(); err != nil {
+				logger.Errorf("Unable to reload the users database, the users previously loaded are kept: %v", err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.Errorf("Error while watching the users database: %v", err)
+		}
+	}
+}
+
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *FileUserProvider) CheckUserPassword(username string, password string) (bool, error) {
-	if details, ok := p.database.Users[username]; ok {
+	p.lock.RLock()
+	details, ok := p.database.Users[username]
+	p.lock.RUnlock()
+
+	if ok {
 		ok, err := CheckPassword(password, details.HashedPassword)
 		if err != nil {
 			return false, err
 		}
 
+		if ok {
+			p.rehashIfNeeded(username, password, details.HashedPassword)
+		}
+
 		return ok, nil
 	}
 
@@ -180,9 +284,83 @@ func (p *FileUserProvider) CheckUserPassword(username string, password string) (
 	return false, ErrUserNotFound
 }
 
+// rehashIfNeeded hashes the password again and saves the new hash in the background when the current hash has not
+// been generated with the algorithm and the parameters of the configuration. The user is logged in anyway if it fails.
+func (p *FileUserProvider) rehashIfNeeded(username, password, currentHash string) {
+	if p.configuration.DisableRehash {
+		return
+	}
+
+	hash, err := ParseHash(currentHash)
+	if err != nil || !needsRehash(hash, p.configuration.Password) {
+		return
+	}
+
+	p.rehashLock.Lock()
+	defer p.rehashLock.Unlock()
+
+	if p.rehashDisabled || p.rehashing[username] {
+		return
+	}
+
+	p.rehashing[username] = true
+
+	p.rehashes.Add(1)
+
+	go func() {
+		defer p.rehashes.Done()
+
+		err := p.rehash(username, password, currentHash)
+
+		p.rehashLock.Lock()
+		defer p.rehashLock.Unlock()
+
+		delete(p.rehashing, username)
+
+		switch err {
+		case nil:
+			logging.Logger().Debugf("Password of user %s has been hashed again with the configured parameters", username)
+		case errRehashNotRelevant:
+		default:
+			// The file is most likely read-only, hence rehashing is disabled rather than failing at every login.
+			if !p.rehashDisabled {
+				p.rehashDisabled = true
+
+				logging.Logger().Warnf("Unable to hash the password of user %s again with the configured parameters, "+
+					"the passwords will not be hashed again until restart: %v", username, err)
+			}
+		}
+	}()
+}
+
+func (p *FileUserProvider) rehash(username, password, currentHash string) error {
+	newHash, err := hashPasswordWithConfiguration(password, p.configuration.Password)
+	if err != nil {
+		return err
+	}
+
+	return p.update(func(database *DatabaseModel) error {
+		details, ok := database.Users[username]
+
+		// The password has been changed or the user removed in the meantime, the rehash is not relevant anymore.
+		if !ok || details.HashedPassword != currentHash {
+			return errRehashNotRelevant
+		}
+
+		details.HashedPassword = newHash
+		database.Users[username] = details
+
+		return nil
+	})
+}
+
 // GetDetails retrieve the groups a user belongs to.
 func (p *FileUserProvider) GetDetails(username string) (*UserDetails, error) {
-	if details, ok := p.database.Users[username]; ok {
+	p.lock.RLock()
+	details, ok := p.database.Users[username]
+	p.lock.RUnlock()
+
+	if ok {
 		return &UserDetails{
 			Username:    username,
 			DisplayName: details.DisplayName,
@@ -196,38 +374,206 @@ func (p *FileUserProvider) GetDetails(username string) (*UserDetails, error) {
 
 // UpdatePassword update the password of the given user.
 func (p *FileUserProvider) UpdatePassword(username string, newPassword string) error {
-	details, ok := p.database.Users[username]
-	if !ok {
-		return ErrUserNotFound
+	hash, err := hashPasswordWithConfiguration(newPassword, p.configuration.Password)
+	if err != nil {
+		return err
 	}
 
-	algorithm, err := ConfigAlgoToCryptoAlgo(p.configuration.Password.Algorithm)
+	return p.update(func(database *DatabaseModel) error {
+		details, ok := database.Users[username]
+		if !ok {
+			return ErrUserNotFound
+		}
+
+		details.HashedPassword = hash
+		database.Users[username] = details
+
+		return nil
+	})
+}
+
+// ListUsers retrieve the details of all the users sorted by username.
+func (p *FileUserProvider) ListUsers() []UserDetails {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	users := make([]UserDetails, 0, len(p.database.Users))
+
+	for username, details := range p.database.Users {
+		users = append(users, UserDetails{
+			Username:    username,
+			DisplayName: details.DisplayName,
+			Groups:      details.Groups,
+			Emails:      []string{details.Email},
+		})
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users
+}
+
+// AddUser add a user whose password is hashed with the configured algorithm.
+func (p *FileUserProvider) AddUser(username, displayName, email, password string, groups []string) error {
+	if strings.TrimSpace(username) == "" {
+		return errors.New("The username must not be empty")
+	}
+
+	hash, err := hashPasswordWithConfiguration(password, p.configuration.Password)
 	if err != nil {
 		return err
 	}
 
-	hash, err := HashPassword(
-		newPassword, "", algorithm, p.configuration.Password.Iterations,
-		p.configuration.Password.Memory*1024, p.configuration.Password.Parallelism,
-		p.configuration.Password.KeyLength, p.configuration.Password.SaltLength)
+	return p.update(func(database *DatabaseModel) error {
+		if _, ok := database.Users[username]; ok {
+			return ErrUserAlreadyExists
+		}
 
+		database.Users[username] = UserDetailsModel{
+			HashedPassword: hash,
+			DisplayName:    displayName,
+			Email:          email,
+			Groups:         groups,
+		}
+
+		return nil
+	})
+}
+
+// DeleteUser delete the given user.
+func (p *FileUserProvider) DeleteUser(username string) error {
+	return p.update(func(database *DatabaseModel) error {
+		if _, ok := database.Users[username]; !ok {
+			return ErrUserNotFound
+		}
+
+		delete(database.Users, username)
+
+		return nil
+	})
+}
+
+// UpdateGroups replace the groups the given user belongs to.
+func (p *FileUserProvider) UpdateGroups(username string, groups []string) error {
+	return p.update(func(database *DatabaseModel) error {
+		details, ok := database.Users[username]
+		if !ok {
+			return ErrUserNotFound
+		}
+
+		details.Groups = groups
+		database.Users[username] = details
+
+		return nil
+	})
+}
+
+// update applies a change to the database read again from the file, validates it as a database read from the file and
+// writes it to the file before swapping it in. The database in use is left untouched if any of these steps fails. The
+// file is locked during the update so that the changes made concurrently by another process, like the users command
+// and the server, are not lost. The change is patched into the content of the file so that its comments are kept.
+func (p *FileUserProvider) update(change func(database *DatabaseModel) error) error {
+	unlock, err := lockDatabase(p.configuration.Path)
 	if err != nil {
 		return err
 	}
 
-	details.HashedPassword = hash
+	defer unlock()
 
 	p.lock.Lock()
-	p.database.Users[username] = details
+	defer p.lock.Unlock()
 
-	b, err := yaml.Marshal(p.database)
+	database, current, err := readDatabase(p.configuration.Path)
 	if err != nil {
-		p.lock.Unlock()
 		return err
 	}
 
-	err = ioutil.WriteFile(p.configuration.Path, b, fileAuthenticationMode)
-	p.lock.Unlock()
+	if err := checkPasswordHashes(database); err != nil {
+		return err
+	}
+
+	if err := change(database); err != nil {
+		return err
+	}
 
-	return err
+	if _, err := govalidator.ValidateStruct(database); err != nil {
+		return fmt.Errorf("Invalid schema of database: %s", err)
+	}
+
+	if err := checkPasswordHashes(database); err != nil {
+		return err
+	}
+
+	content, err := marshalDatabase(current, database)
+	if err != nil {
+		return err
+	}
+
+	if err := writeDatabase(p.configuration.Path, content); err != nil {
+		return err
+	}
+
+	// The content is recorded along with the database so that the change is not reloaded by the watcher.
+	p.database = database
+	p.content = content
+
+	return nil
+}
+
+// databaseLockPath returns the path of the file locked while the database is updated. The database file itself cannot
+// be locked since it is replaced by the updates.
+func databaseLockPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+}
+
+// lockDatabase takes an exclusive lock on the lock file of the database, waiting for the other processes holding it,
+// and returns the function releasing the lock.
+func lockDatabase(path string) (func(), error) {
+	file, err := os.OpenFile(databaseLockPath(path), os.O_CREATE|os.O_RDWR, fileAuthenticationMode)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open the lock file of the database: %v", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Unable to lock the database: %v", err)
+	}
+
+	return func() {
+		// Closing the file releases the lock.
+		file.Close()
+	}, nil
+}
+
+// writeDatabase replaces the content of the database file atomically by renaming a temporary file written in the
+// same directory so that a reader never sees a partially written file.
+func writeDatabase(path string, content []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("Unable to create a temporary file to write the database: %v", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("Unable to write the database to %s: %v", file.Name(), err)
+	}
+
+	if err := file.Chmod(fileAuthenticationMode); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("Unable to replace the database file %s: %v", path, err)
+	}
+
+	return nil
 }
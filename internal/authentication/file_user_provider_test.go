@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"aletheia.icu/broccoli/fs"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +22,7 @@ func WithDatabase(content []byte, f func(path string)) {
 	}
 
 	defer os.Remove(tmpfile.Name()) // clean up
+	defer os.Remove(databaseLockPath(tmpfile.Name()))
 
 	if _, err := tmpfile.Write(content); err != nil {
 		tmpfile.Close()
@@ -93,6 +95,85 @@ func TestShouldCheckUserSHA512PasswordIsCorrect(t *testing.T) {
 	})
 }
 
+func TestShouldRehashPasswordWhenParametersDiffer(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		ok, err := provider.CheckUserPassword("harry", "password")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		provider.rehashes.Wait()
+
+		provider = NewFileUserProvider(&config)
+		hash := provider.database.Users["harry"].HashedPassword
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=131072,t=1,p=8$"))
+
+		ok, err = provider.CheckUserPassword("harry", "password")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		provider.rehashes.Wait()
+		assert.Equal(t, hash, provider.database.Users["harry"].HashedPassword)
+	})
+}
+
+func TestShouldNotRehashPasswordWhenRehashIsDisabled(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		config.DisableRehash = true
+		provider := NewFileUserProvider(&config)
+		hash := provider.database.Users["harry"].HashedPassword
+
+		ok, err := provider.CheckUserPassword("harry", "password")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		provider.rehashes.Wait()
+		assert.Equal(t, hash, provider.database.Users["harry"].HashedPassword)
+	})
+}
+
+func TestShouldDisableRehashWhenDatabaseCannotBeWritten(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		hash := provider.database.Users["harry"].HashedPassword
+
+		// The file cannot be replaced once its path is a directory.
+		require.NoError(t, os.Remove(path))
+		require.NoError(t, os.Mkdir(path, 0700))
+
+		defer os.Remove(path)
+
+		ok, err := provider.CheckUserPassword("harry", "password")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		provider.rehashes.Wait()
+		assert.Equal(t, hash, provider.database.Users["harry"].HashedPassword)
+		assert.True(t, provider.rehashDisabled)
+	})
+}
+
+func TestShouldNotRehashPasswordWhenPasswordIsWrong(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		hash := provider.database.Users["harry"].HashedPassword
+
+		ok, err := provider.CheckUserPassword("harry", "wrong_password")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, hash, provider.database.Users["harry"].HashedPassword)
+	})
+}
+
 func TestShouldCheckUserPasswordIsWrong(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
@@ -270,6 +351,193 @@ func TestShouldSupportHashPasswordWithoutCRYPT(t *testing.T) {
 	})
 }
 
+func TestShouldReloadDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, ioutil.WriteFile(path, UserDatabaseWithoutCryptContent, fileAuthenticationMode))
+		require.NoError(t, provider.Reload())
+
+		_, err := provider.GetDetails("harry")
+		assert.EqualError(t, err, "User 'harry' does not exist in database")
+
+		details, err := provider.GetDetails("james")
+		require.NoError(t, err)
+		assert.Equal(t, "James Dean", details.DisplayName)
+	})
+}
+
+func TestShouldKeepDatabaseWhenReloadingInvalidDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, ioutil.WriteFile(path, MalformedUserDatabaseContent, fileAuthenticationMode))
+		assert.EqualError(t, provider.Reload(), "Unable to parse database: yaml: line 4: mapping values are not allowed in this context")
+
+		require.NoError(t, ioutil.WriteFile(path, BadSHA512HashContent, fileAuthenticationMode))
+		assert.EqualError(t, provider.Reload(), "Unable to parse hash of user john: Hash key is not the last parameter, the hash is likely malformed ($6$rounds00000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/)")
+
+		details, err := provider.GetDetails("harry")
+		require.NoError(t, err)
+		assert.Equal(t, "Harry Potter", details.DisplayName)
+	})
+}
+
+func TestShouldReloadDatabaseWhenFileChanges(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, provider.Watch())
+		require.NoError(t, ioutil.WriteFile(path, UserDatabaseWithoutCryptContent, fileAuthenticationMode))
+
+		assert.Eventually(t, func() bool {
+			_, err := provider.GetDetails("harry")
+			return err != nil
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestShouldAddUserAndWriteDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, provider.AddUser("luke", "Luke Skywalker", "luke@authelia.com", "password", []string{"jedi"}))
+		assert.Equal(t, ErrUserAlreadyExists, provider.AddUser("john", "John Doe", "", "password", nil))
+
+		provider = NewFileUserProvider(&config)
+
+		ok, err := provider.CheckUserPassword("luke", "password")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		details, err := provider.GetDetails("luke")
+		require.NoError(t, err)
+		assert.Equal(t, "Luke Skywalker", details.DisplayName)
+		assert.Equal(t, []string{"jedi"}, details.Groups)
+	})
+}
+
+func TestShouldNotAddInvalidUser(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		assert.EqualError(t, provider.AddUser(" ", "Nobody", "", "password", nil), "The username must not be empty")
+		assert.Len(t, provider.ListUsers(), 5)
+	})
+}
+
+func TestShouldDeleteUserAndUpdateGroups(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, provider.DeleteUser("harry"))
+		assert.Equal(t, ErrUserNotFound, provider.DeleteUser("harry"))
+		require.NoError(t, provider.UpdateGroups("john", []string{"dev"}))
+		assert.Equal(t, ErrUserNotFound, provider.UpdateGroups("harry", nil))
+
+		provider = NewFileUserProvider(&config)
+
+		users := provider.ListUsers()
+		require.Len(t, users, 4)
+		assert.Equal(t, "bob", users[0].Username)
+		assert.Equal(t, "enumeration", users[1].Username)
+		assert.Equal(t, "james", users[2].Username)
+		assert.Equal(t, "john", users[3].Username)
+		assert.Equal(t, []string{"dev"}, users[3].Groups)
+	})
+}
+
+func TestShouldKeepChangesOfOtherProcessesWhenUpdating(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		// Another process, like the users command, changes the file before the provider reloads it.
+		other := NewFileUserProvider(&config)
+		require.NoError(t, other.DeleteUser("harry"))
+
+		require.NoError(t, provider.UpdateGroups("john", []string{"dev"}))
+
+		users := provider.ListUsers()
+		require.Len(t, users, 4)
+		assert.Equal(t, "john", users[3].Username)
+		assert.Equal(t, []string{"dev"}, users[3].Groups)
+
+		provider = NewFileUserProvider(&config)
+		assert.Len(t, provider.ListUsers(), 4)
+	})
+}
+
+func TestShouldKeepCommentsAndOrderOfDatabaseWhenUpdating(t *testing.T) {
+	WithDatabase(CommentedUserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, provider.UpdateGroups("john", []string{"admins", "ops"}))
+		require.NoError(t, provider.DeleteUser("harry"))
+		require.NoError(t, provider.AddUser("luke", "Luke Skywalker", "luke@authelia.com", "password", []string{"jedi"}))
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		database := string(content)
+
+		assert.Contains(t, database, "# The users allowed to log in.")
+		assert.Contains(t, database, "# The administrator.")
+		assert.Contains(t, database, "# Full access.")
+		assert.Contains(t, database, `displayname: "John Doe"`)
+		assert.NotContains(t, database, "harry")
+
+		// The users keep the order of the file, the new users are appended.
+		assert.True(t, strings.Index(database, "john:") < strings.Index(database, "bob:"))
+		assert.True(t, strings.Index(database, "bob:") < strings.Index(database, "luke:"))
+
+		provider = NewFileUserProvider(&config)
+
+		details, err := provider.GetDetails("john")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admins", "ops"}, details.Groups)
+
+		details, err = provider.GetDetails("luke")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"jedi"}, details.Groups)
+	})
+}
+
+func TestShouldNotChangeAliasesOfDatabaseWhenUpdatingAnchor(t *testing.T) {
+	WithDatabase(AnchoredUserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, provider.UpdateGroups("john", []string{"admins"}))
+
+		provider = NewFileUserProvider(&config)
+
+		details, err := provider.GetDetails("john")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admins"}, details.Groups)
+
+		details, err = provider.GetDetails("bob")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dev"}, details.Groups)
+	})
+}
+
 var (
 	DefaultFileAuthenticationBackendConfiguration = schema.FileAuthenticationBackendConfiguration{
 		Path: "",
@@ -405,3 +673,39 @@ users:
       - admins
       - dev
 `)
+
+var CommentedUserDatabaseContent = []byte(`# The users allowed to log in.
+users:
+  # The administrator.
+  john:
+    displayname: "John Doe"
+    password: "{CRYPT}$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"
+    email: john.doe@authelia.com
+    groups:
+      - admins # Full access.
+      - dev
+  harry:
+    displayname: "Harry Potter"
+    password: "{CRYPT}$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: harry.potter@authelia.com
+    groups: []
+  bob:
+    displayname: "Bob Dylan"
+    password: "{CRYPT}$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: bob.dylan@authelia.com
+`)
+
+var AnchoredUserDatabaseContent = []byte(`
+users:
+  john:
+    displayname: "John Doe"
+    password: "{CRYPT}$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"
+    email: john.doe@authelia.com
+    groups: &developers
+      - dev
+  bob:
+    displayname: "Bob Dylan"
+    password: "{CRYPT}$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: bob.dylan@authelia.com
+    groups: *developers
+`)
@@ -0,0 +1,178 @@
+package authentication
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// Attributes holding the state of the Active Directory accounts.
+const (
+	ldapAttributeUserAccountControl         = "userAccountControl"
+	ldapAttributeUserAccountControlComputed = "msDS-User-Account-Control-Computed"
+	ldapAttributePwdLastSet                 = "pwdLastSet"
+	ldapAttributeAccountExpires             = "accountExpires"
+)
+
+// Attributes holding the state of the accounts managed by the password policy overlay of OpenLDAP.
+const (
+	ldapAttributePwdAccountLockedTime = "pwdAccountLockedTime"
+	ldapAttributePwdReset             = "pwdReset"
+	ldapAttributePwdEndTime           = "pwdEndTime"
+)
+
+// Flags of the userAccountControl attribute of Active Directory. The lockout and password expired flags are only
+// set in the msDS-User-Account-Control-Computed attribute.
+const (
+	adAccountDisable  = 0x2
+	adLockout         = 0x10
+	adPasswordExpired = 0x800000
+)
+
+// ppolicyPermanentlyLocked is the value of pwdAccountLockedTime of the accounts locked by an administrator rather than
+// after too many failed attempts.
+const ppolicyPermanentlyLocked = "000001010000Z"
+
+// ldapGeneralizedTimeLayout is the layout of the generalized time values of the ppolicy attributes.
+const ldapGeneralizedTimeLayout = "20060102150405Z0700"
+
+// activeDirectoryBindErrors maps the codes in the diagnostic messages of the binds refused by Active Directory to the
+// state of the account.
+var activeDirectoryBindErrors = map[string]error{
+	"data 532": ErrPasswordExpired,
+	"data 773": ErrPasswordExpired,
+	"data 533": ErrUserDisabled,
+	"data 701": ErrUserDisabled,
+	"data 775": ErrUserDisabled,
+}
+
+// accountStateAttributes returns the attributes to retrieve along with the profile to read the state of the account.
+func (p *LDAPUserProvider) accountStateAttributes() []string {
+	switch p.configuration.AccountState {
+	case schema.LDAPAccountStateActiveDirectory:
+		return []string{ldapAttributeUserAccountControl, ldapAttributeUserAccountControlComputed,
+			ldapAttributePwdLastSet, ldapAttributeAccountExpires}
+	case schema.LDAPAccountStatePPolicy:
+		return []string{ldapAttributePwdAccountLockedTime, ldapAttributePwdReset, ldapAttributePwdEndTime}
+	}
+
+	return nil
+}
+
+// readAccountState reads the state of the account from the entry of the user.
+func (p *LDAPUserProvider) readAccountState(profile *ldapUserProfile, entry *ldap.Entry, now time.Time) error {
+	switch p.configuration.AccountState {
+	case schema.LDAPAccountStateActiveDirectory:
+		return readActiveDirectoryAccountState(profile, entry, now)
+	case schema.LDAPAccountStatePPolicy:
+		return readPPolicyAccountState(profile, entry, now)
+	}
+
+	return nil
+}
+
+func readActiveDirectoryAccountState(profile *ldapUserProfile, entry *ldap.Entry, now time.Time) error {
+	for _, attribute := range []string{ldapAttributeUserAccountControl, ldapAttributeUserAccountControlComputed} {
+		value := entry.GetAttributeValue(attribute)
+		if value == "" {
+			continue
+		}
+
+		flags, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Attribute %s is not numeric (%s)", attribute, value)
+		}
+
+		profile.Disabled = profile.Disabled || flags&(adAccountDisable|adLockout) != 0
+		profile.PasswordExpired = profile.PasswordExpired || flags&adPasswordExpired != 0
+	}
+
+	// A pwdLastSet of 0 means the user must change their password at the next logon.
+	if entry.GetAttributeValue(ldapAttributePwdLastSet) == "0" {
+		profile.PasswordExpired = true
+	}
+
+	if value := entry.GetAttributeValue(ldapAttributeAccountExpires); value != "" {
+		expires, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Attribute %s is not numeric (%s)", ldapAttributeAccountExpires, value)
+		}
+
+		// Both 0 and the maximum value mean the account never expires.
+		if expires != 0 && expires != math.MaxInt64 && fileTimeToTime(expires).Before(now) {
+			profile.Disabled = true
+		}
+	}
+
+	return nil
+}
+
+func readPPolicyAccountState(profile *ldapUserProfile, entry *ldap.Entry, now time.Time) error {
+	if entry.GetAttributeValue(ldapAttributePwdAccountLockedTime) == ppolicyPermanentlyLocked {
+		profile.Disabled = true
+	}
+
+	if value := entry.GetAttributeValue(ldapAttributePwdEndTime); value != "" {
+		end, err := time.Parse(ldapGeneralizedTimeLayout, value)
+		if err != nil {
+			return fmt.Errorf("Attribute %s is not a generalized time (%s)", ldapAttributePwdEndTime, value)
+		}
+
+		if end.Before(now) {
+			profile.Disabled = true
+		}
+	}
+
+	if strings.EqualFold(entry.GetAttributeValue(ldapAttributePwdReset), "TRUE") {
+		profile.PasswordExpired = true
+	}
+
+	return nil
+}
+
+// bindAccountState returns the state of the account when the server refused to bind because of it.
+func (p *LDAPUserProvider) bindAccountState(err error) error {
+	if p.configuration.AccountState != schema.LDAPAccountStateActiveDirectory ||
+		!ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil
+	}
+
+	for code, stateErr := range activeDirectoryBindErrors {
+		if strings.Contains(err.Error(), code) {
+			return stateErr
+		}
+	}
+
+	return nil
+}
+
+// fileTimeToTime converts a Windows file time, i.e., the number of 100 nanoseconds intervals since January 1, 1601
+// UTC, to a time.
+func fileTimeToTime(fileTime int64) time.Time {
+	const intervalsPerSecond = 10000000
+
+	const secondsFrom1601To1970 = 11644473600
+
+	return time.Unix(fileTime/intervalsPerSecond-secondsFrom1601To1970, fileTime%intervalsPerSecond*100).UTC()
+}
+
+// encodeActiveDirectoryPassword encodes a password as expected in the unicodePwd attribute, i.e., quoted and encoded
+// in UTF-16LE.
+func encodeActiveDirectoryPassword(password string) string {
+	encoded := utf16.Encode([]rune("\"" + password + "\""))
+	b := make([]byte, 2*len(encoded))
+
+	for i, c := range encoded {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+
+	return string(b)
+}
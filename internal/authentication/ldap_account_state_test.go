@@ -0,0 +1,129 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+var testAccountStateNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func createEntryWithAttributes(attributes map[string]string) *ldap.Entry {
+	entry := &ldap.Entry{DN: "uid=john,dc=example,dc=com"}
+
+	for name, value := range attributes {
+		entry.Attributes = append(entry.Attributes, &ldap.EntryAttribute{Name: name, Values: []string{value}})
+	}
+
+	return entry
+}
+
+func TestShouldReadActiveDirectoryAccountState(t *testing.T) {
+	testCases := []struct {
+		description     string
+		attributes      map[string]string
+		disabled        bool
+		passwordExpired bool
+	}{
+		{"enabled account", map[string]string{"userAccountControl": "512", "pwdLastSet": "132300000000000000", "accountExpires": "9223372036854775807"}, false, false},
+		{"account never expiring", map[string]string{"userAccountControl": "66048", "accountExpires": "0"}, false, false},
+		{"disabled account", map[string]string{"userAccountControl": "514"}, true, false},
+		{"locked out account", map[string]string{"userAccountControl": "512", "msDS-User-Account-Control-Computed": "16"}, true, false},
+		{"expired password", map[string]string{"userAccountControl": "512", "msDS-User-Account-Control-Computed": "8388608"}, false, true},
+		{"password to change at next logon", map[string]string{"userAccountControl": "512", "pwdLastSet": "0"}, false, true},
+		// 132274944000000000 is 2020-03-01 and 132400000000000000 is 2020-07-23.
+		{"expired account", map[string]string{"userAccountControl": "512", "accountExpires": "132274944000000000"}, true, false},
+		{"account expiring later", map[string]string{"userAccountControl": "512", "accountExpires": "132400000000000000"}, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			profile := &ldapUserProfile{}
+
+			require.NoError(t, readActiveDirectoryAccountState(profile, createEntryWithAttributes(tc.attributes), testAccountStateNow))
+			assert.Equal(t, tc.disabled, profile.Disabled)
+			assert.Equal(t, tc.passwordExpired, profile.PasswordExpired)
+		})
+	}
+}
+
+func TestShouldRaiseErrorWhenActiveDirectoryAccountStateIsNotNumeric(t *testing.T) {
+	err := readActiveDirectoryAccountState(&ldapUserProfile{},
+		createEntryWithAttributes(map[string]string{"userAccountControl": "abc"}), testAccountStateNow)
+	assert.EqualError(t, err, "Attribute userAccountControl is not numeric (abc)")
+
+	err = readActiveDirectoryAccountState(&ldapUserProfile{},
+		createEntryWithAttributes(map[string]string{"accountExpires": "never"}), testAccountStateNow)
+	assert.EqualError(t, err, "Attribute accountExpires is not numeric (never)")
+}
+
+func TestShouldReadPPolicyAccountState(t *testing.T) {
+	testCases := []struct {
+		description     string
+		attributes      map[string]string
+		disabled        bool
+		passwordExpired bool
+	}{
+		{"enabled account", map[string]string{}, false, false},
+		{"account locked after failed attempts", map[string]string{"pwdAccountLockedTime": "20200601110000Z"}, false, false},
+		{"account locked by an administrator", map[string]string{"pwdAccountLockedTime": "000001010000Z"}, true, false},
+		{"expired account", map[string]string{"pwdEndTime": "20200501000000Z"}, true, false},
+		{"account expiring later", map[string]string{"pwdEndTime": "20200701000000Z"}, false, false},
+		{"password reset by an administrator", map[string]string{"pwdReset": "TRUE"}, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			profile := &ldapUserProfile{}
+
+			require.NoError(t, readPPolicyAccountState(profile, createEntryWithAttributes(tc.attributes), testAccountStateNow))
+			assert.Equal(t, tc.disabled, profile.Disabled)
+			assert.Equal(t, tc.passwordExpired, profile.PasswordExpired)
+		})
+	}
+}
+
+func TestShouldRaiseErrorWhenPPolicyEndTimeIsMalformed(t *testing.T) {
+	err := readPPolicyAccountState(&ldapUserProfile{},
+		createEntryWithAttributes(map[string]string{"pwdEndTime": "tomorrow"}), testAccountStateNow)
+	assert.EqualError(t, err, "Attribute pwdEndTime is not a generalized time (tomorrow)")
+}
+
+func TestShouldReadAccountStateFromActiveDirectoryBindErrors(t *testing.T) {
+	provider := &LDAPUserProvider{configuration: schema.LDAPAuthenticationBackendConfiguration{
+		AccountState: schema.LDAPAccountStateActiveDirectory,
+	}}
+
+	bindError := func(data string) error {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials,
+			errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, "+data+", v4563"))
+	}
+
+	assert.Equal(t, ErrPasswordExpired, provider.bindAccountState(bindError("data 532")))
+	assert.Equal(t, ErrPasswordExpired, provider.bindAccountState(bindError("data 773")))
+	assert.Equal(t, ErrUserDisabled, provider.bindAccountState(bindError("data 533")))
+	assert.Equal(t, ErrUserDisabled, provider.bindAccountState(bindError("data 701")))
+	assert.Equal(t, ErrUserDisabled, provider.bindAccountState(bindError("data 775")))
+	assert.NoError(t, provider.bindAccountState(bindError("data 52e")))
+	assert.NoError(t, provider.bindAccountState(ldap.NewError(ldap.ErrorNetwork, errors.New("data 532"))))
+
+	provider.configuration.AccountState = schema.LDAPAccountStateNone
+	assert.NoError(t, provider.bindAccountState(bindError("data 532")))
+}
+
+func TestShouldConvertFileTimeToTime(t *testing.T) {
+	assert.Equal(t, time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC), fileTimeToTime(0))
+	assert.Equal(t, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), fileTimeToTime(116444736000000000))
+	assert.Equal(t, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), fileTimeToTime(132274944000000000))
+}
+
+func TestShouldEncodeActiveDirectoryPassword(t *testing.T) {
+	assert.Equal(t, "\"\x00p\x00w\x00\"\x00", encodeActiveDirectoryPassword("pw"))
+	assert.Equal(t, "\"\x00\xe9\x00\"\x00", encodeActiveDirectoryPassword("é"))
+}
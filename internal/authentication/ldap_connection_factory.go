@@ -2,6 +2,9 @@ package authentication
 
 import (
 	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 )
@@ -12,6 +15,8 @@ import (
 type LDAPConnection interface {
 	Bind(username, password string) error
 	Close()
+	IsClosing() bool
+	StartTLS(config *tls.Config) error
 
 	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
 	Modify(modifyRequest *ldap.ModifyRequest) error
@@ -37,6 +42,16 @@ func (lc *LDAPConnectionImpl) Close() {
 	lc.conn.Close()
 }
 
+// IsClosing returns whether the ldap connection is closing or closed, e.g., because the server closed it.
+func (lc *LDAPConnectionImpl) IsClosing() bool {
+	return lc.conn.IsClosing()
+}
+
+// StartTLS upgrades the ldap connection to TLS.
+func (lc *LDAPConnectionImpl) StartTLS(config *tls.Config) error {
+	return lc.conn.StartTLS(config)
+}
+
 // Search searches a ldap server.
 func (lc *LDAPConnectionImpl) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
 	return lc.conn.Search(searchRequest)
@@ -82,3 +97,157 @@ func (lcf *LDAPConnectionFactoryImpl) Dial(network, addr string) (LDAPConnection
 
 	return NewLDAPConnectionImpl(conn), nil
 }
+
+// ********************* POOL ***********************.
+
+// errLDAPConnectionPoolTimeout is returned when no connection of the pool has been available in time.
+var errLDAPConnectionPoolTimeout = errors.New("Timed out waiting for an available LDAP connection")
+
+// ldapConnectionPool a bounded pool of connections to the ldap server bound as the same user. The connections are
+// checked before being reused and closed once they have been idle for too long.
+type ldapConnectionPool struct {
+	open        func() (LDAPConnection, error)
+	idleTimeout time.Duration
+	timeout     time.Duration
+
+	// slots bounds the number of connections open at the same time.
+	slots chan struct{}
+
+	lock *sync.Mutex
+	idle []*pooledLDAPConnection
+}
+
+// pooledLDAPConnection a connection of the pool which records whether it is broken.
+type pooledLDAPConnection struct {
+	LDAPConnection
+
+	reused   bool
+	broken   bool
+	returned time.Time
+}
+
+// Search searches a ldap server and records whether the connection is broken.
+func (pc *pooledLDAPConnection) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := pc.LDAPConnection.Search(searchRequest)
+	pc.check(err)
+
+	return result, err
+}
+
+// Modify modifies an ldap object and records whether the connection is broken.
+func (pc *pooledLDAPConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
+	err := pc.LDAPConnection.Modify(modifyRequest)
+	pc.check(err)
+
+	return err
+}
+
+func (pc *pooledLDAPConnection) check(err error) {
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		pc.broken = true
+	}
+}
+
+// newLDAPConnectionPool creates a pool of at most size connections opened with the open function. The idle
+// connections are never closed if idleTimeout is 0 and getting a connection fails once it has waited for timeout.
+func newLDAPConnectionPool(size int, idleTimeout, timeout time.Duration,
+	open func() (LDAPConnection, error)) *ldapConnectionPool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &ldapConnectionPool{
+		open:        open,
+		idleTimeout: idleTimeout,
+		timeout:     timeout,
+		slots:       make(chan struct{}, size),
+		lock:        &sync.Mutex{},
+	}
+}
+
+// get returns an idle connection if there is a healthy one or a new connection otherwise. It blocks while all the
+// connections are in use and fails once it has waited for the timeout of the pool.
+func (p *ldapConnectionPool) get() (*pooledLDAPConnection, error) {
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, errLDAPConnectionPoolTimeout
+	}
+
+	if conn := p.popIdle(); conn != nil {
+		conn.reused = true
+		return conn, nil
+	}
+
+	conn, err := p.open()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	return &pooledLDAPConnection{LDAPConnection: conn}, nil
+}
+
+// put gives a connection back to the pool. A broken connection is closed along with the idle connections since
+// they are likely broken as well, e.g., when the server has been restarted.
+func (p *ldapConnectionPool) put(conn *pooledLDAPConnection) {
+	defer func() { <-p.slots }()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if conn.broken || conn.IsClosing() {
+		conn.Close()
+		p.closeIdle(func(*pooledLDAPConnection) bool { return true })
+
+		return
+	}
+
+	conn.reused = false
+	conn.returned = time.Now()
+	p.idle = append(p.idle, conn)
+	p.closeIdle(p.expired)
+}
+
+// popIdle returns the idle connection which has been used most recently, the connections which are not healthy
+// anymore are closed.
+func (p *ldapConnectionPool) popIdle() *pooledLDAPConnection {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.closeIdle(func(conn *pooledLDAPConnection) bool {
+		return p.expired(conn) || conn.IsClosing()
+	})
+
+	if len(p.idle) == 0 {
+		return nil
+	}
+
+	conn := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+
+	return conn
+}
+
+func (p *ldapConnectionPool) expired(conn *pooledLDAPConnection) bool {
+	return p.idleTimeout > 0 && time.Since(conn.returned) > p.idleTimeout
+}
+
+// closeIdle closes and removes the idle connections matching the given function, the lock must be held.
+func (p *ldapConnectionPool) closeIdle(matches func(conn *pooledLDAPConnection) bool) {
+	idle := p.idle[:0]
+
+	for _, conn := range p.idle {
+		if matches(conn) {
+			conn.Close()
+			continue
+		}
+
+		idle = append(idle, conn)
+	}
+
+	p.idle = idle
+}
@@ -0,0 +1,157 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReuseIdleConnectionOfPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := NewMockLDAPConnection(ctrl)
+	opened := 0
+
+	pool := newLDAPConnectionPool(2, time.Minute, time.Second, func() (LDAPConnection, error) {
+		opened++
+		return mockConn, nil
+	})
+
+	mockConn.EXPECT().IsClosing().Return(false).Times(3)
+
+	conn, err := pool.get()
+	require.NoError(t, err)
+	assert.False(t, conn.reused)
+	pool.put(conn)
+
+	conn, err = pool.get()
+	require.NoError(t, err)
+	assert.True(t, conn.reused)
+	pool.put(conn)
+
+	assert.Equal(t, 1, opened)
+}
+
+func TestShouldNotReuseClosingConnectionOfPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	closingConn := NewMockLDAPConnection(ctrl)
+	newConn := NewMockLDAPConnection(ctrl)
+	conns := []LDAPConnection{closingConn, newConn}
+
+	pool := newLDAPConnectionPool(1, time.Minute, time.Second, func() (LDAPConnection, error) {
+		conn := conns[0]
+		conns = conns[1:]
+
+		return conn, nil
+	})
+
+	gomock.InOrder(
+		closingConn.EXPECT().IsClosing().Return(false),
+		closingConn.EXPECT().IsClosing().Return(true),
+		closingConn.EXPECT().Close(),
+	)
+
+	conn, err := pool.get()
+	require.NoError(t, err)
+	pool.put(conn)
+
+	conn, err = pool.get()
+	require.NoError(t, err)
+	assert.False(t, conn.reused)
+	assert.Equal(t, newConn, conn.LDAPConnection)
+}
+
+func TestShouldCloseExpiredIdleConnectionOfPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	pool := newLDAPConnectionPool(1, time.Minute, time.Second, func() (LDAPConnection, error) {
+		return mockConn, nil
+	})
+
+	mockConn.EXPECT().IsClosing().Return(false)
+	mockConn.EXPECT().Close()
+
+	conn, err := pool.get()
+	require.NoError(t, err)
+	pool.put(conn)
+
+	pool.idle[0].returned = time.Now().Add(-2 * time.Minute)
+
+	conn, err = pool.get()
+	require.NoError(t, err)
+	assert.False(t, conn.reused)
+}
+
+func TestShouldCloseBrokenConnectionOfPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	pool := newLDAPConnectionPool(1, time.Minute, time.Second, func() (LDAPConnection, error) {
+		return mockConn, nil
+	})
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed")))
+	mockConn.EXPECT().Close()
+
+	conn, err := pool.get()
+	require.NoError(t, err)
+
+	_, err = conn.Search(&ldap.SearchRequest{})
+	assert.Error(t, err)
+	assert.True(t, conn.broken)
+
+	pool.put(conn)
+	assert.Len(t, pool.idle, 0)
+}
+
+func TestShouldReleaseSlotWhenConnectionCannotBeOpened(t *testing.T) {
+	pool := newLDAPConnectionPool(1, time.Minute, time.Second, func() (LDAPConnection, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := pool.get()
+		assert.EqualError(t, err, "connection refused")
+	}
+}
+
+func TestShouldTimeOutWhenAllConnectionsOfPoolAreInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	pool := newLDAPConnectionPool(1, time.Minute, 50*time.Millisecond, func() (LDAPConnection, error) {
+		return mockConn, nil
+	})
+
+	mockConn.EXPECT().IsClosing().Return(false).Times(2)
+
+	conn, err := pool.get()
+	require.NoError(t, err)
+
+	_, err = pool.get()
+	assert.Equal(t, errLDAPConnectionPoolTimeout, err)
+
+	// The connection is available again once it has been given back.
+	pool.put(conn)
+
+	conn, err = pool.get()
+	require.NoError(t, err)
+	assert.True(t, conn.reused)
+}
@@ -2,38 +2,146 @@ package authentication
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // LDAPUserProvider is a provider using a LDAP or AD as a user database.
 type LDAPUserProvider struct {
 	configuration schema.LDAPAuthenticationBackendConfiguration
+	tlsConfig     *tls.Config
 
 	connectionFactory LDAPConnectionFactory
+
+	// pool the connections bound as the admin user. The connections bound as the users checking their password are
+	// not pooled.
+	pool *ldapConnectionPool
 }
 
 // NewLDAPUserProvider creates a new instance of LDAPUserProvider.
-func NewLDAPUserProvider(configuration schema.LDAPAuthenticationBackendConfiguration) *LDAPUserProvider {
-	return &LDAPUserProvider{
-		configuration:     configuration,
-		connectionFactory: NewLDAPConnectionFactoryImpl(),
-	}
+func NewLDAPUserProvider(configuration schema.LDAPAuthenticationBackendConfiguration) (*LDAPUserProvider, error) {
+	return NewLDAPUserProviderWithFactory(configuration, NewLDAPConnectionFactoryImpl())
 }
 
 // NewLDAPUserProviderWithFactory creates a new instance of LDAPUserProvider with existing factory.
 func NewLDAPUserProviderWithFactory(configuration schema.LDAPAuthenticationBackendConfiguration,
-	connectionFactory LDAPConnectionFactory) *LDAPUserProvider {
-	return &LDAPUserProvider{
+	connectionFactory LDAPConnectionFactory) (*LDAPUserProvider, error) {
+	tlsConfig, err := newLDAPTLSConfig(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &LDAPUserProvider{
 		configuration:     configuration,
+		tlsConfig:         tlsConfig,
 		connectionFactory: connectionFactory,
 	}
+
+	// The idle timeout has been validated when reading the configuration, the connections are kept forever otherwise.
+	idleTimeout, _ := utils.ParseDurationString(configuration.PoolIdleTimeout)
+
+	timeout, err := utils.ParseDurationString(configuration.PoolTimeout)
+	if err != nil || timeout <= 0 {
+		timeout, _ = utils.ParseDurationString(schema.DefaultLDAPAuthenticationBackendConfiguration.PoolTimeout)
+	}
+
+	provider.pool = newLDAPConnectionPool(configuration.PoolSize, idleTimeout, timeout, func() (LDAPConnection, error) {
+		return provider.connect(configuration.User, configuration.Password)
+	})
+
+	return provider, nil
+}
+
+// newLDAPTLSConfig creates the TLS configuration of the connections to the LDAP server trusting the configured
+// certificate authorities in addition to the system ones and presenting the client certificate, if any. The minimum
+// TLS version is the default of Go unless configured.
+func newLDAPTLSConfig(configuration schema.LDAPAuthenticationBackendConfiguration) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: configuration.SkipVerify, //nolint:gosec // This is a configurable option, is desirable in some situations and is off by default
+	}
+
+	// The server name is required to verify the certificate of the server when upgrading a connection with StartTLS.
+	if u, err := url.Parse(configuration.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	if configuration.TLS == nil {
+		return tlsConfig, nil
+	}
+
+	if configuration.TLS.ServerName != "" {
+		tlsConfig.ServerName = configuration.TLS.ServerName
+	}
+
+	if configuration.TLS.MinimumVersion != "" {
+		version, ok := tlsVersions[configuration.TLS.MinimumVersion]
+		if !ok {
+			return nil, fmt.Errorf("Unknown minimum TLS version %s", configuration.TLS.MinimumVersion)
+		}
+
+		tlsConfig.MinVersion = version
+	}
+
+	if configuration.TLS.TrustedCert != "" {
+		certPool, err := x509.SystemCertPool()
+		if err != nil || certPool == nil {
+			certPool = x509.NewCertPool()
+		}
+
+		pem, err := ioutil.ReadFile(configuration.TLS.TrustedCert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the trusted certificates: %v", err)
+		}
+
+		if ok := certPool.AppendCertsFromPEM(pem); !ok {
+			return nil, fmt.Errorf("Unable to import the trusted certificates of file %s", configuration.TLS.TrustedCert)
+		}
+
+		tlsConfig.RootCAs = certPool
+	}
+
+	if configuration.TLS.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(configuration.TLS.Cert, configuration.TLS.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load the client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// withAdminConnection runs the operation with a pooled connection bound as the admin user. The operation is run once
+// more with a new connection if a reused connection turns out to be broken since the server may have closed it.
+func (p *LDAPUserProvider) withAdminConnection(operation func(conn LDAPConnection) error) error {
+	for {
+		conn, err := p.pool.get()
+		if err != nil {
+			return err
+		}
+
+		err = operation(conn)
+		broken, reused := conn.broken, conn.reused
+
+		p.pool.put(conn)
+
+		if !broken || !reused {
+			return err
+		}
+
+		logging.Logger().Debugf("LDAP connection is broken, retrying with a new connection: %v", err)
+	}
 }
 
 func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnection, error) {
@@ -48,9 +156,7 @@ func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnecti
 	if url.Scheme == "ldaps" {
 		logging.Logger().Trace("LDAP client starts a TLS session")
 
-		conn, err := p.connectionFactory.DialTLS("tcp", url.Host, &tls.Config{
-			InsecureSkipVerify: p.configuration.SkipVerify, //nolint:gosec // This is a configurable option, is desirable in some situations and is off by default
-		})
+		conn, err := p.connectionFactory.DialTLS("tcp", url.Host, p.tlsConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -63,9 +169,19 @@ func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnecti
 			return nil, err
 		}
 		newConnection = conn
+
+		if p.configuration.StartTLS {
+			logging.Logger().Trace("LDAP client upgrades the session to TLS")
+
+			if err := newConnection.StartTLS(p.tlsConfig); err != nil {
+				newConnection.Close()
+				return nil, fmt.Errorf("Unable to upgrade the connection to TLS with StartTLS: %s", err)
+			}
+		}
 	}
 
 	if err := newConnection.Bind(userDN, password); err != nil {
+		newConnection.Close()
 		return nil, err
 	}
 
@@ -74,23 +190,31 @@ func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnecti
 
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password string) (bool, error) {
-	adminClient, err := p.connect(p.configuration.User, p.configuration.Password)
-	if err != nil {
-		return false, err
-	}
-	defer adminClient.Close()
+	var profile *ldapUserProfile
 
-	profile, err := p.getUserProfile(adminClient, inputUsername)
+	err := p.withAdminConnection(func(conn LDAPConnection) (err error) {
+		profile, err = p.getUserProfile(conn, inputUsername)
+		return err
+	})
 	if err != nil {
 		return false, err
 	}
 
 	conn, err := p.connect(profile.DN, password)
 	if err != nil {
+		if stateErr := p.bindAccountState(err); stateErr != nil {
+			return false, stateErr
+		}
+
 		return false, fmt.Errorf("Authentication of user %s failed. Cause: %s", inputUsername, err)
 	}
 	defer conn.Close()
 
+	// The state of the account is only revealed to the users who know the password.
+	if err := profile.accountState(); err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
 
@@ -108,10 +232,24 @@ func (p *LDAPUserProvider) ldapEscape(inputUsername string) string {
 }
 
 type ldapUserProfile struct {
-	DN          string
-	Emails      []string
-	DisplayName string
-	Username    string
+	DN              string
+	Emails          []string
+	DisplayName     string
+	Username        string
+	Disabled        bool
+	PasswordExpired bool
+}
+
+func (p *ldapUserProfile) accountState() error {
+	if p.Disabled {
+		return ErrUserDisabled
+	}
+
+	if p.PasswordExpired {
+		return ErrPasswordExpired
+	}
+
+	return nil
 }
 
 func (p *LDAPUserProvider) resolveUsersFilter(userFilter string, inputUsername string) string {
@@ -145,6 +283,7 @@ func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername str
 		p.configuration.DisplayNameAttribute,
 		p.configuration.MailAttribute,
 		p.configuration.UsernameAttribute}
+	attributes = append(attributes, p.accountStateAttributes()...)
 
 	// Search for the given username.
 	searchRequest := ldap.NewSearchRequest(
@@ -192,6 +331,10 @@ func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername str
 		return nil, fmt.Errorf("No DN has been found for user %s", inputUsername)
 	}
 
+	if err := p.readAccountState(&userProfile, sr.Entries[0], time.Now()); err != nil {
+		return nil, fmt.Errorf("Unable to read the state of the account of user %s. Cause: %s", inputUsername, err)
+	}
+
 	return &userProfile, nil
 }
 
@@ -213,18 +356,25 @@ func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ld
 }
 
 // GetDetails retrieve the groups a user belongs to.
-func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error) {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
+func (p *LDAPUserProvider) GetDetails(inputUsername string) (details *UserDetails, err error) {
+	err = p.withAdminConnection(func(conn LDAPConnection) (err error) {
+		details, err = p.getDetails(conn, inputUsername)
+		return err
+	})
+
+	return details, err
+}
 
+func (p *LDAPUserProvider) getDetails(conn LDAPConnection, inputUsername string) (*UserDetails, error) {
 	profile, err := p.getUserProfile(conn, inputUsername)
 	if err != nil {
 		return nil, err
 	}
 
+	if profile.Disabled {
+		return nil, ErrUserDisabled
+	}
+
 	groupsFilter, err := p.resolveGroupsFilter(inputUsername, profile)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create group filter for user %s. Cause: %s", inputUsername, err)
@@ -232,26 +382,21 @@ func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error
 
 	logging.Logger().Tracef("Computed groups filter is %s", groupsFilter)
 
-	groupBaseDN := p.configuration.BaseDN
-	if p.configuration.AdditionalGroupsDN != "" {
-		groupBaseDN = p.configuration.AdditionalGroupsDN + "," + groupBaseDN
-	}
-
-	// Search for the given username.
-	searchGroupRequest := ldap.NewSearchRequest(
-		groupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		0, 0, false, groupsFilter, []string{p.configuration.GroupNameAttribute}, nil,
-	)
-
-	sr, err := conn.Search(searchGroupRequest)
-
+	entries, err := p.searchGroups(conn, groupsFilter)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to retrieve groups of user %s. Cause: %s", inputUsername, err)
 	}
 
+	if p.configuration.NestedGroups {
+		entries, err = p.searchNestedGroups(conn, inputUsername, profile, entries)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve nested groups of user %s. Cause: %s", inputUsername, err)
+		}
+	}
+
 	groups := make([]string, 0)
 
-	for _, res := range sr.Entries {
+	for _, res := range entries {
 		if len(res.Attributes) == 0 {
 			logging.Logger().Warningf("No groups retrieved from LDAP for user %s", inputUsername)
 			break
@@ -268,25 +413,92 @@ func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error
 	}, nil
 }
 
-// UpdatePassword update the password of the given user.
-func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
-	client, err := p.connect(p.configuration.User, p.configuration.Password)
+func (p *LDAPUserProvider) searchGroups(conn LDAPConnection, groupsFilter string) ([]*ldap.Entry, error) {
+	groupBaseDN := p.configuration.BaseDN
+	if p.configuration.AdditionalGroupsDN != "" {
+		groupBaseDN = p.configuration.AdditionalGroupsDN + "," + groupBaseDN
+	}
 
+	// Search for the groups matching the filter.
+	searchGroupRequest := ldap.NewSearchRequest(
+		groupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, groupsFilter, []string{p.configuration.GroupNameAttribute}, nil,
+	)
+
+	sr, err := conn.Search(searchGroupRequest)
 	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+		return nil, err
 	}
 
-	profile, err := p.getUserProfile(client, inputUsername)
+	return sr.Entries, nil
+}
 
-	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+// searchNestedGroups walks up the groups the given groups are members of, level by level, by searching the groups
+// matching the groups filter with the DN of each group in place of the DN of the user. The groups already visited are
+// skipped so that cycles do not loop forever, and the walk stops at the configured depth.
+func (p *LDAPUserProvider) searchNestedGroups(conn LDAPConnection, inputUsername string, profile *ldapUserProfile,
+	groups []*ldap.Entry) ([]*ldap.Entry, error) {
+	visited := make(map[string]bool, len(groups))
+
+	for _, group := range groups {
+		visited[strings.ToLower(group.DN)] = true
 	}
 
-	modifyRequest := ldap.NewModifyRequest(profile.DN, nil)
+	current := groups
+
+	for depth := 0; depth < p.configuration.NestedGroupsMaxDepth && len(current) > 0; depth++ {
+		var parents []*ldap.Entry
+
+		for _, group := range current {
+			groupsFilter, err := p.resolveGroupsFilter(inputUsername, &ldapUserProfile{DN: group.DN, Username: profile.Username})
+			if err != nil {
+				return nil, err
+			}
+
+			entries, err := p.searchGroups(conn, groupsFilter)
+			if err != nil {
+				return nil, err
+			}
 
-	modifyRequest.Replace("userPassword", []string{newPassword})
+			for _, entry := range entries {
+				if dn := strings.ToLower(entry.DN); !visited[dn] {
+					visited[dn] = true
+					parents = append(parents, entry)
+				}
+			}
+		}
+
+		groups = append(groups, parents...)
+		current = parents
+	}
+
+	if len(current) > 0 {
+		logging.Logger().Debugf("Nested groups of user %s have been searched up to the maximum depth of %d",
+			inputUsername, p.configuration.NestedGroupsMaxDepth)
+	}
+
+	return groups, nil
+}
+
+// UpdatePassword update the password of the given user.
+func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
+	err := p.withAdminConnection(func(conn LDAPConnection) error {
+		profile, err := p.getUserProfile(conn, inputUsername)
+		if err != nil {
+			return err
+		}
+
+		modifyRequest := ldap.NewModifyRequest(profile.DN, nil)
+
+		if p.configuration.AccountState == schema.LDAPAccountStateActiveDirectory {
+			// Active Directory only changes the password through the unicodePwd attribute.
+			modifyRequest.Replace("unicodePwd", []string{encodeActiveDirectoryPassword(newPassword)})
+		} else {
+			modifyRequest.Replace("userPassword", []string{newPassword})
+		}
 
-	err = client.Modify(modifyRequest)
+		return conn.Modify(modifyRequest)
+	})
 
 	if err != nil {
 		return fmt.Errorf("Unable to update password. Cause: %s", err)
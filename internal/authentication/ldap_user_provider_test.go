@@ -1,6 +1,9 @@
 package authentication
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/go-ldap/ldap/v3"
@@ -18,9 +21,10 @@ func TestShouldCreateRawConnectionWhenSchemeIsLDAP(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldap := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL: "ldap://127.0.0.1:389",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockFactory.EXPECT().
 		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
@@ -30,7 +34,7 @@ func TestShouldCreateRawConnectionWhenSchemeIsLDAP(t *testing.T) {
 		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
 		Return(nil)
 
-	_, err := ldap.connect("cn=admin,dc=example,dc=com", "password")
+	_, err = ldap.connect("cn=admin,dc=example,dc=com", "password")
 
 	require.NoError(t, err)
 }
@@ -42,9 +46,10 @@ func TestShouldCreateTLSConnectionWhenSchemeIsLDAPS(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldap := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL: "ldaps://127.0.0.1:389",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockFactory.EXPECT().
 		DialTLS(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389"), gomock.Any()).
@@ -54,19 +59,155 @@ func TestShouldCreateTLSConnectionWhenSchemeIsLDAPS(t *testing.T) {
 		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
 		Return(nil)
 
-	_, err := ldap.connect("cn=admin,dc=example,dc=com", "password")
+	_, err = ldap.connect("cn=admin,dc=example,dc=com", "password")
 
 	require.NoError(t, err)
 }
 
+func TestShouldUpgradeConnectionWithStartTLS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:      "ldap://ldap.example.com:389",
+		StartTLS: true,
+	}, mockFactory)
+	require.NoError(t, err)
+
+	mockFactory.EXPECT().
+		Dial(gomock.Eq("tcp"), gomock.Eq("ldap.example.com:389")).
+		Return(mockConn, nil)
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			StartTLS(NewTLSConfigMatcher("ldap.example.com", 0)).
+			Return(nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	_, err = ldap.connect("cn=admin,dc=example,dc=com", "password")
+
+	require.NoError(t, err)
+}
+
+func TestShouldCloseConnectionWhenStartTLSFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:      "ldap://127.0.0.1:389",
+		StartTLS: true,
+	}, mockFactory)
+	require.NoError(t, err)
+
+	mockFactory.EXPECT().
+		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		StartTLS(gomock.Any()).
+		Return(errors.New("x509: certificate signed by unknown authority"))
+
+	mockConn.EXPECT().
+		Close()
+
+	_, err = ldap.connect("cn=admin,dc=example,dc=com", "password")
+
+	assert.EqualError(t, err, "Unable to upgrade the connection to TLS with StartTLS: x509: certificate signed by unknown authority")
+}
+
+func TestShouldDialTLSWithConfiguredServerNameAndMinimumVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL: "ldaps://10.0.0.1:636",
+		TLS: &schema.LDAPAuthenticationBackendTLSConfiguration{
+			MinimumVersion: "TLS1.3",
+			ServerName:     "ldap.example.com",
+		},
+	}, mockFactory)
+	require.NoError(t, err)
+
+	mockFactory.EXPECT().
+		DialTLS(gomock.Eq("tcp"), gomock.Eq("10.0.0.1:636"), NewTLSConfigMatcher("ldap.example.com", tls.VersionTLS13)).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	_, err = ldap.connect("cn=admin,dc=example,dc=com", "password")
+
+	require.NoError(t, err)
+}
+
+func TestShouldLoadTrustedCertificatesAndClientCertificate(t *testing.T) {
+	tlsConfig, err := newLDAPTLSConfig(schema.LDAPAuthenticationBackendConfiguration{
+		URL: "ldaps://ldap.example.com",
+		TLS: &schema.LDAPAuthenticationBackendTLSConfiguration{
+			TrustedCert: "../suites/common/ssl/cert.pem",
+			Cert:        "../suites/common/ssl/cert.pem",
+			Key:         "../suites/common/ssl/key.pem",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, "ldap.example.com", tlsConfig.ServerName)
+	assert.Equal(t, uint16(0), tlsConfig.MinVersion)
+}
+
+func TestShouldNotCreateProviderWhenTrustedCertificatesCannotBeRead(t *testing.T) {
+	_, err := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{
+		URL: "ldaps://ldap.example.com",
+		TLS: &schema.LDAPAuthenticationBackendTLSConfiguration{
+			TrustedCert: "/path/not/exist/ca.pem",
+		},
+	})
+
+	assert.EqualError(t, err, "Unable to read the trusted certificates: open /path/not/exist/ca.pem: no such file or directory")
+}
+
+type TLSConfigMatcher struct {
+	serverName string
+	minVersion uint16
+}
+
+func NewTLSConfigMatcher(serverName string, minVersion uint16) *TLSConfigMatcher {
+	return &TLSConfigMatcher{serverName, minVersion}
+}
+
+func (m *TLSConfigMatcher) Matches(x interface{}) bool {
+	config := x.(*tls.Config)
+	return config.ServerName == m.serverName && config.MinVersion == m.minVersion
+}
+
+func (m *TLSConfigMatcher) String() string {
+	return fmt.Sprintf("is a TLS configuration with server name %s and minimum version %x", m.serverName, m.minVersion)
+}
+
 func TestEscapeSpecialCharsFromUserInput(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
-	ldap := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL: "ldaps://127.0.0.1:389",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	// No escape
 	assert.Equal(t, "xyz", ldap.ldapEscape("xyz"))
@@ -91,10 +232,11 @@ func TestEscapeSpecialCharsInGroupsFilter(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
-	ldap := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldap, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:          "ldaps://127.0.0.1:389",
 		GroupsFilter: "(|(member={dn})(uid={username})(uid={input}))",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	profile := ldapUserProfile{
 		DN:          "cn=john (external),dc=example,dc=com",
@@ -134,7 +276,7 @@ func TestShouldEscapeUserInput(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldapClient := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:                  "ldap://127.0.0.1:389",
 		User:                 "cn=admin,dc=example,dc=com",
 		UsersFilter:          "(|({username_attribute}={input})({mail_attribute}={input}))",
@@ -145,6 +287,7 @@ func TestShouldEscapeUserInput(t *testing.T) {
 		AdditionalUsersDN:    "ou=users",
 		BaseDN:               "dc=example,dc=com",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockConn.EXPECT().
 		// Here we ensure that the input has been correctly escaped.
@@ -161,7 +304,7 @@ func TestShouldCombineUsernameFilterAndUsersFilter(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldapClient := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:                  "ldap://127.0.0.1:389",
 		User:                 "cn=admin,dc=example,dc=com",
 		UsernameAttribute:    "uid",
@@ -172,6 +315,7 @@ func TestShouldCombineUsernameFilterAndUsersFilter(t *testing.T) {
 		MailAttribute:        "mail",
 		DisplayNameAttribute: "displayname",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockConn.EXPECT().
 		Search(NewSearchRequestMatcher("(&(uid=john)(&(objectCategory=person)(objectClass=user)))")).
@@ -201,7 +345,7 @@ func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldapClient := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:                  "ldap://127.0.0.1:389",
 		User:                 "cn=admin,dc=example,dc=com",
 		Password:             "password",
@@ -212,6 +356,7 @@ func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
 		AdditionalUsersDN:    "ou=users",
 		BaseDN:               "dc=example,dc=com",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockFactory.EXPECT().
 		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
@@ -222,7 +367,8 @@ func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
 		Return(nil)
 
 	mockConn.EXPECT().
-		Close()
+		IsClosing().
+		Return(false)
 
 	searchGroups := mockConn.EXPECT().
 		Search(gomock.Any()).
@@ -269,7 +415,7 @@ func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldapClient := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:               "ldap://127.0.0.1:389",
 		User:              "cn=admin,dc=example,dc=com",
 		Password:          "password",
@@ -278,6 +424,7 @@ func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
 		AdditionalUsersDN: "ou=users",
 		BaseDN:            "dc=example,dc=com",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockFactory.EXPECT().
 		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
@@ -288,7 +435,8 @@ func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
 		Return(nil)
 
 	mockConn.EXPECT().
-		Close()
+		IsClosing().
+		Return(false)
 
 	searchGroups := mockConn.EXPECT().
 		Search(gomock.Any()).
@@ -326,7 +474,7 @@ func TestShouldReturnUsernameFromLDAP(t *testing.T) {
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
-	ldapClient := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
 		URL:                  "ldap://127.0.0.1:389",
 		User:                 "cn=admin,dc=example,dc=com",
 		Password:             "password",
@@ -337,6 +485,7 @@ func TestShouldReturnUsernameFromLDAP(t *testing.T) {
 		AdditionalUsersDN:    "ou=users",
 		BaseDN:               "dc=example,dc=com",
 	}, mockFactory)
+	require.NoError(t, err)
 
 	mockFactory.EXPECT().
 		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
@@ -347,7 +496,8 @@ func TestShouldReturnUsernameFromLDAP(t *testing.T) {
 		Return(nil)
 
 	mockConn.EXPECT().
-		Close()
+		IsClosing().
+		Return(false)
 
 	searchGroups := mockConn.EXPECT().
 		Search(gomock.Any()).
@@ -386,3 +536,505 @@ func TestShouldReturnUsernameFromLDAP(t *testing.T) {
 	assert.Equal(t, details.DisplayName, "John Doe")
 	assert.Equal(t, details.Username, "John")
 }
+
+func newNestedGroupsTestProvider(t *testing.T, mockFactory LDAPConnectionFactory, maxDepth int) *LDAPUserProvider {
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:                  "ldap://127.0.0.1:389",
+		User:                 "cn=admin,dc=example,dc=com",
+		Password:             "password",
+		UsernameAttribute:    "uid",
+		MailAttribute:        "mail",
+		DisplayNameAttribute: "displayname",
+		UsersFilter:          "uid={input}",
+		GroupsFilter:         "(member={dn})",
+		GroupNameAttribute:   "cn",
+		BaseDN:               "dc=example,dc=com",
+		NestedGroups:         true,
+		NestedGroupsMaxDepth: maxDepth,
+	}, mockFactory)
+	require.NoError(t, err)
+
+	return ldapClient
+}
+
+func expectNestedGroupsTestProfileSearch(mockFactory *MockLDAPConnectionFactory, mockConn *MockLDAPConnection) {
+	mockFactory.EXPECT().
+		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("uid=john")).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=john,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil)
+}
+
+func createSearchResultWithGroups(names ...string) *ldap.SearchResult {
+	result := &ldap.SearchResult{}
+
+	for _, name := range names {
+		result.Entries = append(result.Entries, &ldap.Entry{
+			DN: "cn=" + name + ",ou=groups,dc=example,dc=com",
+			Attributes: []*ldap.EntryAttribute{
+				{
+					Name:   "cn",
+					Values: []string{name},
+				},
+			},
+		})
+	}
+
+	return result
+}
+
+func TestShouldResolveNestedGroupsAndStopOnCycles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newNestedGroupsTestProvider(t, mockFactory, 10)
+
+	expectNestedGroupsTestProfileSearch(mockFactory, mockConn)
+
+	// john is a member of dev which is a member of staff, staff and admins are members of each other.
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("dev"), nil)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=cn=dev,ou=groups,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("staff"), nil)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=cn=staff,ou=groups,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("admins"), nil)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=cn=admins,ou=groups,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("staff", "dev"), nil)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dev", "staff", "admins"}, details.Groups)
+}
+
+func TestShouldStopResolvingNestedGroupsAtMaximumDepth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newNestedGroupsTestProvider(t, mockFactory, 1)
+
+	expectNestedGroupsTestProfileSearch(mockFactory, mockConn)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("dev"), nil)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=cn=dev,ou=groups,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("staff"), nil)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dev", "staff"}, details.Groups)
+}
+
+func TestShouldReturnErrorWhenNestedGroupsCannotBeRetrieved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newNestedGroupsTestProvider(t, mockFactory, 10)
+
+	expectNestedGroupsTestProfileSearch(mockFactory, mockConn)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+		Return(createSearchResultWithGroups("dev"), nil)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=cn=dev,ou=groups,dc=example,dc=com)")).
+		Return(nil, errors.New("size limit exceeded"))
+
+	_, err := ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "Unable to retrieve nested groups of user john. Cause: size limit exceeded")
+}
+
+func TestShouldReuseAdminConnectionAndBindUserWithNewConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockAdminConn := NewMockLDAPConnection(ctrl)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:               "ldap://127.0.0.1:389",
+		User:              "cn=admin,dc=example,dc=com",
+		Password:          "password",
+		UsernameAttribute: "uid",
+		UsersFilter:       "(uid={input})",
+		BaseDN:            "dc=example,dc=com",
+		PoolSize:          1,
+		PoolIdleTimeout:   "5m",
+	}, mockFactory)
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockAdminConn, nil),
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockUserConn, nil).
+			Times(2),
+	)
+
+	mockAdminConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockAdminConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=john,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil).
+		Times(2)
+
+	mockAdminConn.EXPECT().
+		IsClosing().
+		Return(false).
+		Times(3)
+
+	gomock.InOrder(
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockUserConn.EXPECT().
+			Close(),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("wrong_password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+		mockUserConn.EXPECT().
+			Close(),
+	)
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = ldapClient.CheckUserPassword("john", "wrong_password")
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestShouldRetryWithNewConnectionWhenPooledConnectionIsBroken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockBrokenConn := NewMockLDAPConnection(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:               "ldap://127.0.0.1:389",
+		User:              "cn=admin,dc=example,dc=com",
+		Password:          "password",
+		UsernameAttribute: "uid",
+		UsersFilter:       "(uid={input})",
+		BaseDN:            "dc=example,dc=com",
+		PoolSize:          1,
+		PoolIdleTimeout:   "5m",
+	}, mockFactory)
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockBrokenConn, nil),
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockConn, nil),
+	)
+
+	mockBrokenConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	// The connection is healthy when the first update completes but the server closes it before the second one.
+	gomock.InOrder(
+		mockBrokenConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithDN("uid=john,dc=example,dc=com"), nil),
+		mockBrokenConn.EXPECT().
+			Modify(gomock.Any()).
+			Return(nil),
+		mockBrokenConn.EXPECT().
+			IsClosing().
+			Return(false),
+		mockBrokenConn.EXPECT().
+			IsClosing().
+			Return(false),
+		mockBrokenConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset by peer"))),
+		mockBrokenConn.EXPECT().
+			Close(),
+	)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithDN("uid=john,dc=example,dc=com"), nil)
+
+	mockConn.EXPECT().
+		Modify(gomock.Any()).
+		Return(nil)
+
+	mockConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	require.NoError(t, ldapClient.UpdatePassword("john", "password"))
+	require.NoError(t, ldapClient.UpdatePassword("john", "newpassword"))
+}
+
+func createSearchResultWithDN(dn string) *ldap.SearchResult {
+	return &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{DN: dn},
+		},
+	}
+}
+
+func newAccountStateTestProvider(t *testing.T, mockFactory LDAPConnectionFactory, accountState string) *LDAPUserProvider {
+	ldapClient, err := NewLDAPUserProviderWithFactory(schema.LDAPAuthenticationBackendConfiguration{
+		URL:                  "ldap://127.0.0.1:389",
+		User:                 "cn=admin,dc=example,dc=com",
+		Password:             "password",
+		UsernameAttribute:    "uid",
+		MailAttribute:        "mail",
+		DisplayNameAttribute: "displayname",
+		UsersFilter:          "uid={input}",
+		GroupsFilter:         "(member={dn})",
+		BaseDN:               "dc=example,dc=com",
+		AccountState:         accountState,
+	}, mockFactory)
+	require.NoError(t, err)
+
+	return ldapClient
+}
+
+func createSearchResultWithAccountState(name, value string) *ldap.SearchResult {
+	return &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: "uid=john,dc=example,dc=com",
+				Attributes: []*ldap.EntryAttribute{
+					{
+						Name:   "uid",
+						Values: []string{"john"},
+					},
+					{
+						Name:   name,
+						Values: []string{value},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestShouldReturnPasswordExpiredWhenActiveDirectoryRefusesToBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockAdminConn := NewMockLDAPConnection(ctrl)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newAccountStateTestProvider(t, mockFactory, schema.LDAPAccountStateActiveDirectory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockAdminConn, nil),
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockUserConn, nil),
+	)
+
+	mockAdminConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockAdminConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAccountState("userAccountControl", "512"), nil)
+
+	mockAdminConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	gomock.InOrder(
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials,
+				errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 532, v4563"))),
+		mockUserConn.EXPECT().
+			Close(),
+	)
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	assert.Equal(t, ErrPasswordExpired, err)
+	assert.False(t, ok)
+}
+
+func TestShouldReturnPasswordExpiredWhenPPolicyPasswordHasBeenReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockAdminConn := NewMockLDAPConnection(ctrl)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newAccountStateTestProvider(t, mockFactory, schema.LDAPAccountStatePPolicy)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockAdminConn, nil),
+		mockFactory.EXPECT().
+			Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+			Return(mockUserConn, nil),
+	)
+
+	mockAdminConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockAdminConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAccountState("pwdReset", "TRUE"), nil)
+
+	mockAdminConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	gomock.InOrder(
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockUserConn.EXPECT().
+			Close(),
+	)
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	assert.Equal(t, ErrPasswordExpired, err)
+	assert.False(t, ok)
+}
+
+func TestShouldReturnUserDisabledWhenGettingDetailsOfDisabledAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newAccountStateTestProvider(t, mockFactory, schema.LDAPAccountStateActiveDirectory)
+
+	mockFactory.EXPECT().
+		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	// The groups are not searched.
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("uid=john")).
+		Return(createSearchResultWithAccountState("userAccountControl", "514"), nil)
+
+	mockConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	_, err := ldapClient.GetDetails("john")
+	assert.Equal(t, ErrUserDisabled, err)
+}
+
+func TestShouldUpdateActiveDirectoryPasswordThroughUnicodePwd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newAccountStateTestProvider(t, mockFactory, schema.LDAPAccountStateActiveDirectory)
+
+	mockFactory.EXPECT().
+		Dial(gomock.Eq("tcp"), gomock.Eq("127.0.0.1:389")).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAccountState("pwdLastSet", "0"), nil)
+
+	modifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", nil)
+	modifyRequest.Replace("unicodePwd", []string{"\"\x00p\x00w\x00\"\x00"})
+
+	mockConn.EXPECT().
+		Modify(gomock.Eq(modifyRequest)).
+		Return(nil)
+
+	mockConn.EXPECT().
+		IsClosing().
+		Return(false)
+
+	require.NoError(t, ldapClient.UpdatePassword("john", "pw"))
+}
@@ -8,11 +8,13 @@ import (
 
 	"github.com/simia-tech/crypt"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
 )
 
 // PasswordHash represents all characteristics of a password hash.
-// Authelia only supports salted SHA512 or salted argon2id method, i.e., $6$ mode or $argon2id$ mode.
+// Authelia hashes passwords with salted SHA512 or salted argon2id method, i.e., $6$ mode or $argon2id$ mode. It also
+// verifies bcrypt, scrypt and PBKDF2 hashes so that users can be imported from other systems.
 type PasswordHash struct {
 	Algorithm   CryptAlgo
 	Iterations  int
@@ -21,6 +23,11 @@ type PasswordHash struct {
 	KeyLength   int
 	Memory      int
 	Parallelism int
+	BlockSize   int
+
+	// salt and key the decoded salt and key of the scrypt and PBKDF2 hashes.
+	salt []byte
+	key  []byte
 }
 
 // ConfigAlgoToCryptoAlgo returns a CryptAlgo and nil error if valid, otherwise it returns argon2id and an error.
@@ -37,6 +44,10 @@ func ConfigAlgoToCryptoAlgo(fromConfig string) (CryptAlgo, error) {
 
 // ParseHash extracts all characteristics of a hash given its string representation.
 func ParseHash(hash string) (passwordHash *PasswordHash, err error) {
+	if h, ok, err := parseOtherHash(hash); ok {
+		return h, err
+	}
+
 	parts := strings.Split(hash, "$")
 
 	// This error can be ignored as it's always nil.
@@ -96,7 +107,7 @@ func ParseHash(hash string) (passwordHash *PasswordHash, err error) {
 			return nil, fmt.Errorf("Argon2id key length parameter (%d) does not match the actual key length (%d)", h.KeyLength, len(decodedKey))
 		}
 	default:
-		return nil, fmt.Errorf("Authelia only supports salted SHA512 hashing ($6$) and salted argon2id ($argon2id$), not $%s$", code)
+		return nil, fmt.Errorf("Authelia only supports salted SHA512 hashing ($6$), salted argon2id ($argon2id$), bcrypt ($2a$, $2b$, $2y$), scrypt ($scrypt$) and PBKDF2 ($pbkdf2$, $pbkdf2-sha256$, $pbkdf2-sha512$), not $%s$", code)
 	}
 
 	return h, nil
@@ -106,8 +117,14 @@ func ParseHash(hash string) (passwordHash *PasswordHash, err error) {
 func HashPassword(password, salt string, algorithm CryptAlgo, iterations, memory, parallelism, keyLength, saltLength int) (hash string, err error) {
 	var settings string
 
-	if algorithm != HashingAlgorithmArgon2id && algorithm != HashingAlgorithmSHA512 {
-		return "", fmt.Errorf("Hashing algorithm input of '%s' is invalid, only values of %s and %s are supported", algorithm, HashingAlgorithmArgon2id, HashingAlgorithmSHA512)
+	switch algorithm {
+	case HashingAlgorithmArgon2id, HashingAlgorithmSHA512:
+	case HashingAlgorithmBcrypt, HashingAlgorithmScrypt, HashingAlgorithmPBKDF2SHA1, HashingAlgorithmPBKDF2SHA256, HashingAlgorithmPBKDF2SHA512:
+		return hashOtherPassword(password, salt, algorithm, iterations, parallelism, keyLength, saltLength)
+	default:
+		return "", fmt.Errorf("Hashing algorithm input of '%s' is invalid, only values of %s, %s, %s, %s, %s, %s and %s are supported",
+			algorithm, HashingAlgorithmArgon2id, HashingAlgorithmSHA512, HashingAlgorithmBcrypt, HashingAlgorithmScrypt,
+			HashingAlgorithmPBKDF2SHA1, HashingAlgorithmPBKDF2SHA256, HashingAlgorithmPBKDF2SHA512)
 	}
 
 	if algorithm == HashingAlgorithmArgon2id {
@@ -134,6 +151,56 @@ func HashPassword(password, salt string, algorithm CryptAlgo, iterations, memory
 	return hash, nil
 }
 
+// hashPasswordWithConfiguration hash the password with the algorithm and the parameters of the configuration.
+func hashPasswordWithConfiguration(password string, configuration *schema.PasswordConfiguration) (string, error) {
+	algorithm, err := ConfigAlgoToCryptoAlgo(configuration.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	return HashPassword(
+		password, "", algorithm, configuration.Iterations,
+		configuration.Memory*1024, configuration.Parallelism,
+		configuration.KeyLength, configuration.SaltLength)
+}
+
+// needsRehash returns true when the hash has not been generated with the algorithm and the parameters of the
+// configuration, i.e., when the password should be hashed again to benefit from the configuration.
+func needsRehash(hash *PasswordHash, configuration *schema.PasswordConfiguration) bool {
+	algorithm, err := ConfigAlgoToCryptoAlgo(configuration.Algorithm)
+	if err != nil {
+		return false
+	}
+
+	if hash.Algorithm != algorithm || hash.Iterations != configuration.Iterations {
+		return true
+	}
+
+	if algorithm != HashingAlgorithmArgon2id {
+		return false
+	}
+
+	return hash.Memory != configuration.Memory*1024 || hash.Parallelism != configuration.Parallelism ||
+		hash.KeyLength != configuration.KeyLength
+}
+
+// newFakeHash generate a hash with the parameters of the configuration which no password matches. Checking a
+// password against it when a user does not exist takes the same time as for an existing user.
+// TODO: Remove this. This is only here to temporarily fix the username enumeration security flaw in #949.
+func newFakeHash(configuration *schema.PasswordConfiguration) string {
+	var cryptAlgo CryptAlgo = HashingAlgorithmArgon2id
+	if configuration.Algorithm == sha512 {
+		cryptAlgo = HashingAlgorithmSHA512
+	}
+
+	settings := getCryptSettings(utils.RandomString(configuration.SaltLength, HashingPossibleSaltCharacters),
+		cryptAlgo, configuration.Iterations, configuration.Memory*1024, configuration.Parallelism,
+		configuration.KeyLength)
+	data := crypt.Base64Encoding.EncodeToString([]byte(utils.RandomString(configuration.KeyLength, HashingPossibleSaltCharacters)))
+
+	return fmt.Sprintf("%s$%s", settings, data)
+}
+
 // CheckPassword check a password against a hash.
 func CheckPassword(password, hash string) (ok bool, err error) {
 	expectedHash, err := ParseHash(hash)
@@ -141,6 +208,10 @@ func CheckPassword(password, hash string) (ok bool, err error) {
 		return false, err
 	}
 
+	if expectedHash.Algorithm != HashingAlgorithmArgon2id && expectedHash.Algorithm != HashingAlgorithmSHA512 {
+		return checkOtherPassword(password, expectedHash)
+	}
+
 	passwordHashString, err := HashPassword(password, expectedHash.Salt, expectedHash.Algorithm, expectedHash.Iterations, expectedHash.Memory, expectedHash.Parallelism, expectedHash.KeyLength, len(expectedHash.Salt))
 	if err != nil {
 		return false, err
@@ -0,0 +1,293 @@
+package authentication
+
+import (
+	"crypto/sha1" //nolint:gosec // Only used to verify the PBKDF2-SHA1 hashes imported from other systems.
+	"crypto/sha256"
+	cryptosha512 "crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/simia-tech/crypt"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// The hashes below are not generated by default, they are supported so that users can be imported from other systems,
+// like htpasswd files or Django applications, and their passwords hashed again with the configured algorithm once
+// they log in.
+
+// adaptedBase64Encoding is the base64 encoding of the salts and keys of the PBKDF2 hashes in the modular crypt format,
+// i.e., the standard alphabet with '.' instead of '+' and without padding. The salts and keys of the scrypt hashes are
+// encoded with the standard alphabet without padding instead.
+var adaptedBase64Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").
+	WithPadding(base64.NoPadding)
+
+// pbkdf2Digests maps the PBKDF2 algorithms to their digest.
+var pbkdf2Digests = map[CryptAlgo]func() hash.Hash{
+	HashingAlgorithmPBKDF2SHA1:   sha1.New,
+	HashingAlgorithmPBKDF2SHA256: sha256.New,
+	HashingAlgorithmPBKDF2SHA512: cryptosha512.New,
+}
+
+// parseOtherHash extracts the characteristics of a bcrypt, scrypt or PBKDF2 hash. The second value is false if the
+// hash is not one of them.
+func parseOtherHash(hash string) (*PasswordHash, bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		h, err := parseBcryptHash(hash)
+		return h, true, err
+	case strings.HasPrefix(hash, "bcrypt$$2"):
+		// Django prefixes the bcrypt hashes with the name of its hasher.
+		h, err := parseBcryptHash(strings.TrimPrefix(hash, "bcrypt$"))
+		return h, true, err
+	case strings.HasPrefix(hash, "$scrypt$"):
+		h, err := parseScryptHash(hash)
+		return h, true, err
+	case strings.HasPrefix(hash, "$pbkdf2"):
+		h, err := parsePBKDF2Hash(hash)
+		return h, true, err
+	case strings.HasPrefix(hash, "pbkdf2_"):
+		h, err := parseDjangoPBKDF2Hash(hash)
+		return h, true, err
+	}
+
+	return nil, false, nil
+}
+
+func parseBcryptHash(hash string) (*PasswordHash, error) {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return nil, fmt.Errorf("Bcrypt hash is malformed: %v", err)
+	}
+
+	return &PasswordHash{
+		Algorithm:  HashingAlgorithmBcrypt,
+		Iterations: cost,
+		Key:        hash,
+	}, nil
+}
+
+// parseScryptHash parses a hash formatted as $scrypt$ln=<log2(N)>,r=<block size>,p=<parallelism>$<salt>$<key>.
+func parseScryptHash(hash string) (*PasswordHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("Scrypt hash is malformed (%s)", hash)
+	}
+
+	h := &PasswordHash{Algorithm: HashingAlgorithmScrypt, Salt: parts[3], Key: parts[4]}
+
+	for _, parameter := range strings.Split(parts[2], ",") {
+		kv := strings.SplitN(parameter, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Scrypt parameter %s is malformed", parameter)
+		}
+
+		value, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("Scrypt parameter %s is not numeric", parameter)
+		}
+
+		switch kv[0] {
+		case "ln":
+			h.Iterations = value
+		case "r":
+			h.BlockSize = value
+		case "p":
+			h.Parallelism = value
+		default:
+			return nil, fmt.Errorf("Scrypt parameter %s is unknown", kv[0])
+		}
+	}
+
+	if h.Iterations < 1 || h.Iterations > 31 || h.BlockSize < 1 || h.Parallelism < 1 {
+		return nil, fmt.Errorf("Scrypt parameters are invalid (%s)", parts[2])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(h.Salt)
+	if err != nil {
+		return nil, errors.New("Salt contains invalid base64 characters")
+	}
+
+	return h, decodeKey(h, base64.RawStdEncoding, salt)
+}
+
+// parsePBKDF2Hash parses a hash formatted as $pbkdf2[-<digest>]$<iterations>$<salt>$<key>.
+func parsePBKDF2Hash(hash string) (*PasswordHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("PBKDF2 hash is malformed (%s)", hash)
+	}
+
+	h := &PasswordHash{Algorithm: CryptAlgo(parts[1]), Salt: parts[3], Key: parts[4]}
+
+	if _, ok := pbkdf2Digests[h.Algorithm]; !ok {
+		return nil, fmt.Errorf("PBKDF2 digest of %s is not supported", h.Algorithm)
+	}
+
+	salt, err := adaptedBase64Encoding.DecodeString(h.Salt)
+	if err != nil {
+		return nil, errors.New("Salt contains invalid base64 characters")
+	}
+
+	if h.Iterations, err = strconv.Atoi(parts[2]); err != nil || h.Iterations < 1 {
+		return nil, fmt.Errorf("PBKDF2 iterations is not a positive number (%s)", parts[2])
+	}
+
+	return h, decodeKey(h, adaptedBase64Encoding, salt)
+}
+
+// parseDjangoPBKDF2Hash parses a hash formatted by Django as pbkdf2_<digest>$<iterations>$<salt>$<key>.
+func parseDjangoPBKDF2Hash(hash string) (*PasswordHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("PBKDF2 hash is malformed (%s)", hash)
+	}
+
+	var algorithm CryptAlgo
+
+	switch parts[0] {
+	case "pbkdf2_sha1":
+		algorithm = HashingAlgorithmPBKDF2SHA1
+	case "pbkdf2_sha256":
+		algorithm = HashingAlgorithmPBKDF2SHA256
+	default:
+		return nil, fmt.Errorf("PBKDF2 digest of %s is not supported", parts[0])
+	}
+
+	h := &PasswordHash{Algorithm: algorithm, Salt: parts[2], Key: parts[3]}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return nil, fmt.Errorf("PBKDF2 iterations is not a positive number (%s)", parts[1])
+	}
+
+	h.Iterations = iterations
+
+	// The salt is used as is by Django.
+	return h, decodeKey(h, base64.StdEncoding, []byte(h.Salt))
+}
+
+func decodeKey(h *PasswordHash, encoding *base64.Encoding, salt []byte) error {
+	if h.Key == "" {
+		return errors.New("Hash key contains no characters")
+	}
+
+	key, err := encoding.DecodeString(h.Key)
+	if err != nil {
+		return errors.New("Hash key contains invalid base64 characters")
+	}
+
+	h.KeyLength = len(key)
+	h.salt = salt
+	h.key = key
+
+	return nil
+}
+
+// checkOtherPassword checks a password against a bcrypt, scrypt or PBKDF2 hash.
+func checkOtherPassword(password string, h *PasswordHash) (bool, error) {
+	if h.Algorithm == HashingAlgorithmBcrypt {
+		err := bcrypt.CompareHashAndPassword([]byte(h.Key), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+
+	key, err := deriveKey(password, h.salt, h.Algorithm, h.Iterations, h.BlockSize, h.Parallelism, h.KeyLength)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func deriveKey(password string, salt []byte, algorithm CryptAlgo, iterations, blockSize, parallelism, keyLength int) ([]byte, error) {
+	if algorithm == HashingAlgorithmScrypt {
+		return scrypt.Key([]byte(password), salt, 1<<uint(iterations), blockSize, parallelism, keyLength)
+	}
+
+	return pbkdf2.Key([]byte(password), salt, iterations, keyLength, pbkdf2Digests[algorithm]), nil
+}
+
+// hashOtherPassword hashes a password with bcrypt, scrypt or PBKDF2. The iterations are the cost for bcrypt and the
+// log2 of the CPU/memory cost for scrypt.
+func hashOtherPassword(password, salt string, algorithm CryptAlgo, iterations, parallelism, keyLength, saltLength int) (string, error) {
+	if algorithm == HashingAlgorithmBcrypt {
+		if salt != "" {
+			return "", errors.New("Salt input is not supported by bcrypt, the salt is always generated")
+		}
+
+		// bcrypt silently replaces a cost below its minimum with its default cost.
+		if iterations < bcrypt.MinCost || iterations > bcrypt.MaxCost {
+			return "", fmt.Errorf("Iterations (bcrypt) input of %d is invalid, it must be between %d and %d",
+				iterations, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), iterations)
+
+		return string(hash), err
+	}
+
+	if err := validateSalt(salt, saltLength); err != nil {
+		return "", err
+	}
+
+	rawSalt := []byte(utils.RandomString(saltLength, HashingPossibleSaltCharacters))
+
+	if salt != "" {
+		// The salt has been validated above.
+		rawSalt, _ = crypt.Base64Encoding.DecodeString(salt)
+	}
+
+	if keyLength < 16 {
+		return "", fmt.Errorf("Key length input of %d is invalid, it must be 16 or higher", keyLength)
+	}
+
+	if err := validateDerivedKeySettings(algorithm, iterations, parallelism); err != nil {
+		return "", err
+	}
+
+	key, err := deriveKey(password, rawSalt, algorithm, iterations, HashingDefaultScryptBlockSize, parallelism, keyLength)
+	if err != nil {
+		return "", err
+	}
+
+	if algorithm == HashingAlgorithmScrypt {
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", iterations, HashingDefaultScryptBlockSize, parallelism,
+			base64.RawStdEncoding.EncodeToString(rawSalt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	return fmt.Sprintf("$%s$%d$%s$%s", algorithm, iterations,
+		adaptedBase64Encoding.EncodeToString(rawSalt), adaptedBase64Encoding.EncodeToString(key)), nil
+}
+
+// validateDerivedKeySettings checks the scrypt and PBKDF2 settings are valid.
+func validateDerivedKeySettings(algorithm CryptAlgo, iterations, parallelism int) error {
+	if algorithm != HashingAlgorithmScrypt {
+		if iterations < 1 {
+			return fmt.Errorf("Iterations (PBKDF2) input of %d is invalid, it must be 1 or more", iterations)
+		}
+
+		return nil
+	}
+
+	if iterations < 1 || iterations > 31 {
+		return fmt.Errorf("Iterations (scrypt) input of %d is invalid, it must be between 1 and 31", iterations)
+	}
+
+	if parallelism < 1 {
+		return fmt.Errorf("Parallelism (scrypt) input of %d is invalid, it must be 1 or higher", parallelism)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldCheckPasswordOfOtherHashes(t *testing.T) {
+	hashes := map[string]string{
+		"bcrypt":         "$2b$04$abcdefghijklmnopqrstuughE8Ev8uGFaUgY2cNEySvxngrb/Jzdm",
+		"bcrypt 2y":      "$2y$04$abcdefghijklmnopqrstuughE8Ev8uGFaUgY2cNEySvxngrb/Jzdm",
+		"bcrypt django":  "bcrypt$$2b$04$abcdefghijklmnopqrstuughE8Ev8uGFaUgY2cNEySvxngrb/Jzdm",
+		"scrypt":         "$scrypt$ln=10,r=8,p=1$YUZyNTZIakszRHJCOHQzUw$oN1oHbsG3yAJP8XifVvqzKMAJTmuC8guzSGm6vsyXYs",
+		"scrypt passlib": "$scrypt$ln=10,r=8,p=1$YUZyNTZIakszRHJCMDAwMQ$2WD0miH3ECc/V+opimL6dJpUZ1kOjUBiTnjud7hQPAM",
+		"pbkdf2-sha256":  "$pbkdf2-sha256$1000$YUZyNTZIakszRHJCOHQzUw$u6jZ7VfE1T7zLZisK.MUOTQD7ND7q8bONQSTXZmpfwg",
+		"pbkdf2-sha512":  "$pbkdf2-sha512$1000$YUZyNTZIakszRHJCOHQzUw$d5ithe5flHlMZ0qjHde4TFZhuDPB.kfQjEe/C9FffSKOPexMbpDdk5aM.A0qw0KuXGl6Wy43NybFZWDFzlrNuQ",
+		"pbkdf2 django":  "pbkdf2_sha256$1000$aFr56HjK3DrB8t3S$u6jZ7VfE1T7zLZisK+MUOTQD7ND7q8bONQSTXZmpfwg=",
+	}
+
+	for name, hash := range hashes {
+		t.Run(name, func(t *testing.T) {
+			ok, err := CheckPassword("password", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = CheckPassword("wrong_password", hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestShouldParseOtherHashes(t *testing.T) {
+	hash, err := ParseHash("$2y$04$abcdefghijklmnopqrstuughE8Ev8uGFaUgY2cNEySvxngrb/Jzdm")
+	require.NoError(t, err)
+	assert.Equal(t, HashingAlgorithmBcrypt, hash.Algorithm)
+	assert.Equal(t, 4, hash.Iterations)
+
+	hash, err = ParseHash("$scrypt$ln=10,r=8,p=1$YUZyNTZIakszRHJCOHQzUw$oN1oHbsG3yAJP8XifVvqzKMAJTmuC8guzSGm6vsyXYs")
+	require.NoError(t, err)
+	assert.Equal(t, HashingAlgorithmScrypt, hash.Algorithm)
+	assert.Equal(t, 10, hash.Iterations)
+	assert.Equal(t, 8, hash.BlockSize)
+	assert.Equal(t, 1, hash.Parallelism)
+	assert.Equal(t, 32, hash.KeyLength)
+
+	hash, err = ParseHash("pbkdf2_sha256$1000$aFr56HjK3DrB8t3S$u6jZ7VfE1T7zLZisK+MUOTQD7ND7q8bONQSTXZmpfwg=")
+	require.NoError(t, err)
+	assert.Equal(t, HashingAlgorithmPBKDF2SHA256, hash.Algorithm)
+	assert.Equal(t, 1000, hash.Iterations)
+	assert.Equal(t, 32, hash.KeyLength)
+}
+
+func TestShouldNotParseMalformedOtherHashes(t *testing.T) {
+	_, err := ParseHash("$scrypt$ln=10,r=8$YUZyNTZIakszRHJCOHQzUw")
+	assert.EqualError(t, err, "Scrypt hash is malformed ($scrypt$ln=10,r=8$YUZyNTZIakszRHJCOHQzUw)")
+
+	_, err = ParseHash("$scrypt$ln=abc,r=8,p=1$YUZyNTZIakszRHJCOHQzUw$oN1oHbsG3yAJP8XifVvqzKMAJTmuC8guzSGm6vsyXYs")
+	assert.EqualError(t, err, "Scrypt parameter ln=abc is not numeric")
+
+	_, err = ParseHash("$pbkdf2-md5$1000$YUZyNTZIakszRHJCOHQzUw$u6jZ7VfE1T7zLZisK.MUOTQD7ND7q8bONQSTXZmpfwg")
+	assert.EqualError(t, err, "PBKDF2 digest of pbkdf2-md5 is not supported")
+
+	_, err = ParseHash("pbkdf2_sha256$abc$aFr56HjK3DrB8t3S$u6jZ7VfE1T7zLZisK+MUOTQD7ND7q8bONQSTXZmpfwg=")
+	assert.EqualError(t, err, "PBKDF2 iterations is not a positive number (abc)")
+}
+
+func TestShouldHashPasswordWithOtherAlgorithms(t *testing.T) {
+	algorithms := map[CryptAlgo]int{
+		HashingAlgorithmBcrypt:       4,
+		HashingAlgorithmScrypt:       10,
+		HashingAlgorithmPBKDF2SHA1:   1000,
+		HashingAlgorithmPBKDF2SHA256: 1000,
+		HashingAlgorithmPBKDF2SHA512: 1000,
+	}
+
+	for algorithm, iterations := range algorithms {
+		t.Run(string(algorithm), func(t *testing.T) {
+			hash, err := HashPassword(testPassword, "", algorithm, iterations, 0, 1, 32, 16)
+			require.NoError(t, err)
+
+			passwordHash, err := ParseHash(hash)
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, passwordHash.Algorithm)
+			assert.Equal(t, iterations, passwordHash.Iterations)
+
+			ok, err := CheckPassword(testPassword, hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestShouldNotHashBcryptPasswordWithSalt(t *testing.T) {
+	_, err := HashPassword(testPassword, "BpLnfgDsc2WD8F2q", HashingAlgorithmBcrypt, 4, 0, 1, 32, 16)
+	assert.EqualError(t, err, "Salt input is not supported by bcrypt, the salt is always generated")
+}
+
+func TestShouldNotHashBcryptPasswordWithInvalidCost(t *testing.T) {
+	_, err := HashPassword(testPassword, "", HashingAlgorithmBcrypt, 3, 0, 1, 32, 16)
+	assert.EqualError(t, err, "Iterations (bcrypt) input of 3 is invalid, it must be between 4 and 31")
+
+	_, err = HashPassword(testPassword, "", HashingAlgorithmBcrypt, 32, 0, 1, 32, 16)
+	assert.EqualError(t, err, "Iterations (bcrypt) input of 32 is invalid, it must be between 4 and 31")
+}
@@ -91,7 +91,7 @@ func TestShouldNotHashPasswordWithNonExistentAlgorithm(t *testing.T) {
 		schema.DefaultCIPasswordConfiguration.SaltLength)
 
 	assert.Equal(t, "", hash)
-	assert.EqualError(t, err, "Hashing algorithm input of 'bogus' is invalid, only values of argon2id and 6 are supported")
+	assert.EqualError(t, err, "Hashing algorithm input of 'bogus' is invalid, only values of argon2id, 6, 2a, scrypt, pbkdf2, pbkdf2-sha256 and pbkdf2-sha512 are supported")
 }
 
 func TestShouldNotHashArgon2idPasswordDueToMemoryParallelismMismatch(t *testing.T) {
@@ -256,7 +256,7 @@ func TestCannotParseArgon2idHash(t *testing.T) {
 func TestOnlySupportSHA512AndArgon2id(t *testing.T) {
 	ok, err := CheckPassword("password", "$8$rounds=50000$aFr56HjK3DrB8t3S$zhPQiS85cgBlNhUKKE6n/AHMlpqrvYSnSL3fEVkK0yHFQ.oFFAd8D4OhPAy18K5U61Z2eBhxQXExGU/eknXlY1")
 
-	assert.EqualError(t, err, "Authelia only supports salted SHA512 hashing ($6$) and salted argon2id ($argon2id$), not $8$")
+	assert.EqualError(t, err, "Authelia only supports salted SHA512 hashing ($6$), salted argon2id ($argon2id$), bcrypt ($2a$, $2b$, $2y$), scrypt ($scrypt$) and PBKDF2 ($pbkdf2$, $pbkdf2-sha256$, $pbkdf2-sha512$), not $8$")
 	assert.False(t, ok)
 }
 
@@ -322,3 +322,37 @@ func TestShouldCheckPasswordSHA512HashedWithAuthelia(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, equal)
 }
+
+func TestShouldNeedRehashWhenParametersDiffer(t *testing.T) {
+	configuration := schema.DefaultCIPasswordConfiguration
+
+	hash, err := HashPassword(testPassword, "", HashingAlgorithmArgon2id, configuration.Iterations,
+		configuration.Memory*1024, configuration.Parallelism, configuration.KeyLength, configuration.SaltLength)
+	require.NoError(t, err)
+
+	passwordHash, err := ParseHash(hash)
+	require.NoError(t, err)
+	assert.False(t, needsRehash(passwordHash, &configuration))
+
+	configuration.Memory *= 2
+	assert.True(t, needsRehash(passwordHash, &configuration))
+
+	configuration = schema.DefaultCIPasswordConfiguration
+	configuration.Iterations++
+	assert.True(t, needsRehash(passwordHash, &configuration))
+
+	assert.True(t, needsRehash(passwordHash, &schema.DefaultPasswordSHA512Configuration))
+}
+
+func TestShouldNeedRehashOfSHA512HashOnlyWhenAlgorithmOrRoundsDiffer(t *testing.T) {
+	configuration := schema.DefaultPasswordSHA512Configuration
+
+	passwordHash, err := ParseHash("$6$rounds=50000$aFr56HjK3DrB8t3S$zhPQiS85cgBlNhUKKE6n/AHMlpqrvYSnSL3fEVkK0yHFQ.oFFAd8D4OhPAy18K5U61Z2eBhxQXExGU/eknXlY1")
+	require.NoError(t, err)
+	assert.False(t, needsRehash(passwordHash, &configuration))
+
+	configuration.Iterations = 100000
+	assert.True(t, needsRehash(passwordHash, &configuration))
+
+	assert.True(t, needsRehash(passwordHash, &schema.DefaultCIPasswordConfiguration))
+}
@@ -0,0 +1,84 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// SQLUserProvider is a provider reading the users from the tables of the SQL database configured as storage backend.
+type SQLUserProvider struct {
+	configuration *schema.SQLAuthenticationBackendConfiguration
+	database      storage.UserDatabase
+
+	// TODO: Remove this. This is only here to temporarily fix the username enumeration security flaw in #949.
+	fakeHash string
+}
+
+// NewSQLUserProvider creates a new instance of SQLUserProvider.
+func NewSQLUserProvider(configuration *schema.SQLAuthenticationBackendConfiguration, database storage.UserDatabase) *SQLUserProvider {
+	return &SQLUserProvider{
+		configuration: configuration,
+		database:      database,
+		fakeHash:      newFakeHash(configuration.Password),
+	}
+}
+
+// CheckUserPassword checks if provided password matches for the given user.
+func (p *SQLUserProvider) CheckUserPassword(username string, password string) (bool, error) {
+	user, err := p.database.LoadUser(context.Background(), username)
+	if err == storage.ErrNoUser {
+		// TODO: Remove this. This is only here to temporarily fix the username enumeration security flaw in #949.
+		_, _ = CheckPassword(password, p.fakeHash)
+
+		return false, ErrUserNotFound
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return CheckPassword(password, strings.ReplaceAll(user.HashedPassword, "{CRYPT}", ""))
+}
+
+// GetDetails retrieve the details of a user.
+func (p *SQLUserProvider) GetDetails(username string) (*UserDetails, error) {
+	user, err := p.database.LoadUser(context.Background(), username)
+	if err == storage.ErrNoUser {
+		return nil, fmt.Errorf("User '%s' does not exist in database", username)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	details := &UserDetails{
+		Username:    username,
+		DisplayName: user.DisplayName,
+		Groups:      user.Groups,
+	}
+
+	if user.Email != "" {
+		details.Emails = []string{user.Email}
+	}
+
+	return details, nil
+}
+
+// UpdatePassword update the password of the given user.
+func (p *SQLUserProvider) UpdatePassword(username string, newPassword string) error {
+	hash, err := hashPasswordWithConfiguration(newPassword, p.configuration.Password)
+	if err != nil {
+		return err
+	}
+
+	err = p.database.UpdateUserPassword(context.Background(), username, hash)
+	if err == storage.ErrNoUser {
+		return ErrUserNotFound
+	}
+
+	return err
+}
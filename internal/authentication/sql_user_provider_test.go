@@ -0,0 +1,156 @@
+package authentication
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+func newTestSQLUserProvider(database storage.UserDatabase) *SQLUserProvider {
+	password := schema.DefaultPasswordSHA512Configuration
+
+	return NewSQLUserProvider(&schema.SQLAuthenticationBackendConfiguration{Password: &password}, database)
+}
+
+func TestShouldCheckPasswordOfSQLUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	hash, err := HashPassword(testPassword, "", HashingAlgorithmSHA512, 5000, 0, 0, 0, 16)
+	require.NoError(t, err)
+
+	database.EXPECT().
+		LoadUser(gomock.Any(), gomock.Eq("john")).
+		Return(&models.User{Username: "john", HashedPassword: "{CRYPT}" + hash}, nil).
+		Times(2)
+
+	ok, err := provider.CheckUserPassword("john", testPassword)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = provider.CheckUserPassword("john", "wrong")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestShouldNotCheckPasswordOfUnknownSQLUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	database.EXPECT().
+		LoadUser(gomock.Any(), gomock.Eq("fake")).
+		Return(nil, storage.ErrNoUser)
+
+	ok, err := provider.CheckUserPassword("fake", testPassword)
+	assert.Equal(t, ErrUserNotFound, err)
+	assert.False(t, ok)
+}
+
+func TestShouldRetrieveDetailsOfSQLUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	database.EXPECT().
+		LoadUser(gomock.Any(), gomock.Eq("john")).
+		Return(&models.User{
+			Username:    "john",
+			DisplayName: "John Doe",
+			Email:       "john.doe@authelia.com",
+			Groups:      []string{"admins", "dev"},
+		}, nil)
+
+	details, err := provider.GetDetails("john")
+	require.NoError(t, err)
+	assert.Equal(t, &UserDetails{
+		Username:    "john",
+		DisplayName: "John Doe",
+		Emails:      []string{"john.doe@authelia.com"},
+		Groups:      []string{"admins", "dev"},
+	}, details)
+}
+
+func TestShouldNotRetrieveEmailsOfSQLUserWithoutEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	database.EXPECT().
+		LoadUser(gomock.Any(), gomock.Eq("john")).
+		Return(&models.User{Username: "john", DisplayName: "John Doe"}, nil)
+
+	details, err := provider.GetDetails("john")
+	require.NoError(t, err)
+	assert.Len(t, details.Emails, 0)
+}
+
+func TestShouldReturnErrorWhenLoadingSQLUserFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	database.EXPECT().
+		LoadUser(gomock.Any(), gomock.Eq("john")).
+		Return(nil, errors.New("connection refused"))
+
+	_, err := provider.GetDetails("john")
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestShouldUpdatePasswordOfSQLUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	var hash string
+
+	database.EXPECT().
+		UpdateUserPassword(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		DoAndReturn(func(_ interface{}, _ string, hashedPassword string) error {
+			hash = hashedPassword
+			return nil
+		})
+
+	require.NoError(t, provider.UpdatePassword("john", "newpassword"))
+	assert.True(t, strings.HasPrefix(hash, "$6$rounds=50000$"))
+
+	ok, err := CheckPassword("newpassword", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestShouldNotUpdatePasswordOfUnknownSQLUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	database := storage.NewMockUserDatabase(ctrl)
+	provider := newTestSQLUserProvider(database)
+
+	database.EXPECT().
+		UpdateUserPassword(gomock.Any(), gomock.Eq("fake"), gomock.Any()).
+		Return(storage.ErrNoUser)
+
+	assert.Equal(t, ErrUserNotFound, provider.UpdatePassword("fake", "newpassword"))
+}
@@ -13,6 +13,9 @@ import (
 
 func init() {
 	HashPasswordCmd.Flags().BoolP("sha512", "z", false, fmt.Sprintf("use sha512 as the algorithm (changes iterations to %d, change with -i)", schema.DefaultPasswordSHA512Configuration.Iterations))
+	HashPasswordCmd.Flags().Bool("bcrypt", false, fmt.Sprintf("use bcrypt as the algorithm, the iterations are the cost between 4 and 31 (changes iterations to %d, change with -i)", authentication.HashingDefaultBcryptCost))
+	HashPasswordCmd.Flags().Bool("scrypt", false, fmt.Sprintf("use scrypt as the algorithm, the iterations are the log2 of the cost (changes iterations to %d and parallelism to %d, change with -i and -p)", authentication.HashingDefaultScryptIterations, authentication.HashingDefaultScryptParallelism))
+	HashPasswordCmd.Flags().Bool("pbkdf2", false, fmt.Sprintf("use PBKDF2 with SHA256 as the algorithm (changes iterations to %d, change with -i)", authentication.HashingDefaultPBKDF2Iterations))
 	HashPasswordCmd.Flags().IntP("iterations", "i", schema.DefaultPasswordConfiguration.Iterations, "set the number of hashing iterations")
 	HashPasswordCmd.Flags().StringP("salt", "s", "", "set the salt string")
 	HashPasswordCmd.Flags().IntP("memory", "m", schema.DefaultPasswordConfiguration.Memory, "[argon2id] set the amount of memory param (in MB)")
@@ -26,7 +29,6 @@ var HashPasswordCmd = &cobra.Command{
 	Use:   "hash-password [password]",
 	Short: "Hash a password to be used in file-based users database. Default algorithm is argon2id.",
 	Run: func(cobraCmd *cobra.Command, args []string) {
-		sha512, _ := cobraCmd.Flags().GetBool("sha512")
 		iterations, _ := cobraCmd.Flags().GetInt("iterations")
 		salt, _ := cobraCmd.Flags().GetString("salt")
 		keyLength, _ := cobraCmd.Flags().GetInt("key-length")
@@ -36,16 +38,17 @@ var HashPasswordCmd = &cobra.Command{
 
 		var err error
 		var hash string
-		var algorithm authentication.CryptAlgo
 
-		if sha512 {
-			if iterations == schema.DefaultPasswordConfiguration.Iterations {
-				iterations = schema.DefaultPasswordSHA512Configuration.Iterations
-			}
-			algorithm = authentication.HashingAlgorithmSHA512
-		} else {
-			algorithm = authentication.HashingAlgorithmArgon2id
+		algorithm, defaultIterations := hashingAlgorithm(cobraCmd)
+
+		if iterations == schema.DefaultPasswordConfiguration.Iterations {
+			iterations = defaultIterations
+		}
+
+		if algorithm == authentication.HashingAlgorithmScrypt && parallelism == schema.DefaultPasswordConfiguration.Parallelism {
+			parallelism = authentication.HashingDefaultScryptParallelism
 		}
+
 		if salt != "" {
 			salt = crypt.Base64Encoding.EncodeToString([]byte(salt))
 		}
@@ -59,3 +62,24 @@ var HashPasswordCmd = &cobra.Command{
 	},
 	Args: cobra.MinimumNArgs(1),
 }
+
+// hashingAlgorithm returns the algorithm selected with the flags and its default number of iterations.
+func hashingAlgorithm(cobraCmd *cobra.Command) (authentication.CryptAlgo, int) {
+	sha512, _ := cobraCmd.Flags().GetBool("sha512")
+	bcrypt, _ := cobraCmd.Flags().GetBool("bcrypt")
+	scrypt, _ := cobraCmd.Flags().GetBool("scrypt")
+	pbkdf2, _ := cobraCmd.Flags().GetBool("pbkdf2")
+
+	switch {
+	case sha512:
+		return authentication.HashingAlgorithmSHA512, schema.DefaultPasswordSHA512Configuration.Iterations
+	case bcrypt:
+		return authentication.HashingAlgorithmBcrypt, authentication.HashingDefaultBcryptCost
+	case scrypt:
+		return authentication.HashingAlgorithmScrypt, authentication.HashingDefaultScryptIterations
+	case pbkdf2:
+		return authentication.HashingAlgorithmPBKDF2SHA256, authentication.HashingDefaultPBKDF2Iterations
+	default:
+		return authentication.HashingAlgorithmArgon2id, schema.DefaultPasswordConfiguration.Iterations
+	}
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// readConfiguration reads and validates the configuration, exiting if it is invalid.
+func readConfiguration(configPath string) *schema.Configuration {
+	config, errs := configuration.Read(configPath)
+	if len(errs) != 0 {
+		errors := ""
+		for _, err := range errs {
+			errors += fmt.Sprintf("\t%s\n", err.Error())
+		}
+
+		log.Fatalf("Error occurred parsing configuration:\n%s", errors)
+	}
+
+	return config
+}
+
+// newStorageProvider creates the configured storage provider, exiting if there is none.
+func newStorageProvider(config *schema.Configuration) storage.Provider {
+	provider, err := storage.NewProvider(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return provider
+}
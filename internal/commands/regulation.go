@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+var regulationConfigPath string
+
+func init() {
+	RegulationCmd.PersistentFlags().StringVarP(&regulationConfigPath, "config", "c", "", "Configuration file")
+
+	RegulationCmd.AddCommand(RegulationListCmd, RegulationUnbanCmd)
+}
+
+// RegulationCmd command managing the bans of the regulation.
+var RegulationCmd = &cobra.Command{
+	Use:   "regulation",
+	Short: "Manage the bans of the authentication regulation",
+}
+
+// RegulationListCmd command listing the users and networks currently banned.
+var RegulationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users and networks currently banned",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		regulator := newRegulator()
+
+		bans, err := regulator.ListBans(context.Background())
+		if err != nil {
+			log.Fatalf("Error occurred listing the bans: %s", err)
+		}
+
+		if len(bans) == 0 {
+			fmt.Println("No user or network is banned.")
+			return
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "USER/NETWORK\tTYPE\tBANNED UNTIL")
+
+		for _, ban := range bans {
+			banned := ban.Username
+			if ban.Network != nil {
+				banned = ban.Network.String()
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", banned, ban.Type, ban.Until.Format(time.RFC3339))
+		}
+
+		writer.Flush()
+	},
+}
+
+// RegulationUnbanCmd command unbanning a user or a remote IP.
+var RegulationUnbanCmd = &cobra.Command{
+	Use:   "unban [user|ip]",
+	Short: "Unban a user or the network of a remote IP, the failed attempts made before are ignored",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		regulator := newRegulator()
+
+		var err error
+
+		if ip := net.ParseIP(args[0]); ip != nil {
+			err = regulator.UnbanIP(context.Background(), ip)
+		} else {
+			err = regulator.Unban(context.Background(), args[0])
+		}
+
+		if err != nil {
+			log.Fatalf("Error occurred unbanning %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Unbanned %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func newRegulator() *regulation.Regulator {
+	config := readConfiguration(regulationConfigPath)
+
+	return regulation.NewRegulator(config.Regulation, newStorageProvider(config), utils.RealClock{})
+}
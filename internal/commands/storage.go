@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/authelia/authelia/internal/storage"
+)
+
+var (
+	storageConfigPath        string
+	storageUpTargetVersion   int
+	storageDownTargetVersion int
+	storageFromConfigPath    string
+	storageToConfigPath      string
+
+	storagePreviousEncryptionKey string
+)
+
+func init() {
+	StorageCmd.PersistentFlags().StringVarP(&storageConfigPath, "config", "c", "", "Configuration file")
+
+	StorageMigrateUpCmd.Flags().IntVarP(&storageUpTargetVersion, "target", "t", 0, "Version to migrate the schema up to, the latest version by default")
+	StorageMigrateDownCmd.Flags().IntVarP(&storageDownTargetVersion, "target", "t", -1, "Version to migrate the schema down to, the previous version by default")
+
+	StorageMigrateBackendCmd.Flags().StringVar(&storageFromConfigPath, "from", "", "Configuration file of the storage backend to migrate the data from")
+	StorageMigrateBackendCmd.Flags().StringVar(&storageToConfigPath, "to", "", "Configuration file of the storage backend to migrate the data to")
+
+	for _, flag := range []string{"from", "to"} {
+		if err := StorageMigrateBackendCmd.MarkFlagRequired(flag); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	StorageEncryptCmd.Flags().StringVar(&storagePreviousEncryptionKey, "previous-encryption-key", "", "Encryption key the secrets were encrypted with before the configured one")
+
+	StorageMigrateCmd.AddCommand(StorageMigrateUpCmd, StorageMigrateDownCmd, StorageMigrateStatusCmd)
+	StorageCmd.AddCommand(StorageMigrateCmd, StorageExportCmd, StorageImportCmd, StorageMigrateBackendCmd, StorageEncryptCmd)
+}
+
+// StorageCmd command managing the storage backend.
+var StorageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage the storage backend",
+}
+
+// StorageMigrateCmd command managing the versions of the schema of the storage backend.
+var StorageMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the versions of the schema of the storage backend",
+}
+
+// StorageMigrateUpCmd command applying the migrations of the schema.
+var StorageMigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Migrate the schema up to the latest or the target version",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		migrator := newMigrator()
+
+		target := storageUpTargetVersion
+		if target == 0 {
+			target = migrator.LatestSchemaVersion()
+		}
+
+		if err := migrator.MigrateSchemaUp(target); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Schema migrated up to version %d\n", target)
+	},
+}
+
+// StorageMigrateDownCmd command reverting the migrations of the schema.
+var StorageMigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Migrate the schema down to the previous or the target version",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		migrator := newMigrator()
+
+		target := storageDownTargetVersion
+		if target == -1 {
+			current, err := migrator.SchemaVersion()
+			if err != nil {
+				log.Fatalf("Unable to get the schema version: %s", err)
+			}
+
+			target = current - 1
+		}
+
+		if err := migrator.MigrateSchemaDown(target); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Schema migrated down to version %d\n", target)
+	},
+}
+
+// StorageMigrateStatusCmd command showing the migrations applied to the schema.
+var StorageMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the version of the schema and the migrations applied to it",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		migrator := newMigrator()
+
+		current, err := migrator.SchemaVersion()
+		if err != nil {
+			log.Fatalf("Unable to get the schema version: %s", err)
+		}
+
+		fmt.Printf("Schema version: %d (latest version: %d)\n\n", current, migrator.LatestSchemaVersion())
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tDESCRIPTION\tSTATUS")
+
+		for _, m := range migrator.SchemaMigrations() {
+			status := "pending"
+			if m.Version <= current {
+				status = "applied"
+			}
+
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", m.Version, m.Description, status)
+		}
+
+		writer.Flush()
+	},
+}
+
+// StorageExportCmd command exporting all the data of the storage backend.
+var StorageExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export all the data of the storage backend to a JSON or YAML file depending on its extension",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		dataset, err := newDatasetProvider(storageConfigPath).ExportDataset()
+		if err != nil {
+			log.Fatalf("Error occurred exporting the data: %s", err)
+		}
+
+		content, err := marshalDataset(args[0], dataset)
+		if err != nil {
+			log.Fatalf("Error occurred encoding the data: %s", err)
+		}
+
+		// The dataset holds secrets, hence it is only readable by its owner.
+		if err := ioutil.WriteFile(args[0], content, 0600); err != nil {
+			log.Fatalf("Error occurred writing file %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Data exported to %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageImportCmd command importing data into the storage backend.
+var StorageImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import the data of a JSON or YAML file exported by the export command into an empty storage backend",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		content, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("Error occurred reading file %s: %s", args[0], err)
+		}
+
+		dataset, err := unmarshalDataset(args[0], content)
+		if err != nil {
+			log.Fatalf("Error occurred decoding file %s: %s", args[0], err)
+		}
+
+		if err := newDatasetProvider(storageConfigPath).ImportDataset(dataset); err != nil {
+			log.Fatalf("Error occurred importing the data: %s", err)
+		}
+
+		fmt.Printf("Data imported from %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageMigrateBackendCmd command migrating all the data of a storage backend to another one.
+var StorageMigrateBackendCmd = &cobra.Command{
+	Use:   "migrate-backend",
+	Short: "Migrate all the data of the storage backend of a configuration to the empty storage backend of another configuration",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		dataset, err := newDatasetProvider(storageFromConfigPath).ExportDataset()
+		if err != nil {
+			log.Fatalf("Error occurred exporting the data: %s", err)
+		}
+
+		if err := newDatasetProvider(storageToConfigPath).ImportDataset(dataset); err != nil {
+			log.Fatalf("Error occurred importing the data: %s", err)
+		}
+
+		fmt.Println("Data migrated successfully.")
+	},
+}
+
+// StorageEncryptCmd command encrypting the secrets of the storage backend with the configured encryption key.
+var StorageEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the secrets stored in plain text or with the previous encryption key with the configured encryption key",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		config := readConfiguration(storageConfigPath)
+
+		encryptor, err := storage.NewEncryptor(config.Storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		count, err := encryptor.EncryptSecrets(storagePreviousEncryptionKey)
+		if err != nil {
+			log.Fatalf("Error occurred encrypting the secrets: %s", err)
+		}
+
+		fmt.Printf("%d secrets encrypted\n", count)
+	},
+}
+
+func newDatasetProvider(configPath string) storage.DatasetProvider {
+	config := readConfiguration(configPath)
+
+	provider, err := storage.NewDatasetProvider(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return provider
+}
+
+func isYAMLFile(path string) bool {
+	extension := filepath.Ext(path)
+	return extension == ".yml" || extension == ".yaml"
+}
+
+func marshalDataset(path string, dataset *storage.Dataset) ([]byte, error) {
+	if isYAMLFile(path) {
+		return yaml.Marshal(dataset)
+	}
+
+	return json.MarshalIndent(dataset, "", "  ")
+}
+
+func unmarshalDataset(path string, content []byte) (*storage.Dataset, error) {
+	dataset := &storage.Dataset{}
+
+	if isYAMLFile(path) {
+		return dataset, yaml.Unmarshal(content, dataset)
+	}
+
+	return dataset, json.Unmarshal(content, dataset)
+}
+
+func newMigrator() storage.Migrator {
+	config := readConfiguration(storageConfigPath)
+
+	migrator, err := storage.NewMigrator(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return migrator
+}
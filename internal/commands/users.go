@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+var usersConfigPath string
+
+func init() {
+	UsersCmd.PersistentFlags().StringVarP(&usersConfigPath, "config", "c", "", "Configuration file")
+
+	UsersAddCmd.Flags().String("display-name", "", "Display name of the user")
+	UsersAddCmd.Flags().String("email", "", "Email of the user")
+	UsersAddCmd.Flags().StringSlice("group", nil, "Group of the user, can be repeated")
+	UsersAddCmd.Flags().String("password", "", "Password of the user, read from the standard input if not provided (insecure, the password is visible in the shell history and the process list)")
+
+	UsersPasswdCmd.Flags().String("password", "", "New password of the user, read from the standard input if not provided (insecure, the password is visible in the shell history and the process list)")
+
+	UsersGroupsCmd.Flags().StringSlice("add", nil, "Group to add the user to, can be repeated")
+	UsersGroupsCmd.Flags().StringSlice("remove", nil, "Group to remove the user from, can be repeated")
+
+	UsersCmd.AddCommand(UsersAddCmd, UsersDeleteCmd, UsersPasswdCmd, UsersGroupsCmd, UsersListCmd)
+}
+
+// UsersCmd command managing the users of the file authentication backend.
+var UsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manage the users of the file authentication backend",
+	Long: `Manage the users of the file authentication backend.
+
+The changes are written to the users database file keeping its comments, the order of its keys and the quoting of its
+values. Its indentation is normalized to two spaces and its blank lines are removed.`,
+}
+
+// UsersAddCmd command adding a user.
+var UsersAddCmd = &cobra.Command{
+	Use:   "add [username]",
+	Short: "Add a user whose password is hashed with the configured algorithm",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		displayName, _ := cobraCmd.Flags().GetString("display-name")
+		email, _ := cobraCmd.Flags().GetString("email")
+		groups, _ := cobraCmd.Flags().GetStringSlice("group")
+
+		if displayName == "" {
+			displayName = args[0]
+		}
+
+		provider := newFileUserProvider()
+
+		if err := provider.AddUser(args[0], displayName, email, readPassword(cobraCmd), groups); err != nil {
+			log.Fatalf("Error occurred adding the user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Added the user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersDeleteCmd command deleting a user.
+var UsersDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Delete a user",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider := newFileUserProvider()
+
+		if err := provider.DeleteUser(args[0]); err != nil {
+			log.Fatalf("Error occurred deleting the user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Deleted the user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersPasswdCmd command changing the password of a user.
+var UsersPasswdCmd = &cobra.Command{
+	Use:   "passwd [username]",
+	Short: "Change the password of a user",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider := newFileUserProvider()
+
+		if err := provider.UpdatePassword(args[0], readPassword(cobraCmd)); err != nil {
+			log.Fatalf("Error occurred changing the password of the user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Changed the password of the user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersGroupsCmd command showing or changing the groups of a user.
+var UsersGroupsCmd = &cobra.Command{
+	Use:   "groups [username]",
+	Short: "Show the groups of a user or add and remove the user from groups",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		add, _ := cobraCmd.Flags().GetStringSlice("add")
+		remove, _ := cobraCmd.Flags().GetStringSlice("remove")
+
+		provider := newFileUserProvider()
+
+		details, err := provider.GetDetails(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(add) == 0 && len(remove) == 0 {
+			fmt.Println(strings.Join(details.Groups, "\n"))
+			return
+		}
+
+		groups := make([]string, 0, len(details.Groups)+len(add))
+
+		for _, group := range details.Groups {
+			if !utils.IsStringInSlice(group, remove) {
+				groups = append(groups, group)
+			}
+		}
+
+		for _, group := range add {
+			if !utils.IsStringInSlice(group, groups) {
+				groups = append(groups, group)
+			}
+		}
+
+		if err := provider.UpdateGroups(args[0], groups); err != nil {
+			log.Fatalf("Error occurred changing the groups of the user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("The user %s is now in the groups: %s\n", args[0], strings.Join(groups, ", "))
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersListCmd command listing the users.
+var UsersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		users := newFileUserProvider().ListUsers()
+
+		if len(users) == 0 {
+			fmt.Println("No user in the database.")
+			return
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "USERNAME\tDISPLAY NAME\tEMAIL\tGROUPS")
+
+		for _, user := range users {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", user.Username, user.DisplayName, strings.Join(user.Emails, ", "),
+				strings.Join(user.Groups, ", "))
+		}
+
+		writer.Flush()
+	},
+}
+
+func newFileUserProvider() *authentication.FileUserProvider {
+	config := readConfiguration(usersConfigPath)
+
+	if config.AuthenticationBackend.File == nil {
+		log.Fatal("The users can only be managed with the file authentication backend")
+	}
+
+	return authentication.NewFileUserProvider(config.AuthenticationBackend.File)
+}
+
+// readPassword reads the password from the flag or from the standard input so that it does not appear in the shell
+// history. The password is prompted twice without being echoed when the standard input is a terminal, only the first
+// line is read otherwise, e.g., when the password is piped.
+func readPassword(cobraCmd *cobra.Command) string {
+	password, _ := cobraCmd.Flags().GetString("password")
+	if password != "" {
+		return password
+	}
+
+	fd := int(os.Stdin.Fd())
+
+	if terminal.IsTerminal(fd) {
+		password = promptPassword(fd, "Password: ")
+
+		if promptPassword(fd, "Confirm password: ") != password {
+			log.Fatal("The passwords do not match")
+		}
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Error occurred reading the password: %s", err)
+		}
+
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		log.Fatal("The password must not be empty")
+	}
+
+	return password
+}
+
+func promptPassword(fd int, prompt string) string {
+	fmt.Fprint(os.Stderr, prompt)
+
+	password, err := terminal.ReadPassword(fd)
+
+	// The line break typed by the user is not echoed either.
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		log.Fatalf("Error occurred reading the password: %s", err)
+	}
+
+	return string(password)
+}
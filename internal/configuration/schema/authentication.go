@@ -1,25 +1,50 @@
 package schema
 
+// LDAPAuthenticationBackendTLSConfiguration represents the TLS configuration of the connections to the LDAP server.
+type LDAPAuthenticationBackendTLSConfiguration struct {
+	TrustedCert    string `mapstructure:"trusted_cert"`
+	Cert           string `mapstructure:"cert"`
+	Key            string `mapstructure:"key"`
+	MinimumVersion string `mapstructure:"minimum_version"`
+	ServerName     string `mapstructure:"server_name"`
+}
+
 // LDAPAuthenticationBackendConfiguration represents the configuration related to LDAP server.
 type LDAPAuthenticationBackendConfiguration struct {
 	URL                  string `mapstructure:"url"`
 	SkipVerify           bool   `mapstructure:"skip_verify"`
+	StartTLS             bool   `mapstructure:"start_tls"`
 	BaseDN               string `mapstructure:"base_dn"`
 	AdditionalUsersDN    string `mapstructure:"additional_users_dn"`
 	UsersFilter          string `mapstructure:"users_filter"`
 	AdditionalGroupsDN   string `mapstructure:"additional_groups_dn"`
 	GroupsFilter         string `mapstructure:"groups_filter"`
 	GroupNameAttribute   string `mapstructure:"group_name_attribute"`
+	NestedGroups         bool   `mapstructure:"nested_groups"`
+	NestedGroupsMaxDepth int    `mapstructure:"nested_groups_max_depth"`
 	UsernameAttribute    string `mapstructure:"username_attribute"`
 	MailAttribute        string `mapstructure:"mail_attribute"`
 	DisplayNameAttribute string `mapstructure:"display_name_attribute"`
+	AccountState         string `mapstructure:"account_state"`
 	User                 string `mapstructure:"user"`
 	Password             string `mapstructure:"password"`
+	PoolSize             int    `mapstructure:"pool_size"`
+	PoolIdleTimeout      string `mapstructure:"pool_idle_timeout"`
+	PoolTimeout          string `mapstructure:"pool_timeout"`
+
+	TLS *LDAPAuthenticationBackendTLSConfiguration `mapstructure:"tls"`
 }
 
 // FileAuthenticationBackendConfiguration represents the configuration related to file-based backend.
 type FileAuthenticationBackendConfiguration struct {
-	Path     string                 `mapstructure:"path"`
+	Path          string                 `mapstructure:"path"`
+	Password      *PasswordConfiguration `mapstructure:"password"`
+	DisableRehash bool                   `mapstructure:"disable_rehash"`
+}
+
+// SQLAuthenticationBackendConfiguration represents the configuration related to the backend reading the users
+// from the tables of the SQL database configured as storage backend.
+type SQLAuthenticationBackendConfiguration struct {
 	Password *PasswordConfiguration `mapstructure:"password"`
 }
 
@@ -39,6 +64,7 @@ type AuthenticationBackendConfiguration struct {
 	RefreshInterval      string                                  `mapstructure:"refresh_interval"`
 	Ldap                 *LDAPAuthenticationBackendConfiguration `mapstructure:"ldap"`
 	File                 *FileAuthenticationBackendConfiguration `mapstructure:"file"`
+	SQL                  *SQLAuthenticationBackendConfiguration  `mapstructure:"sql"`
 }
 
 // DefaultPasswordConfiguration represents the default configuration related to Argon2id hashing.
@@ -73,4 +99,9 @@ var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendCon
 	MailAttribute:        "mail",
 	DisplayNameAttribute: "displayname",
 	GroupNameAttribute:   "cn",
+	NestedGroupsMaxDepth: 10,
+	AccountState:         LDAPAccountStateNone,
+	PoolSize:             8,
+	PoolIdleTimeout:      "5m",
+	PoolTimeout:          "10s",
 }
@@ -14,6 +14,17 @@ const ProfileRefreshDisabled = "disable"
 // ProfileRefreshAlways represents a value for refresh_interval that's the same as 0ms.
 const ProfileRefreshAlways = "always"
 
+// LDAPAccountStateNone represents a value for account_state that does not read the state of the LDAP accounts.
+const LDAPAccountStateNone = "none"
+
+// LDAPAccountStateActiveDirectory represents a value for account_state that reads the state of the Active Directory
+// accounts.
+const LDAPAccountStateActiveDirectory = "activedirectory"
+
+// LDAPAccountStatePPolicy represents a value for account_state that reads the state of the accounts from the
+// attributes of the password policy overlay of OpenLDAP.
+const LDAPAccountStatePPolicy = "ppolicy"
+
 // RefreshIntervalDefault represents the default value of refresh_interval.
 const RefreshIntervalDefault = "5m"
 
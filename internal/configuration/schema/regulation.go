@@ -5,11 +5,67 @@ type RegulationConfiguration struct {
 	MaxRetries int    `mapstructure:"max_retries"`
 	FindTime   string `mapstructure:"find_time"`
 	BanTime    string `mapstructure:"ban_time"`
+
+	SecondFactor *RegulationThresholdsConfiguration `mapstructure:"second_factor"`
+	IP           *RegulationIPConfiguration         `mapstructure:"ip"`
+	Escalation   *RegulationEscalationConfiguration `mapstructure:"escalation"`
+}
+
+// RegulationThresholdsConfiguration represents the thresholds of the regulation applying to a specific kind of attempts.
+type RegulationThresholdsConfiguration struct {
+	MaxRetries int    `mapstructure:"max_retries"`
+	FindTime   string `mapstructure:"find_time"`
+	BanTime    string `mapstructure:"ban_time"`
+}
+
+// RegulationIPConfiguration represents the thresholds of the regulation applying to the failed attempts made
+// from a network, whatever the username.
+type RegulationIPConfiguration struct {
+	RegulationThresholdsConfiguration `mapstructure:",squash"`
+
+	IPv4PrefixLength int `mapstructure:"ipv4_prefix_length"`
+	IPv6PrefixLength int `mapstructure:"ipv6_prefix_length"`
+}
+
+// RegulationEscalationConfiguration represents the policy escalating the ban time of repeat offenders.
+type RegulationEscalationConfiguration struct {
+	Factor     int    `mapstructure:"factor"`
+	Window     string `mapstructure:"window"`
+	MaxBanTime string `mapstructure:"max_ban_time"`
 }
 
 // DefaultRegulationConfiguration represents default configuration parameters for the regulator.
 var DefaultRegulationConfiguration = RegulationConfiguration{
-	MaxRetries: 3,
-	FindTime:   "2m",
-	BanTime:    "5m",
+	MaxRetries:   3,
+	FindTime:     "2m",
+	BanTime:      "5m",
+	SecondFactor: &DefaultSecondFactorRegulationConfiguration,
+}
+
+// DefaultSecondFactorRegulationConfiguration represents default configuration parameters for the regulation of
+// the second factor attempts.
+var DefaultSecondFactorRegulationConfiguration = RegulationThresholdsConfiguration{
+	MaxRetries: 5,
+	FindTime:   "5m",
+	BanTime:    "15m",
+}
+
+// DefaultIPRegulationConfiguration represents default configuration parameters for the regulation of the
+// attempts made from a network, which only applies when it is configured.
+var DefaultIPRegulationConfiguration = RegulationIPConfiguration{
+	RegulationThresholdsConfiguration: RegulationThresholdsConfiguration{
+		MaxRetries: 20,
+		FindTime:   "5m",
+		BanTime:    "15m",
+	},
+	IPv4PrefixLength: 32,
+	IPv6PrefixLength: 64,
+}
+
+// DefaultRegulationEscalationConfiguration represents default configuration parameters for the escalation of
+// the ban time when it is enabled.
+var DefaultRegulationEscalationConfiguration = RegulationEscalationConfiguration{
+	Factor:     2,
+	Window:     "24h",
+	MaxBanTime: "24h",
 }
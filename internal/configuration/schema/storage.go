@@ -5,6 +5,13 @@ type LocalStorageConfiguration struct {
 	Path string `mapstructure:"path"`
 }
 
+// SQLStorageTLSConfiguration represents the TLS configuration of the connections to the SQL database.
+type SQLStorageTLSConfiguration struct {
+	TrustedCert string `mapstructure:"trusted_cert"`
+	Cert        string `mapstructure:"cert"`
+	Key         string `mapstructure:"key"`
+}
+
 // SQLStorageConfiguration represents the configuration of the SQL database.
 type SQLStorageConfiguration struct {
 	Host     string `mapstructure:"host"`
@@ -12,6 +19,13 @@ type SQLStorageConfiguration struct {
 	Database string `mapstructure:"database"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
+
+	MaxOpenConnections    int    `mapstructure:"max_open_connections"`
+	MaxIdleConnections    int    `mapstructure:"max_idle_connections"`
+	ConnectionMaxLifetime string `mapstructure:"connection_max_lifetime"`
+	QueryTimeout          string `mapstructure:"query_timeout"`
+
+	TLS *SQLStorageTLSConfiguration `mapstructure:"tls"`
 }
 
 // MySQLStorageConfiguration represents the configuration of a MySQL database.
@@ -25,9 +39,30 @@ type PostgreSQLStorageConfiguration struct {
 	SSLMode                 string `mapstructure:"sslmode"`
 }
 
+// StorageRetentionConfiguration represents the retention of the data pruned periodically from the storage backend.
+type StorageRetentionConfiguration struct {
+	Interval           string `mapstructure:"interval"`
+	AuthenticationLogs string `mapstructure:"authentication_logs"`
+}
+
 // StorageConfiguration represents the configuration of the storage backend.
 type StorageConfiguration struct {
-	Local      *LocalStorageConfiguration      `mapstructure:"local"`
-	MySQL      *MySQLStorageConfiguration      `mapstructure:"mysql"`
-	PostgreSQL *PostgreSQLStorageConfiguration `mapstructure:"postgres"`
+	Local         *LocalStorageConfiguration      `mapstructure:"local"`
+	MySQL         *MySQLStorageConfiguration      `mapstructure:"mysql"`
+	PostgreSQL    *PostgreSQLStorageConfiguration `mapstructure:"postgres"`
+	EncryptionKey string                          `mapstructure:"encryption_key"`
+	Retention     *StorageRetentionConfiguration  `mapstructure:"retention"`
+}
+
+// DefaultSQLStorageConfiguration represents the default configuration of the connections to the SQL database.
+// The number of connections is not limited by default and the number of idle connections is the one of the driver.
+var DefaultSQLStorageConfiguration = SQLStorageConfiguration{
+	ConnectionMaxLifetime: "1h",
+	QueryTimeout:          "10s",
+}
+
+// DefaultStorageRetentionConfiguration represents the default retention of the data of the storage backend.
+var DefaultStorageRetentionConfiguration = StorageRetentionConfiguration{
+	Interval:           "1h",
+	AuthenticationLogs: "1y",
 }
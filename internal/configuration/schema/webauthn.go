@@ -0,0 +1,17 @@
+package schema
+
+// WebauthnConfiguration represents the webauthn config.
+type WebauthnConfiguration struct {
+	DisplayName          string `mapstructure:"display_name"`
+	ConveyancePreference string `mapstructure:"attestation_conveyance_preference"`
+	UserVerification     string `mapstructure:"user_verification"`
+	Timeout              string `mapstructure:"timeout"`
+}
+
+// DefaultWebauthnConfiguration describes the default values for the WebauthnConfiguration.
+var DefaultWebauthnConfiguration = WebauthnConfiguration{
+	DisplayName:          "Authelia",
+	ConveyancePreference: "indirect",
+	UserVerification:     "preferred",
+	Timeout:              "60s",
+}
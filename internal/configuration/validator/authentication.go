@@ -10,7 +10,6 @@ import (
 	"github.com/authelia/authelia/internal/utils"
 )
 
-//nolint:gocyclo // TODO: Consider refactoring/simplifying, time permitting
 func validateFileAuthenticationBackend(configuration *schema.FileAuthenticationBackendConfiguration, validator *schema.StructValidator) {
 	if configuration.Path == "" {
 		validator.Push(errors.New("Please provide a `path` for the users database in `authentication_backend`"))
@@ -19,55 +18,68 @@ func validateFileAuthenticationBackend(configuration *schema.FileAuthenticationB
 	if configuration.Password == nil {
 		configuration.Password = &schema.DefaultPasswordConfiguration
 	} else {
-		if configuration.Password.Algorithm == "" {
-			configuration.Password.Algorithm = schema.DefaultPasswordConfiguration.Algorithm
+		validatePasswordConfiguration(configuration.Password, validator)
+	}
+}
+
+func validateSQLAuthenticationBackend(configuration *schema.SQLAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.Password == nil {
+		configuration.Password = &schema.DefaultPasswordConfiguration
+	} else {
+		validatePasswordConfiguration(configuration.Password, validator)
+	}
+}
+
+//nolint:gocyclo // TODO: Consider refactoring/simplifying, time permitting
+func validatePasswordConfiguration(configuration *schema.PasswordConfiguration, validator *schema.StructValidator) {
+	if configuration.Algorithm == "" {
+		configuration.Algorithm = schema.DefaultPasswordConfiguration.Algorithm
+	} else {
+		configuration.Algorithm = strings.ToLower(configuration.Algorithm)
+		if configuration.Algorithm != argon2id && configuration.Algorithm != sha512 {
+			validator.Push(fmt.Errorf("Unknown hashing algorithm supplied, valid values are argon2id and sha512, you configured '%s'", configuration.Algorithm))
+		}
+	}
+
+	// Iterations (time)
+	if configuration.Iterations == 0 {
+		if configuration.Algorithm == argon2id {
+			configuration.Iterations = schema.DefaultPasswordConfiguration.Iterations
 		} else {
-			configuration.Password.Algorithm = strings.ToLower(configuration.Password.Algorithm)
-			if configuration.Password.Algorithm != argon2id && configuration.Password.Algorithm != sha512 {
-				validator.Push(fmt.Errorf("Unknown hashing algorithm supplied, valid values are argon2id and sha512, you configured '%s'", configuration.Password.Algorithm))
-			}
+			configuration.Iterations = schema.DefaultPasswordSHA512Configuration.Iterations
 		}
+	} else if configuration.Iterations < 1 {
+		validator.Push(fmt.Errorf("The number of iterations specified is invalid, must be 1 or more, you configured %d", configuration.Iterations))
+	}
+
+	//Salt Length
+	switch {
+	case configuration.SaltLength == 0:
+		configuration.SaltLength = schema.DefaultPasswordConfiguration.SaltLength
+	case configuration.SaltLength < 8:
+		validator.Push(fmt.Errorf("The salt length must be 2 or more, you configured %d", configuration.SaltLength))
+	}
 
-		// Iterations (time)
-		if configuration.Password.Iterations == 0 {
-			if configuration.Password.Algorithm == argon2id {
-				configuration.Password.Iterations = schema.DefaultPasswordConfiguration.Iterations
-			} else {
-				configuration.Password.Iterations = schema.DefaultPasswordSHA512Configuration.Iterations
-			}
-		} else if configuration.Password.Iterations < 1 {
-			validator.Push(fmt.Errorf("The number of iterations specified is invalid, must be 1 or more, you configured %d", configuration.Password.Iterations))
+	if configuration.Algorithm == argon2id {
+		// Parallelism
+		if configuration.Parallelism == 0 {
+			configuration.Parallelism = schema.DefaultPasswordConfiguration.Parallelism
+		} else if configuration.Parallelism < 1 {
+			validator.Push(fmt.Errorf("Parallelism for argon2id must be 1 or more, you configured %d", configuration.Parallelism))
 		}
 
-		//Salt Length
-		switch {
-		case configuration.Password.SaltLength == 0:
-			configuration.Password.SaltLength = schema.DefaultPasswordConfiguration.SaltLength
-		case configuration.Password.SaltLength < 8:
-			validator.Push(fmt.Errorf("The salt length must be 2 or more, you configured %d", configuration.Password.SaltLength))
+		// Memory
+		if configuration.Memory == 0 {
+			configuration.Memory = schema.DefaultPasswordConfiguration.Memory
+		} else if configuration.Memory < configuration.Parallelism*8 {
+			validator.Push(fmt.Errorf("Memory for argon2id must be %d or more (parallelism * 8), you configured memory as %d and parallelism as %d", configuration.Parallelism*8, configuration.Memory, configuration.Parallelism))
 		}
 
-		if configuration.Password.Algorithm == argon2id {
-			// Parallelism
-			if configuration.Password.Parallelism == 0 {
-				configuration.Password.Parallelism = schema.DefaultPasswordConfiguration.Parallelism
-			} else if configuration.Password.Parallelism < 1 {
-				validator.Push(fmt.Errorf("Parallelism for argon2id must be 1 or more, you configured %d", configuration.Password.Parallelism))
-			}
-
-			// Memory
-			if configuration.Password.Memory == 0 {
-				configuration.Password.Memory = schema.DefaultPasswordConfiguration.Memory
-			} else if configuration.Password.Memory < configuration.Password.Parallelism*8 {
-				validator.Push(fmt.Errorf("Memory for argon2id must be %d or more (parallelism * 8), you configured memory as %d and parallelism as %d", configuration.Password.Parallelism*8, configuration.Password.Memory, configuration.Password.Parallelism))
-			}
-
-			// Key Length
-			if configuration.Password.KeyLength == 0 {
-				configuration.Password.KeyLength = schema.DefaultPasswordConfiguration.KeyLength
-			} else if configuration.Password.KeyLength < 16 {
-				validator.Push(fmt.Errorf("Key length for argon2id must be 16, you configured %d", configuration.Password.KeyLength))
-			}
+		// Key Length
+		if configuration.KeyLength == 0 {
+			configuration.KeyLength = schema.DefaultPasswordConfiguration.KeyLength
+		} else if configuration.KeyLength < 16 {
+			validator.Push(fmt.Errorf("Key length for argon2id must be 16, you configured %d", configuration.KeyLength))
 		}
 	}
 }
@@ -106,6 +118,14 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		configuration.URL = validateLdapURL(configuration.URL, validator)
 	}
 
+	if configuration.StartTLS && strings.HasPrefix(configuration.URL, schemeLDAPS+"://") {
+		validator.Push(errors.New("The start_tls option upgrades ldap:// connections to TLS, it cannot be used with an ldaps:// URL"))
+	}
+
+	if configuration.TLS != nil {
+		validateLdapTLS(configuration.TLS, validator)
+	}
+
 	// TODO: see if it's possible to disable this check if disable_reset_password is set and when anonymous/user binding is supported (#101 and #387)
 	if configuration.User == "" {
 		validator.Push(errors.New("Please provide a user name to connect to the LDAP server"))
@@ -155,22 +175,104 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 	if configuration.DisplayNameAttribute == "" {
 		configuration.DisplayNameAttribute = schema.DefaultLDAPAuthenticationBackendConfiguration.DisplayNameAttribute
 	}
+
+	validateLdapAccountState(configuration, validator)
+	validateLdapNestedGroups(configuration, validator)
+	validateLdapPool(configuration, validator)
+}
+
+func validateLdapAccountState(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.AccountState == "" {
+		configuration.AccountState = schema.DefaultLDAPAuthenticationBackendConfiguration.AccountState
+	} else if !utils.IsStringInSlice(configuration.AccountState, validLDAPAccountStates) {
+		validator.Push(fmt.Errorf("The account state must be one of %s but it is configured as %s",
+			strings.Join(validLDAPAccountStates, ", "), configuration.AccountState))
+	}
+}
+
+func validateLdapNestedGroups(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.NestedGroupsMaxDepth == 0 {
+		configuration.NestedGroupsMaxDepth = schema.DefaultLDAPAuthenticationBackendConfiguration.NestedGroupsMaxDepth
+	} else if configuration.NestedGroupsMaxDepth < 0 {
+		validator.Push(fmt.Errorf("The nested groups max depth must be 1 or more but it is configured as %d", configuration.NestedGroupsMaxDepth))
+	}
+
+	if configuration.NestedGroups && !strings.Contains(configuration.GroupsFilter, "{dn}") {
+		validator.Push(errors.New("The groups filter must contain the {dn} placeholder to search the nested groups"))
+	}
+}
+
+func validateLdapTLS(configuration *schema.LDAPAuthenticationBackendTLSConfiguration, validator *schema.StructValidator) {
+	if configuration.MinimumVersion != "" && !utils.IsStringInSlice(configuration.MinimumVersion, validTLSVersions) {
+		validator.Push(fmt.Errorf("The minimum TLS version must be one of %s but it is configured as %s",
+			strings.Join(validTLSVersions, ", "), configuration.MinimumVersion))
+	}
+
+	if (configuration.Cert == "") != (configuration.Key == "") {
+		validator.Push(errors.New("The TLS cert and key must be provided together to authenticate with a client certificate"))
+	}
+
+	for _, file := range []string{configuration.TrustedCert, configuration.Cert, configuration.Key} {
+		if file == "" {
+			continue
+		}
+
+		if exists, _ := utils.FileExists(file); !exists {
+			validator.Push(fmt.Errorf("The TLS file %s does not exist", file))
+		}
+	}
+}
+
+func validateLdapPool(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.PoolSize == 0 {
+		configuration.PoolSize = schema.DefaultLDAPAuthenticationBackendConfiguration.PoolSize
+	} else if configuration.PoolSize < 0 {
+		validator.Push(fmt.Errorf("The pool size must be 1 or more but it is configured as %d", configuration.PoolSize))
+	}
+
+	if configuration.PoolIdleTimeout == "" {
+		configuration.PoolIdleTimeout = schema.DefaultLDAPAuthenticationBackendConfiguration.PoolIdleTimeout
+	}
+
+	if _, err := utils.ParseDurationString(configuration.PoolIdleTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing pool_idle_timeout string: %s", err))
+	}
+
+	if configuration.PoolTimeout == "" {
+		configuration.PoolTimeout = schema.DefaultLDAPAuthenticationBackendConfiguration.PoolTimeout
+	}
+
+	if timeout, err := utils.ParseDurationString(configuration.PoolTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing pool_timeout string: %s", err))
+	} else if timeout <= 0 {
+		validator.Push(fmt.Errorf("The pool timeout must be more than 0 but it is configured as %s", configuration.PoolTimeout))
+	}
 }
 
 // ValidateAuthenticationBackend validates and update authentication backend configuration.
 func ValidateAuthenticationBackend(configuration *schema.AuthenticationBackendConfiguration, validator *schema.StructValidator) {
-	if configuration.Ldap == nil && configuration.File == nil {
-		validator.Push(errors.New("Please provide `ldap` or `file` object in `authentication_backend`"))
+	backends := 0
+
+	for _, configured := range []bool{configuration.Ldap != nil, configuration.File != nil, configuration.SQL != nil} {
+		if configured {
+			backends++
+		}
 	}
 
-	if configuration.Ldap != nil && configuration.File != nil {
-		validator.Push(errors.New("You cannot provide both `ldap` and `file` objects in `authentication_backend`"))
+	switch {
+	case backends == 0:
+		validator.Push(errors.New("Please provide `ldap`, `file` or `sql` object in `authentication_backend`"))
+	case backends > 1:
+		validator.Push(errors.New("You cannot provide more than one of `ldap`, `file` and `sql` objects in `authentication_backend`"))
 	}
 
-	if configuration.File != nil {
+	switch {
+	case configuration.File != nil:
 		validateFileAuthenticationBackend(configuration.File, validator)
-	} else if configuration.Ldap != nil {
+	case configuration.Ldap != nil:
 		validateLdapAuthenticationBackend(configuration.Ldap, validator)
+	case configuration.SQL != nil:
+		validateSQLAuthenticationBackend(configuration.SQL, validator)
 	}
 
 	if configuration.RefreshInterval == "" {
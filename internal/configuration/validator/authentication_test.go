@@ -17,7 +17,34 @@ func TestShouldRaiseErrorsWhenNoBackendProvided(t *testing.T) {
 	ValidateAuthenticationBackend(&backendConfig, validator)
 
 	assert.Len(t, validator.Errors(), 1)
-	assert.EqualError(t, validator.Errors()[0], "Please provide `ldap` or `file` object in `authentication_backend`")
+	assert.EqualError(t, validator.Errors()[0], "Please provide `ldap`, `file` or `sql` object in `authentication_backend`")
+}
+
+func TestShouldRaiseErrorWhenSeveralBackendsProvided(t *testing.T) {
+	validator := schema.NewStructValidator()
+	backendConfig := schema.AuthenticationBackendConfiguration{
+		File: &schema.FileAuthenticationBackendConfiguration{Path: "/a/path"},
+		SQL:  &schema.SQLAuthenticationBackendConfiguration{},
+	}
+
+	ValidateAuthenticationBackend(&backendConfig, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "You cannot provide more than one of `ldap`, `file` and `sql` objects in `authentication_backend`")
+}
+
+func TestShouldSetDefaultPasswordConfigurationOfSQLBackend(t *testing.T) {
+	validator := schema.NewStructValidator()
+	backendConfig := schema.AuthenticationBackendConfiguration{
+		SQL: &schema.SQLAuthenticationBackendConfiguration{Password: &schema.PasswordConfiguration{Algorithm: "SHA512"}},
+	}
+
+	ValidateAuthenticationBackend(&backendConfig, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "sha512", backendConfig.SQL.Password.Algorithm)
+	assert.Equal(t, schema.DefaultPasswordSHA512Configuration.Iterations, backendConfig.SQL.Password.Iterations)
+	assert.Equal(t, schema.DefaultPasswordConfiguration.SaltLength, backendConfig.SQL.Password.SaltLength)
 }
 
 type FileBasedAuthenticationBackend struct {
@@ -247,6 +274,90 @@ func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultRefreshInterval
 	assert.Equal(suite.T(), "5m", suite.configuration.RefreshInterval)
 }
 
+func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultPool() {
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	assert.Len(suite.T(), suite.validator.Errors(), 0)
+	assert.Equal(suite.T(), 8, suite.configuration.Ldap.PoolSize)
+	assert.Equal(suite.T(), "5m", suite.configuration.Ldap.PoolIdleTimeout)
+	assert.Equal(suite.T(), "10s", suite.configuration.Ldap.PoolTimeout)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadPool() {
+	suite.configuration.Ldap.PoolSize = -1
+	suite.configuration.Ldap.PoolIdleTimeout = "blah"
+	suite.configuration.Ldap.PoolTimeout = "blah"
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	require.Len(suite.T(), suite.validator.Errors(), 3)
+	assert.EqualError(suite.T(), suite.validator.Errors()[0], "The pool size must be 1 or more but it is configured as -1")
+	assert.EqualError(suite.T(), suite.validator.Errors()[1], "Error occurred parsing pool_idle_timeout string: Could not convert the input string of blah into a duration")
+	assert.EqualError(suite.T(), suite.validator.Errors()[2], "Error occurred parsing pool_timeout string: Could not convert the input string of blah into a duration")
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnZeroPoolTimeout() {
+	suite.configuration.Ldap.PoolTimeout = "0s"
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	require.Len(suite.T(), suite.validator.Errors(), 1)
+	assert.EqualError(suite.T(), suite.validator.Errors()[0], "The pool timeout must be more than 0 but it is configured as 0s")
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultAccountState() {
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	assert.Len(suite.T(), suite.validator.Errors(), 0)
+	assert.Equal(suite.T(), "none", suite.configuration.Ldap.AccountState)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadAccountState() {
+	suite.configuration.Ldap.AccountState = "openldap"
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	require.Len(suite.T(), suite.validator.Errors(), 1)
+	assert.EqualError(suite.T(), suite.validator.Errors()[0], "The account state must be one of none, activedirectory, ppolicy but it is configured as openldap")
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultNestedGroupsMaxDepth() {
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	assert.Len(suite.T(), suite.validator.Errors(), 0)
+	assert.False(suite.T(), suite.configuration.Ldap.NestedGroups)
+	assert.Equal(suite.T(), 10, suite.configuration.Ldap.NestedGroupsMaxDepth)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadNestedGroups() {
+	suite.configuration.Ldap.NestedGroups = true
+	suite.configuration.Ldap.NestedGroupsMaxDepth = -1
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	require.Len(suite.T(), suite.validator.Errors(), 2)
+	assert.EqualError(suite.T(), suite.validator.Errors()[0], "The nested groups max depth must be 1 or more but it is configured as -1")
+	assert.EqualError(suite.T(), suite.validator.Errors()[1], "The groups filter must contain the {dn} placeholder to search the nested groups")
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldAllowNestedGroupsWithDNInGroupsFilter() {
+	suite.configuration.Ldap.NestedGroups = true
+	suite.configuration.Ldap.GroupsFilter = "(member={dn})"
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	assert.Len(suite.T(), suite.validator.Errors(), 0)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldNotSetDefaultTLSMinimumVersion() {
+	suite.configuration.Ldap.TLS = &schema.LDAPAuthenticationBackendTLSConfiguration{}
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	assert.Len(suite.T(), suite.validator.Errors(), 0)
+	assert.Equal(suite.T(), "", suite.configuration.Ldap.TLS.MinimumVersion)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadTLSConfiguration() {
+	suite.configuration.Ldap.URL = "ldaps://127.0.0.1"
+	suite.configuration.Ldap.StartTLS = true
+	suite.configuration.Ldap.TLS = &schema.LDAPAuthenticationBackendTLSConfiguration{
+		MinimumVersion: "SSL3.0",
+		Cert:           "/tmp/client.pem",
+	}
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+	require.Len(suite.T(), suite.validator.Errors(), 4)
+	assert.EqualError(suite.T(), suite.validator.Errors()[0], "The start_tls option upgrades ldap:// connections to TLS, it cannot be used with an ldaps:// URL")
+	assert.EqualError(suite.T(), suite.validator.Errors()[1], "The minimum TLS version must be one of TLS1.0, TLS1.1, TLS1.2, TLS1.3 but it is configured as SSL3.0")
+	assert.EqualError(suite.T(), suite.validator.Errors()[2], "The TLS cert and key must be provided together to authenticate with a client certificate")
+	assert.EqualError(suite.T(), suite.validator.Errors()[3], "The TLS file /tmp/client.pem does not exist")
+}
+
 func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseWhenUsersFilterDoesNotContainEnclosingParenthesis() {
 	suite.configuration.Ldap.UsersFilter = "uid={input}"
 	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
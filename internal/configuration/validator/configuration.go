@@ -48,6 +48,12 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateTOTP(configuration.TOTP, validator)
 
+	if configuration.Webauthn == nil {
+		configuration.Webauthn = &schema.DefaultWebauthnConfiguration
+	}
+
+	ValidateWebauthn(configuration.Webauthn, validator)
+
 	ValidateAuthenticationBackend(&configuration.AuthenticationBackend, validator)
 
 	if configuration.AccessControl.DefaultPolicy == "" {
@@ -64,8 +70,15 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateServer(&configuration.Server, validator)
 
+	if configuration.Storage.Retention == nil {
+		retention := schema.DefaultStorageRetentionConfiguration
+		configuration.Storage.Retention = &retention
+	}
+
 	ValidateStorage(configuration.Storage, validator)
 
+	validateStorageRetentionCoversRegulation(configuration.Storage.Retention, configuration.Regulation, validator)
+
 	if configuration.Notifier == nil {
 		validator.Push(fmt.Errorf("A notifier configuration must be provided"))
 	} else {
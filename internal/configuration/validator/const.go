@@ -1,5 +1,7 @@
 package validator
 
+import "github.com/authelia/authelia/internal/configuration/schema"
+
 var validKeys = []string{
 	// Root Keys.
 	"host",
@@ -18,8 +20,17 @@ var validKeys = []string{
 
 	// TOTP Keys.
 	"totp.issuer",
+	"totp.algorithm",
+	"totp.digits",
 	"totp.period",
 	"totp.skew",
+	"totp.secret_size",
+
+	// Webauthn Keys.
+	"webauthn.display_name",
+	"webauthn.attestation_conveyance_preference",
+	"webauthn.user_verification",
+	"webauthn.timeout",
 
 	// Access Control Keys.
 	"access_control.rules",
@@ -48,6 +59,13 @@ var validKeys = []string{
 	"storage.mysql.database",
 	"storage.mysql.username",
 	"storage.mysql.password",
+	"storage.mysql.max_open_connections",
+	"storage.mysql.max_idle_connections",
+	"storage.mysql.connection_max_lifetime",
+	"storage.mysql.query_timeout",
+	"storage.mysql.tls.trusted_cert",
+	"storage.mysql.tls.cert",
+	"storage.mysql.tls.key",
 
 	// PostgreSQL Storage Keys.
 	"storage.postgres.host",
@@ -55,8 +73,20 @@ var validKeys = []string{
 	"storage.postgres.database",
 	"storage.postgres.username",
 	"storage.postgres.password",
+	"storage.postgres.max_open_connections",
+	"storage.postgres.max_idle_connections",
+	"storage.postgres.connection_max_lifetime",
+	"storage.postgres.query_timeout",
+	"storage.postgres.tls.trusted_cert",
+	"storage.postgres.tls.cert",
+	"storage.postgres.tls.key",
 	"storage.postgres.sslmode",
 
+	// Storage Keys.
+	"storage.encryption_key",
+	"storage.retention.interval",
+	"storage.retention.authentication_logs",
+
 	// FileSystem Notifier Keys.
 	"notifier.filesystem.filename",
 	"notifier.disable_startup_check",
@@ -77,6 +107,17 @@ var validKeys = []string{
 	"regulation.max_retries",
 	"regulation.find_time",
 	"regulation.ban_time",
+	"regulation.second_factor.max_retries",
+	"regulation.second_factor.find_time",
+	"regulation.second_factor.ban_time",
+	"regulation.ip.max_retries",
+	"regulation.ip.find_time",
+	"regulation.ip.ban_time",
+	"regulation.ip.ipv4_prefix_length",
+	"regulation.ip.ipv6_prefix_length",
+	"regulation.escalation.factor",
+	"regulation.escalation.window",
+	"regulation.escalation.max_ban_time",
 
 	// DUO API Keys.
 	"duo_api.hostname",
@@ -90,6 +131,12 @@ var validKeys = []string{
 	// LDAP Authentication Backend Keys.
 	"authentication_backend.ldap.url",
 	"authentication_backend.ldap.skip_verify",
+	"authentication_backend.ldap.start_tls",
+	"authentication_backend.ldap.tls.trusted_cert",
+	"authentication_backend.ldap.tls.cert",
+	"authentication_backend.ldap.tls.key",
+	"authentication_backend.ldap.tls.minimum_version",
+	"authentication_backend.ldap.tls.server_name",
 	"authentication_backend.ldap.base_dn",
 	"authentication_backend.ldap.username_attribute",
 	"authentication_backend.ldap.additional_users_dn",
@@ -97,13 +144,20 @@ var validKeys = []string{
 	"authentication_backend.ldap.additional_groups_dn",
 	"authentication_backend.ldap.groups_filter",
 	"authentication_backend.ldap.group_name_attribute",
+	"authentication_backend.ldap.nested_groups",
+	"authentication_backend.ldap.nested_groups_max_depth",
 	"authentication_backend.ldap.mail_attribute",
 	"authentication_backend.ldap.display_name_attribute",
+	"authentication_backend.ldap.account_state",
 	"authentication_backend.ldap.user",
 	"authentication_backend.ldap.password",
+	"authentication_backend.ldap.pool_size",
+	"authentication_backend.ldap.pool_idle_timeout",
+	"authentication_backend.ldap.pool_timeout",
 
 	// File Authentication Backend Keys.
 	"authentication_backend.file.path",
+	"authentication_backend.file.disable_rehash",
 	"authentication_backend.file.password.algorithm",
 	"authentication_backend.file.password.iterations",
 	"authentication_backend.file.password.key_length",
@@ -111,6 +165,14 @@ var validKeys = []string{
 	"authentication_backend.file.password.memory",
 	"authentication_backend.file.password.parallelism",
 
+	// SQL Authentication Backend Keys.
+	"authentication_backend.sql.password.algorithm",
+	"authentication_backend.sql.password.iterations",
+	"authentication_backend.sql.password.key_length",
+	"authentication_backend.sql.password.salt_length",
+	"authentication_backend.sql.password.memory",
+	"authentication_backend.sql.password.parallelism",
+
 	// Secret Keys.
 	"authelia.jwt_secret",
 	"authelia.duo_api.secret_key",
@@ -120,6 +182,7 @@ var validKeys = []string{
 	"authelia.session.redis.password",
 	"authelia.storage.mysql.password",
 	"authelia.storage.postgres.password",
+	"authelia.storage.encryption_key",
 	"authelia.jwt_secret.file",
 	"authelia.duo_api.secret_key.file",
 	"authelia.session.secret.file",
@@ -128,6 +191,7 @@ var validKeys = []string{
 	"authelia.session.redis.password.file",
 	"authelia.storage.mysql.password.file",
 	"authelia.storage.postgres.password.file",
+	"authelia.storage.encryption_key.file",
 }
 
 var specificErrorKeys = map[string]string{
@@ -157,9 +221,16 @@ var specificErrorKeys = map[string]string{
 const argon2id = "argon2id"
 const sha512 = "sha512"
 
+var validLDAPAccountStates = []string{schema.LDAPAccountStateNone, schema.LDAPAccountStateActiveDirectory,
+	schema.LDAPAccountStatePPolicy}
+
+var validTLSVersions = []string{"TLS1.0", "TLS1.1", "TLS1.2", "TLS1.3"}
+
 const schemeLDAP = "ldap"
 const schemeLDAPS = "ldaps"
 
+const minimumEncryptionKeyLength = 20
+
 const testBadTimer = "-1"
 const testModeDisabled = "disable"
 const testJWTSecret = "a_secret"
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
@@ -30,4 +31,89 @@ func ValidateRegulation(configuration *schema.RegulationConfiguration, validator
 	if findTime > banTime {
 		validator.Push(fmt.Errorf("find_time cannot be greater than ban_time"))
 	}
+
+	if configuration.SecondFactor == nil {
+		secondFactor := schema.DefaultSecondFactorRegulationConfiguration
+		configuration.SecondFactor = &secondFactor
+	}
+
+	validateRegulationThresholds("second_factor", configuration.SecondFactor, schema.DefaultSecondFactorRegulationConfiguration, validator)
+
+	// The regulation by remote IP is opt-in since all the users behind a NAT share the same IP.
+	if configuration.IP != nil {
+		validateRegulationIP(configuration.IP, validator)
+	}
+
+	if configuration.Escalation != nil {
+		validateRegulationEscalation(configuration.Escalation, banTime, validator)
+	}
+}
+
+func validateRegulationEscalation(configuration *schema.RegulationEscalationConfiguration, banTime time.Duration, validator *schema.StructValidator) {
+	if configuration.Factor == 0 {
+		configuration.Factor = schema.DefaultRegulationEscalationConfiguration.Factor
+	} else if configuration.Factor < 0 {
+		validator.Push(fmt.Errorf("Regulation escalation factor must be greater than 0 but it is configured as %d", configuration.Factor))
+	}
+
+	if configuration.Window == "" {
+		configuration.Window = schema.DefaultRegulationEscalationConfiguration.Window
+	}
+
+	if configuration.MaxBanTime == "" {
+		configuration.MaxBanTime = schema.DefaultRegulationEscalationConfiguration.MaxBanTime
+	}
+
+	if _, err := utils.ParseDurationString(configuration.Window); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation escalation window string: %s", err))
+	}
+
+	maxBanTime, err := utils.ParseDurationString(configuration.MaxBanTime)
+	if err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation escalation max_ban_time string: %s", err))
+	} else if maxBanTime < banTime {
+		validator.Push(fmt.Errorf("Regulation escalation max_ban_time cannot be less than ban_time"))
+	}
+}
+
+func validateRegulationIP(configuration *schema.RegulationIPConfiguration, validator *schema.StructValidator) {
+	validateRegulationThresholds("ip", &configuration.RegulationThresholdsConfiguration,
+		schema.DefaultIPRegulationConfiguration.RegulationThresholdsConfiguration, validator)
+
+	if configuration.IPv4PrefixLength == 0 {
+		configuration.IPv4PrefixLength = schema.DefaultIPRegulationConfiguration.IPv4PrefixLength
+	} else if configuration.IPv4PrefixLength < 0 || configuration.IPv4PrefixLength > 32 {
+		validator.Push(fmt.Errorf("Regulation ip ipv4_prefix_length must be between 1 and 32 but it is configured as %d", configuration.IPv4PrefixLength))
+	}
+
+	if configuration.IPv6PrefixLength == 0 {
+		configuration.IPv6PrefixLength = schema.DefaultIPRegulationConfiguration.IPv6PrefixLength
+	} else if configuration.IPv6PrefixLength < 0 || configuration.IPv6PrefixLength > 128 {
+		validator.Push(fmt.Errorf("Regulation ip ipv6_prefix_length must be between 1 and 128 but it is configured as %d", configuration.IPv6PrefixLength))
+	}
+}
+
+func validateRegulationThresholds(name string, configuration *schema.RegulationThresholdsConfiguration,
+	defaults schema.RegulationThresholdsConfiguration, validator *schema.StructValidator) {
+	if configuration.FindTime == "" {
+		configuration.FindTime = defaults.FindTime
+	}
+
+	if configuration.BanTime == "" {
+		configuration.BanTime = defaults.BanTime
+	}
+
+	findTime, err := utils.ParseDurationString(configuration.FindTime)
+	if err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation %s find_time string: %s", name, err))
+	}
+
+	banTime, err := utils.ParseDurationString(configuration.BanTime)
+	if err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation %s ban_time string: %s", name, err))
+	}
+
+	if findTime > banTime {
+		validator.Push(fmt.Errorf("%s find_time cannot be greater than ban_time", name))
+	}
 }
@@ -43,3 +43,134 @@ func TestShouldRaiseErrorWhenFindTimeLessThanBanTime(t *testing.T) {
 	assert.Len(t, validator.Errors(), 1)
 	assert.EqualError(t, validator.Errors()[0], "find_time cannot be greater than ban_time")
 }
+
+func TestShouldSetDefaultSecondFactorRegulation(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, schema.DefaultSecondFactorRegulationConfiguration, *config.SecondFactor)
+}
+
+func TestShouldSetDefaultSecondFactorRegulationTimes(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.SecondFactor = &schema.RegulationThresholdsConfiguration{MaxRetries: 10}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, 10, config.SecondFactor.MaxRetries)
+	assert.Equal(t, schema.DefaultSecondFactorRegulationConfiguration.FindTime, config.SecondFactor.FindTime)
+	assert.Equal(t, schema.DefaultSecondFactorRegulationConfiguration.BanTime, config.SecondFactor.BanTime)
+}
+
+func TestShouldRaiseErrorWhenSecondFactorFindTimeLessThanBanTime(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.SecondFactor = &schema.RegulationThresholdsConfiguration{
+		MaxRetries: 5,
+		FindTime:   "1m",
+		BanTime:    "10s",
+	}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "second_factor find_time cannot be greater than ban_time")
+}
+
+func TestShouldNotSetIPRegulationByDefault(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Nil(t, config.IP)
+}
+
+func TestShouldNotEnableIPRegulationWithoutMaxRetries(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.IP = &schema.RegulationIPConfiguration{}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, 0, config.IP.MaxRetries)
+	assert.Equal(t, 32, config.IP.IPv4PrefixLength)
+	assert.Equal(t, 64, config.IP.IPv6PrefixLength)
+}
+
+func TestShouldSetDefaultIPRegulationPrefixLengths(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.IP = &schema.RegulationIPConfiguration{
+		RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{MaxRetries: 50},
+	}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, 50, config.IP.MaxRetries)
+	assert.Equal(t, schema.DefaultIPRegulationConfiguration.FindTime, config.IP.FindTime)
+	assert.Equal(t, schema.DefaultIPRegulationConfiguration.BanTime, config.IP.BanTime)
+	assert.Equal(t, 32, config.IP.IPv4PrefixLength)
+	assert.Equal(t, 64, config.IP.IPv6PrefixLength)
+}
+
+func TestShouldRaiseErrorWhenIPRegulationPrefixLengthsAreInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.IP = &schema.RegulationIPConfiguration{
+		IPv4PrefixLength: 33,
+		IPv6PrefixLength: -1,
+	}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "Regulation ip ipv4_prefix_length must be between 1 and 32 but it is configured as 33")
+	assert.EqualError(t, validator.Errors()[1], "Regulation ip ipv6_prefix_length must be between 1 and 128 but it is configured as -1")
+}
+
+func TestShouldNotSetEscalationByDefault(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Nil(t, config.Escalation)
+}
+
+func TestShouldSetDefaultEscalation(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.Escalation = &schema.RegulationEscalationConfiguration{}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, schema.DefaultRegulationEscalationConfiguration, *config.Escalation)
+}
+
+func TestShouldRaiseErrorWhenEscalationIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.Escalation = &schema.RegulationEscalationConfiguration{
+		Factor:     -1,
+		Window:     "abc",
+		MaxBanTime: "1m",
+	}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 3)
+	assert.EqualError(t, validator.Errors()[0], "Regulation escalation factor must be greater than 0 but it is configured as -1")
+	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing regulation escalation window string: Could not convert the input string of abc into a duration")
+	assert.EqualError(t, validator.Errors()[2], "Regulation escalation max_ban_time cannot be less than ban_time")
+}
@@ -2,8 +2,11 @@ package validator
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // ValidateStorage validates storage configuration.
@@ -12,6 +15,14 @@ func ValidateStorage(configuration schema.StorageConfiguration, validator *schem
 		validator.Push(errors.New("A storage configuration must be provided. It could be 'local', 'mysql' or 'postgres'"))
 	}
 
+	if configuration.EncryptionKey != "" && len(configuration.EncryptionKey) < minimumEncryptionKeyLength {
+		validator.Push(fmt.Errorf("The storage encryption_key must be at least %d characters long", minimumEncryptionKeyLength))
+	}
+
+	if configuration.Retention != nil {
+		validateStorageRetention(configuration.Retention, validator)
+	}
+
 	switch {
 	case configuration.MySQL != nil:
 		validateSQLConfiguration(&configuration.MySQL.SQLStorageConfiguration, validator)
@@ -30,13 +41,63 @@ func validateSQLConfiguration(configuration *schema.SQLStorageConfiguration, val
 	if configuration.Database == "" {
 		validator.Push(errors.New("A database must be provided"))
 	}
+
+	if configuration.MaxOpenConnections < 0 {
+		validator.Push(fmt.Errorf("The max_open_connections must be 0 or more but it is configured as %d", configuration.MaxOpenConnections))
+	}
+
+	if configuration.MaxIdleConnections < 0 {
+		validator.Push(fmt.Errorf("The max_idle_connections must be 0 or more but it is configured as %d", configuration.MaxIdleConnections))
+	}
+
+	if configuration.ConnectionMaxLifetime == "" {
+		configuration.ConnectionMaxLifetime = schema.DefaultSQLStorageConfiguration.ConnectionMaxLifetime
+	}
+
+	if _, err := utils.ParseDurationString(configuration.ConnectionMaxLifetime); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing connection_max_lifetime string: %s", err))
+	}
+
+	if configuration.QueryTimeout == "" {
+		configuration.QueryTimeout = schema.DefaultSQLStorageConfiguration.QueryTimeout
+	}
+
+	if _, err := utils.ParseDurationString(configuration.QueryTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing query_timeout string: %s", err))
+	}
+
+	if configuration.TLS != nil {
+		validateSQLTLSConfiguration(configuration.TLS, validator)
+	}
+}
+
+func validateSQLTLSConfiguration(configuration *schema.SQLStorageTLSConfiguration, validator *schema.StructValidator) {
+	if (configuration.Cert == "") != (configuration.Key == "") {
+		validator.Push(errors.New("The TLS cert and key must be provided together to authenticate with a client certificate"))
+	}
+
+	for _, file := range []string{configuration.TrustedCert, configuration.Cert, configuration.Key} {
+		if file == "" {
+			continue
+		}
+
+		if exists, _ := utils.FileExists(file); !exists {
+			validator.Push(fmt.Errorf("The TLS file %s does not exist", file))
+		}
+	}
 }
 
 func validatePostgreSQLConfiguration(configuration *schema.PostgreSQLStorageConfiguration, validator *schema.StructValidator) {
 	validateSQLConfiguration(&configuration.SQLStorageConfiguration, validator)
 
-	if configuration.SSLMode == "" {
+	switch {
+	case configuration.SSLMode == "" && configuration.TLS != nil:
+		// The certificates configured are only used if the server certificate is verified.
+		configuration.SSLMode = "verify-full"
+	case configuration.SSLMode == "":
 		configuration.SSLMode = testModeDisabled
+	case configuration.SSLMode == testModeDisabled && configuration.TLS != nil:
+		validator.Push(errors.New("SSL mode cannot be 'disable' when TLS is configured"))
 	}
 
 	if !(configuration.SSLMode == testModeDisabled || configuration.SSLMode == "require" ||
@@ -50,3 +111,63 @@ func validateLocalStorageConfiguration(configuration *schema.LocalStorageConfigu
 		validator.Push(errors.New("A file path must be provided with key 'path'"))
 	}
 }
+
+func validateStorageRetention(configuration *schema.StorageRetentionConfiguration, validator *schema.StructValidator) {
+	if configuration.Interval == "" {
+		configuration.Interval = schema.DefaultStorageRetentionConfiguration.Interval
+	}
+
+	if configuration.AuthenticationLogs == "" {
+		configuration.AuthenticationLogs = schema.DefaultStorageRetentionConfiguration.AuthenticationLogs
+	}
+
+	if _, err := utils.ParseDurationString(configuration.Interval); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage retention interval string: %s", err))
+	}
+
+	if _, err := utils.ParseDurationString(configuration.AuthenticationLogs); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage retention authentication_logs string: %s", err))
+	}
+}
+
+// validateStorageRetentionCoversRegulation checks the authentication logs are kept long enough for the regulation
+// to find the attempts leading to the current bans.
+func validateStorageRetentionCoversRegulation(retention *schema.StorageRetentionConfiguration,
+	regulation *schema.RegulationConfiguration, validator *schema.StructValidator) {
+	authenticationLogs, err := utils.ParseDurationString(retention.AuthenticationLogs)
+	if err != nil || authenticationLogs == 0 {
+		return
+	}
+
+	banTimes := []string{regulation.BanTime}
+
+	if regulation.SecondFactor != nil {
+		banTimes = append(banTimes, regulation.SecondFactor.BanTime)
+	}
+
+	if regulation.IP != nil {
+		banTimes = append(banTimes, regulation.IP.BanTime)
+	}
+
+	var lookBack time.Duration
+
+	for _, banTime := range banTimes {
+		if d, err := utils.ParseDurationString(banTime); err == nil && d > lookBack {
+			lookBack = d
+		}
+	}
+
+	if regulation.Escalation != nil && regulation.Escalation.Factor > 1 {
+		window, windowErr := utils.ParseDurationString(regulation.Escalation.Window)
+		maxBanTime, maxBanTimeErr := utils.ParseDurationString(regulation.Escalation.MaxBanTime)
+
+		if windowErr == nil && maxBanTimeErr == nil && window+maxBanTime > lookBack {
+			lookBack = window + maxBanTime
+		}
+	}
+
+	if authenticationLogs < lookBack {
+		validator.Push(fmt.Errorf("Storage retention authentication_logs must be at least %s to cover the regulation but it is configured as %s",
+			lookBack, retention.AuthenticationLogs))
+	}
+}
@@ -97,6 +97,85 @@ func (s *StorageSuite) TestShouldValidatePostgresSSLModeMustBeValid() {
 	s.Assert().EqualError(validator.Errors()[0], "SSL mode must be 'disable', 'require', 'verify-ca', or 'verify-full'")
 }
 
+func (s *StorageSuite) TestShouldSetDefaultSQLConnectionOptions() {
+	validator := schema.NewStructValidator()
+	s.configuration.MySQL = &schema.MySQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username: "myuser",
+			Password: "pass",
+			Database: "database",
+		},
+	}
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 0)
+	s.Assert().Equal("1h", s.configuration.MySQL.ConnectionMaxLifetime)
+	s.Assert().Equal("10s", s.configuration.MySQL.QueryTimeout)
+}
+
+func (s *StorageSuite) TestShouldRaiseErrorsOnInvalidSQLConnectionOptions() {
+	validator := schema.NewStructValidator()
+	s.configuration.MySQL = &schema.MySQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username:              "myuser",
+			Password:              "pass",
+			Database:              "database",
+			MaxOpenConnections:    -1,
+			MaxIdleConnections:    -1,
+			ConnectionMaxLifetime: "1 hour",
+			QueryTimeout:          "10 seconds",
+			TLS:                   &schema.SQLStorageTLSConfiguration{Cert: "/tmp/client.pem"},
+		},
+	}
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 6)
+	s.Assert().EqualError(validator.Errors()[0], "The max_open_connections must be 0 or more but it is configured as -1")
+	s.Assert().EqualError(validator.Errors()[1], "The max_idle_connections must be 0 or more but it is configured as -1")
+	s.Assert().EqualError(validator.Errors()[2], "Error occurred parsing connection_max_lifetime string: Could not convert the input string of 1 hour into a duration")
+	s.Assert().EqualError(validator.Errors()[3], "Error occurred parsing query_timeout string: Could not convert the input string of 10 seconds into a duration")
+	s.Assert().EqualError(validator.Errors()[4], "The TLS cert and key must be provided together to authenticate with a client certificate")
+	s.Assert().EqualError(validator.Errors()[5], "The TLS file /tmp/client.pem does not exist")
+}
+
+func (s *StorageSuite) TestShouldValidatePostgresSSLModeIsVerifyFullWithTLS() {
+	validator := schema.NewStructValidator()
+	s.configuration.PostgreSQL = &schema.PostgreSQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username: "myuser",
+			Password: "pass",
+			Database: "database",
+			TLS:      &schema.SQLStorageTLSConfiguration{},
+		},
+	}
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 0)
+	s.Assert().Equal("verify-full", s.configuration.PostgreSQL.SSLMode)
+
+	validator = schema.NewStructValidator()
+	s.configuration.PostgreSQL.SSLMode = "disable"
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 1)
+	s.Assert().EqualError(validator.Errors()[0], "SSL mode cannot be 'disable' when TLS is configured")
+}
+
+func (s *StorageSuite) TestShouldValidateEncryptionKeyLength() {
+	validator := schema.NewStructValidator()
+	s.configuration.EncryptionKey = "tooshort"
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 1)
+	s.Assert().EqualError(validator.Errors()[0], "The storage encryption_key must be at least 20 characters long")
+
+	validator = schema.NewStructValidator()
+	s.configuration.EncryptionKey = "a_very_long_encryption_key"
+	ValidateStorage(s.configuration, validator)
+
+	s.Require().Len(validator.Errors(), 0)
+}
+
 func TestShouldRunStorageSuite(t *testing.T) {
 	suite.Run(t, new(StorageSuite))
 }
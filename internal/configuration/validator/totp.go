@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -12,6 +13,24 @@ func ValidateTOTP(configuration *schema.TOTPConfiguration, validator *schema.Str
 		configuration.Issuer = schema.DefaultTOTPConfiguration.Issuer
 	}
 
+	switch strings.ToLower(configuration.Algorithm) {
+	case "":
+		configuration.Algorithm = schema.DefaultTOTPConfiguration.Algorithm
+	case "sha1", "sha256", "sha512":
+		configuration.Algorithm = strings.ToLower(configuration.Algorithm)
+	default:
+		validator.Push(fmt.Errorf("TOTP Algorithm must be one of 'sha1', 'sha256' or 'sha512' but it is configured as '%s'", configuration.Algorithm))
+	}
+
+	switch configuration.Digits {
+	case 0:
+		configuration.Digits = schema.DefaultTOTPConfiguration.Digits
+	case 6, 8:
+		break
+	default:
+		validator.Push(fmt.Errorf("TOTP Digits must be 6 or 8 but it is configured as %d", configuration.Digits))
+	}
+
 	if configuration.Period == 0 {
 		configuration.Period = schema.DefaultTOTPConfiguration.Period
 	} else if configuration.Period < 0 {
@@ -23,4 +42,11 @@ func ValidateTOTP(configuration *schema.TOTPConfiguration, validator *schema.Str
 	} else if *configuration.Skew < 0 {
 		validator.Push(fmt.Errorf("TOTP Skew must be 0 or more"))
 	}
+
+	// RFC 4226 requires a shared secret of at least 128 bits and recommends 160 bits.
+	if configuration.SecretSize == 0 {
+		configuration.SecretSize = schema.DefaultTOTPConfiguration.SecretSize
+	} else if configuration.SecretSize < 20 || configuration.SecretSize > 128 {
+		validator.Push(fmt.Errorf("TOTP Secret Size must be between 20 and 128 but it is configured as %d", configuration.SecretSize))
+	}
 }
@@ -19,6 +19,39 @@ func TestShouldSetDefaultTOTPValues(t *testing.T) {
 	assert.Equal(t, "Authelia", config.Issuer)
 	assert.Equal(t, *schema.DefaultTOTPConfiguration.Skew, *config.Skew)
 	assert.Equal(t, schema.DefaultTOTPConfiguration.Period, config.Period)
+	assert.Equal(t, "sha1", config.Algorithm)
+	assert.Equal(t, 6, config.Digits)
+	assert.Equal(t, 32, config.SecretSize)
+}
+
+func TestShouldNormalizeTOTPAlgorithm(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		Algorithm: "SHA512",
+		Digits:    8,
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "sha512", config.Algorithm)
+	assert.Equal(t, 8, config.Digits)
+}
+
+func TestShouldRaiseErrorWhenInvalidTOTPParameters(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		Algorithm:  "md5",
+		Digits:     7,
+		SecretSize: 10,
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 3)
+	assert.EqualError(t, validator.Errors()[0], "TOTP Algorithm must be one of 'sha1', 'sha256' or 'sha512' but it is configured as 'md5'")
+	assert.EqualError(t, validator.Errors()[1], "TOTP Digits must be 6 or 8 but it is configured as 7")
+	assert.EqualError(t, validator.Errors()[2], "TOTP Secret Size must be between 20 and 128 but it is configured as 10")
 }
 
 func TestShouldRaiseErrorWhenInvalidTOTPMinimumValues(t *testing.T) {
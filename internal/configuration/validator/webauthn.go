@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateWebauthn validates and update Webauthn configuration.
+func ValidateWebauthn(configuration *schema.WebauthnConfiguration, validator *schema.StructValidator) {
+	if configuration.DisplayName == "" {
+		configuration.DisplayName = schema.DefaultWebauthnConfiguration.DisplayName
+	}
+
+	switch configuration.ConveyancePreference {
+	case "":
+		configuration.ConveyancePreference = schema.DefaultWebauthnConfiguration.ConveyancePreference
+	case "none", "indirect", "direct":
+		break
+	default:
+		validator.Push(fmt.Errorf("Webauthn attestation_conveyance_preference must be one of 'none', 'indirect' or 'direct' but it is configured as '%s'", configuration.ConveyancePreference))
+	}
+
+	switch configuration.UserVerification {
+	case "":
+		configuration.UserVerification = schema.DefaultWebauthnConfiguration.UserVerification
+	case "discouraged", "preferred", "required":
+		break
+	default:
+		validator.Push(fmt.Errorf("Webauthn user_verification must be one of 'discouraged', 'preferred' or 'required' but it is configured as '%s'", configuration.UserVerification))
+	}
+
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultWebauthnConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing webauthn timeout string: %s", err))
+	}
+}
@@ -0,0 +1,36 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldSetDefaultWebauthnValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.WebauthnConfiguration{}
+
+	ValidateWebauthn(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, schema.DefaultWebauthnConfiguration, config)
+}
+
+func TestShouldRaiseErrorsOnInvalidWebauthnValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.WebauthnConfiguration{
+		ConveyancePreference: "enterprise",
+		UserVerification:     "sometimes",
+		Timeout:              "abc",
+	}
+
+	ValidateWebauthn(&config, validator)
+
+	require.Len(t, validator.Errors(), 3)
+	assert.EqualError(t, validator.Errors()[0], "Webauthn attestation_conveyance_preference must be one of 'none', 'indirect' or 'direct' but it is configured as 'enterprise'")
+	assert.EqualError(t, validator.Errors()[1], "Webauthn user_verification must be one of 'discouraged', 'preferred' or 'required' but it is configured as 'sometimes'")
+	assert.EqualError(t, validator.Errors()[2], "Error occurred parsing webauthn timeout string: Could not convert the input string of abc into a duration")
+}
@@ -6,6 +6,12 @@ const TOTPRegistrationAction = "RegisterTOTPDevice"
 // U2FRegistrationAction is the string representation of the action for which the token has been produced.
 const U2FRegistrationAction = "RegisterU2FDevice"
 
+// WebauthnRegistrationAction is the string representation of the action for which the token has been produced.
+const WebauthnRegistrationAction = "RegisterWebauthnDevice"
+
+// RecoveryCodesGenerationAction is the string representation of the action for which the token has been produced.
+const RecoveryCodesGenerationAction = "GenerateRecoveryCodes"
+
 // ResetPasswordAction is the string representation of the action for which the token has been produced.
 const ResetPasswordAction = "ResetPassword"
 
@@ -30,8 +36,10 @@ const (
 const operationFailedMessage = "Operation failed."
 const authenticationFailedMessage = "Authentication failed. Check your credentials."
 const userBannedMessage = "Please retry in a few minutes."
+const passwordExpiredMessage = "Your password has expired, please reset it."
 const unableToRegisterOneTimePasswordMessage = "Unable to set up one-time passwords." //nolint:gosec
 const unableToRegisterSecurityKeyMessage = "Unable to register your security key."
+const unableToGenerateRecoveryCodesMessage = "Unable to generate your recovery codes."
 const unableToResetPasswordMessage = "Unable to reset your password."
 const mfaValidationFailedMessage = "Authentication failed, please retry later."
 
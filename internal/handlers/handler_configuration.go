@@ -15,7 +15,7 @@ type ConfigurationBody struct {
 // ConfigurationGet get the configuration accessible to authenticated users.
 func ConfigurationGet(ctx *middlewares.AutheliaCtx) {
 	body := ConfigurationBody{}
-	body.AvailableMethods = MethodList{authentication.TOTP, authentication.U2F}
+	body.AvailableMethods = MethodList{authentication.TOTP, authentication.U2F, authentication.Webauthn}
 	body.TOTPPeriod = ctx.Configuration.TOTP.Period
 
 	if ctx.Configuration.DuoAPI != nil {
@@ -34,7 +34,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldServeDefaultMethods() {
 		},
 	}
 	expectedBody := ConfigurationBody{
-		AvailableMethods:    []string{"totp", "u2f"},
+		AvailableMethods:    []string{"totp", "u2f", "webauthn"},
 		SecondFactorEnabled: false,
 		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
 	}
@@ -51,7 +51,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldServeDefaultMethodsAndMo
 		},
 	}
 	expectedBody := ConfigurationBody{
-		AvailableMethods:    []string{"totp", "u2f", "mobile_push"},
+		AvailableMethods:    []string{"totp", "u2f", "webauthn", "mobile_push"},
 		SecondFactorEnabled: false,
 		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
 	}
@@ -85,7 +85,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsDisab
 	})
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
-		AvailableMethods:    []string{"totp", "u2f"},
+		AvailableMethods:    []string{"totp", "u2f", "webauthn"},
 		SecondFactorEnabled: false,
 		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
 	})
@@ -116,7 +116,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 	})
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
-		AvailableMethods:    []string{"totp", "u2f"},
+		AvailableMethods:    []string{"totp", "u2f", "webauthn"},
 		SecondFactorEnabled: true,
 		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
 	})
@@ -147,7 +147,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 	})
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
-		AvailableMethods:    []string{"totp", "u2f"},
+		AvailableMethods:    []string{"totp", "u2f", "webauthn"},
 		SecondFactorEnabled: true,
 		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
 	})
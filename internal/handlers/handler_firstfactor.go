@@ -78,11 +78,11 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
-		bannedUntil, err := ctx.Providers.Regulator.Regulate(bodyJSON.Username)
+		bannedUntil, err := ctx.Providers.Regulator.Regulate(ctx, bodyJSON.Username, ctx.RemoteIP())
 
 		if err != nil {
-			if err == regulation.ErrUserIsBanned {
-				handleAuthenticationUnauthorized(ctx, fmt.Errorf("User %s is banned until %s", bodyJSON.Username, bannedUntil), userBannedMessage)
+			if err == regulation.ErrUserIsBanned || err == regulation.ErrIPIsBanned {
+				handleAuthenticationUnauthorized(ctx, bannedError(ctx, bodyJSON.Username, bannedUntil, err), userBannedMessage)
 				return
 			}
 
@@ -94,8 +94,15 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		userPasswordOk, err := ctx.Providers.UserProvider.CheckUserPassword(bodyJSON.Username, bodyJSON.Password)
 
 		if err != nil {
+			// The password is correct but it must be reset, hence the attempt is not marked as failed and the portal
+			// leads the user to the reset password process.
+			if err == authentication.ErrPasswordExpired {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error while checking password for user %s: %s", bodyJSON.Username, err.Error()), passwordExpiredMessage)
+				return
+			}
+
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-			ctx.Providers.Regulator.Mark(bodyJSON.Username, false) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
+			ctx.Providers.Regulator.Mark(ctx, bodyJSON.Username, ctx.RemoteIP(), regulation.AuthType1FA, false) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error while checking password for user %s: %s", bodyJSON.Username, err.Error()), authenticationFailedMessage)
 
@@ -104,7 +111,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 
 		if !userPasswordOk {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-			ctx.Providers.Regulator.Mark(bodyJSON.Username, false) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
+			ctx.Providers.Regulator.Mark(ctx, bodyJSON.Username, ctx.RemoteIP(), regulation.AuthType1FA, false) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Credentials are wrong for user %s", bodyJSON.Username), authenticationFailedMessage)
 
@@ -116,7 +123,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		ctx.Logger.Debugf("Credentials validation of user %s is ok", bodyJSON.Username)
 
 		ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-		err = ctx.Providers.Regulator.Mark(bodyJSON.Username, true)
+		err = ctx.Providers.Regulator.Mark(ctx, bodyJSON.Username, ctx.RemoteIP(), regulation.AuthType1FA, true)
 
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err.Error()), authenticationFailedMessage)
@@ -15,6 +15,7 @@ import (
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
 )
 
 type FirstFactorSuite struct {
@@ -58,9 +59,11 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderCheckPasswordFail() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
 			Username:   "test",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
 			Time:       s.mock.Clock.Now(),
 		}))
 
@@ -75,6 +78,56 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderCheckPasswordFail() {
 	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
 }
 
+func (s *FirstFactorSuite) TestShouldReplyPasswordExpiredIfUserProviderCheckPasswordReturnsPasswordExpired() {
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, authentication.ErrPasswordExpired)
+
+	// The attempt is not marked as failed since the password is correct.
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": true
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), "Error while checking password for user test: user password has expired", s.mock.Hook.LastEntry().Message)
+	s.mock.Assert401KO(s.T(), "Your password has expired, please reset it.")
+}
+
+func (s *FirstFactorSuite) TestShouldNotRevealDisabledAccountIfUserProviderCheckPasswordReturnsUserDisabled() {
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, authentication.ErrUserDisabled)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   "test",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		}))
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": true
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), "Error while checking password for user test: user account is disabled", s.mock.Hook.LastEntry().Message)
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+}
+
 func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCredentials() {
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -83,9 +136,11 @@ func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCrede
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
 			Username:   "test",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
 			Time:       s.mock.Clock.Now(),
 		}))
 
@@ -106,7 +161,7 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(nil)
 
 	s.mock.UserProviderMock.
@@ -133,7 +188,7 @@ func (s *FirstFactorSuite) TestShouldFailIfAuthenticationMarkFail() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(fmt.Errorf("failed"))
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -164,7 +219,7 @@ func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeChecked() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(nil)
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -204,7 +259,7 @@ func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeUnchecked() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(nil)
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -247,7 +302,7 @@ func (s *FirstFactorSuite) TestShouldSaveUsernameFromAuthenticationBackendInSess
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(nil)
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -305,7 +360,7 @@ func (s *FirstFactorRedirectionSuite) SetupTest() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
+		AppendAuthenticationLog(gomock.Any(), gomock.Any()).
 		Return(nil)
 }
 
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+)
+
+// SecondFactorRecoveryCodesIdentityStart the handler for initiating the identity validation.
+var SecondFactorRecoveryCodesIdentityStart = middlewares.IdentityVerificationStart(middlewares.IdentityVerificationStartArgs{
+	MailTitle:             "Generate your recovery codes",
+	MailButtonContent:     "Generate",
+	TargetEndpoint:        "/recovery-codes/generate",
+	ActionClaim:           RecoveryCodesGenerationAction,
+	IdentityRetrieverFunc: identityRetrieverFromSession,
+})
+
+// secondFactorRecoveryCodesIdentityFinish generates a new set of recovery codes replacing the previous ones.
+// The codes are only sent to the user once since only their hashes are stored.
+func secondFactorRecoveryCodesIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	response := RecoveryCodesResponse{
+		Codes: make([]string, 0, recoveryCodesCount),
+	}
+	codes := make([]models.RecoveryCode, 0, recoveryCodesCount)
+	now := ctx.Clock.Now()
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			ctx.Error(fmt.Errorf("Unable to generate recovery code: %s", err), unableToGenerateRecoveryCodesMessage)
+			return
+		}
+
+		hash, err := hashRecoveryCode(code)
+		if err != nil {
+			ctx.Error(fmt.Errorf("Unable to hash recovery code: %s", err), unableToGenerateRecoveryCodesMessage)
+			return
+		}
+
+		response.Codes = append(response.Codes, code)
+		codes = append(codes, models.RecoveryCode{
+			Username:  username,
+			Hash:      hash,
+			CreatedAt: now,
+		})
+	}
+
+	err := ctx.Providers.StorageProvider.SaveRecoveryCodes(ctx, username, codes)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save recovery codes in DB: %s", err), unableToGenerateRecoveryCodesMessage)
+		return
+	}
+
+	err = ctx.SetJSONBody(response)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to set recovery codes in body: %s", err), unableToGenerateRecoveryCodesMessage)
+		return
+	}
+}
+
+// SecondFactorRecoveryCodesIdentityFinish the handler for finishing the identity validation.
+var SecondFactorRecoveryCodesIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{
+		ActionClaim:          RecoveryCodesGenerationAction,
+		IsTokenUserValidFunc: isTokenUserValidFor2FARegistration,
+	}, secondFactorRecoveryCodesIdentityFinish)
@@ -3,12 +3,16 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/session"
 )
 
+const defaultTOTPDeviceDescription = "Mobile"
+
 // identityRetrieverFromSession retriever computing the identity from the cookie session.
 func identityRetrieverFromSession(ctx *middlewares.AutheliaCtx) (*session.Identity, error) {
 	userSession := ctx.GetSession()
@@ -37,11 +41,32 @@ var SecondFactorTOTPIdentityStart = middlewares.IdentityVerificationStart(middle
 })
 
 func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	var requestBody registerTOTPRequestBody
+
+	err := ctx.ParseBody(&requestBody)
+	if err != nil {
+		ctx.Error(err, unableToRegisterOneTimePasswordMessage)
+		return
+	}
+
+	description := requestBody.Description
+	if description == "" {
+		description = defaultTOTPDeviceDescription
+	}
+
+	algorithm, err := totpAlgorithm(ctx.Configuration.TOTP.Algorithm)
+	if err != nil {
+		ctx.Error(err, unableToRegisterOneTimePasswordMessage)
+		return
+	}
+
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      ctx.Configuration.TOTP.Issuer,
 		AccountName: username,
-		SecretSize:  32,
+		SecretSize:  uint(ctx.Configuration.TOTP.SecretSize),
 		Period:      uint(ctx.Configuration.TOTP.Period),
+		Digits:      otp.Digits(ctx.Configuration.TOTP.Digits),
+		Algorithm:   algorithm,
 	})
 
 	if err != nil {
@@ -49,7 +74,15 @@ func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username strin
 		return
 	}
 
-	err = ctx.Providers.StorageProvider.SaveTOTPSecret(username, key.Secret())
+	err = ctx.Providers.StorageProvider.SaveTOTPConfiguration(ctx, models.TOTPConfiguration{
+		Username:    username,
+		Description: description,
+		Secret:      key.Secret(),
+		Algorithm:   ctx.Configuration.TOTP.Algorithm,
+		Digits:      ctx.Configuration.TOTP.Digits,
+		Period:      ctx.Configuration.TOTP.Period,
+		CreatedAt:   ctx.Clock.Now(),
+	})
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to save TOTP secret in DB: %s", err), unableToRegisterOneTimePasswordMessage)
 		return
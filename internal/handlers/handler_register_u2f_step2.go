@@ -7,12 +7,15 @@ import (
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
 )
 
+const defaultU2FDeviceDescription = "Security Key"
+
 // SecondFactorU2FRegister handler validating the client has successfully validated the challenge
 // to complete the U2F registration.
 func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
-	responseBody := u2f.RegisterResponse{}
+	responseBody := registerU2FRequestBody{}
 	err := ctx.ParseBody(&responseBody)
 
 	if err != nil {
@@ -31,7 +34,7 @@ func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
 		ctx.SaveSession(userSession) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 	}()
 
-	registration, err := u2f.Register(responseBody, *userSession.U2FChallenge, u2fConfig)
+	registration, err := u2f.Register(responseBody.RegisterResponse, *userSession.U2FChallenge, u2fConfig)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to verify U2F registration: %v", err), unableToRegisterSecurityKeyMessage)
@@ -40,8 +43,18 @@ func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
 
 	ctx.Logger.Debugf("Register U2F device for user %s", userSession.Username)
 
-	publicKey := elliptic.Marshal(elliptic.P256(), registration.PubKey.X, registration.PubKey.Y)
-	err = ctx.Providers.StorageProvider.SaveU2FDeviceHandle(userSession.Username, registration.KeyHandle, publicKey)
+	description := responseBody.Description
+	if description == "" {
+		description = defaultU2FDeviceDescription
+	}
+
+	err = ctx.Providers.StorageProvider.SaveU2FDevice(ctx, models.U2FDevice{
+		Username:    userSession.Username,
+		Description: description,
+		KeyHandle:   registration.KeyHandle,
+		PublicKey:   elliptic.Marshal(elliptic.P256(), registration.PubKey.X, registration.PubKey.Y),
+		CreatedAt:   ctx.Clock.Now(),
+	})
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to register U2F device for user %s: %v", userSession.Username, err), unableToRegisterSecurityKeyMessage)
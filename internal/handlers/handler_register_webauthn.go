@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+)
+
+const defaultWebauthnDeviceDescription = "Security Key"
+
+// SecondFactorWebauthnIdentityStart the handler for initiating the identity validation.
+var SecondFactorWebauthnIdentityStart = middlewares.IdentityVerificationStart(middlewares.IdentityVerificationStartArgs{
+	MailTitle:             "Register your key",
+	MailButtonContent:     "Register",
+	TargetEndpoint:        "/webauthn/register",
+	ActionClaim:           WebauthnRegistrationAction,
+	IdentityRetrieverFunc: identityRetrieverFromSession,
+})
+
+func secondFactorWebauthnIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	w, err := newWebauthn(ctx, "")
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to configure Webauthn: %s", err), operationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	user, err := loadWebauthnUser(ctx, username, userSession.DisplayName)
+	if err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	// Prevent the user from registering the same device twice.
+	creation, sessionData, err := w.BeginRegistration(user, webauthn.WithExclusions(user.credentialDescriptors()))
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate Webauthn registration challenge: %s", err), operationFailedMessage)
+		return
+	}
+
+	userSession.Webauthn = sessionData
+	err = ctx.SaveSession(userSession)
+
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save Webauthn session data in session: %s", err), operationFailedMessage)
+		return
+	}
+
+	err = ctx.SetJSONBody(creation)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to set Webauthn registration challenge in body: %s", err), operationFailedMessage)
+		return
+	}
+}
+
+// SecondFactorWebauthnIdentityFinish the handler for finishing the identity validation.
+var SecondFactorWebauthnIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{
+		ActionClaim:          WebauthnRegistrationAction,
+		IsTokenUserValidFunc: isTokenUserValidFor2FARegistration,
+	}, secondFactorWebauthnIdentityFinish)
+
+// SecondFactorWebauthnAttestationPost handler validating the attestation of the authenticator
+// to complete the Webauthn registration.
+func SecondFactorWebauthnAttestationPost(ctx *middlewares.AutheliaCtx) {
+	var requestBody registerWebauthnRequestBody
+
+	err := ctx.ParseBody(&requestBody)
+	if err != nil {
+		ctx.Error(err, unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if userSession.Webauthn == nil {
+		ctx.Error(fmt.Errorf("Webauthn registration has not been initiated yet"), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	sessionData := *userSession.Webauthn
+
+	// Ensure the session data is cleared if anything goes wrong.
+	defer func() {
+		userSession.Webauthn = nil
+
+		if err := ctx.SaveSession(userSession); err != nil {
+			ctx.Logger.Errorf("Unable to clear Webauthn session data of user %s: %s", userSession.Username, err)
+		}
+	}()
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(ctx.PostBody()))
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to parse Webauthn attestation: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	w, err := newWebauthn(ctx, "")
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to configure Webauthn: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	user, err := loadWebauthnUser(ctx, userSession.Username, userSession.DisplayName)
+	if err != nil {
+		ctx.Error(err, unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	// The attestation statement, the challenge, the origin and the relying party are verified here.
+	credential, err := w.CreateCredential(user, sessionData, parsedResponse)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to verify Webauthn attestation: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	description := requestBody.Description
+	if description == "" {
+		description = defaultWebauthnDeviceDescription
+	}
+
+	ctx.Logger.Debugf("Register Webauthn device for user %s", userSession.Username)
+
+	err = ctx.Providers.StorageProvider.SaveWebauthnDevice(ctx, models.WebauthnDevice{
+		Username:        userSession.Username,
+		Description:     description,
+		KID:             credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		CreatedAt:       ctx.Clock.Now(),
+	})
+
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to register Webauthn device for user %s: %s", userSession.Username, err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	ctx.ReplyOK()
+}
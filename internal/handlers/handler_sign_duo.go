@@ -7,6 +7,7 @@ import (
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/duo"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
 )
 
 // SecondFactorDuoPost handler for sending a push notification via duo api.
@@ -23,6 +24,10 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		userSession := ctx.GetSession()
 		remoteIP := ctx.RemoteIP().String()
 
+		if isBannedFromSecondFactor(ctx, userSession.Username) {
+			return
+		}
+
 		ctx.Logger.Debugf("Starting Duo Push Auth Attempt for %s from IP %s", userSession.Username, remoteIP)
 
 		values := url.Values{}
@@ -54,10 +59,17 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		}
 
 		if duoResponse.Response.Result != testResultAllow {
+			markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeDuo, false) //nolint:errcheck // The failed attempt is more relevant.
 			ctx.ReplyUnauthorized()
 			return
 		}
 
+		err = markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeDuo, true)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+			return
+		}
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
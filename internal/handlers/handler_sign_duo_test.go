@@ -13,6 +13,8 @@ import (
 
 	"github.com/authelia/authelia/internal/duo"
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
 )
 
 type SecondFactorDuoPostSuite struct {
@@ -47,6 +49,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndAllowAccess() {
 
 	duoMock.EXPECT().Call(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
@@ -69,6 +81,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndDenyAccess() {
 
 	duoMock.EXPECT().Call(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
@@ -103,6 +125,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToDefaultURL() {
 
 	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
 
 	bodyBytes, err := json.Marshal(signDuoRequestBody{})
@@ -123,6 +155,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotReturnRedirectURL() {
 
 	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	bodyBytes, err := json.Marshal(signDuoRequestBody{})
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
@@ -139,6 +181,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToSafeTargetURL() {
 
 	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "https://mydomain.local",
 	})
@@ -159,6 +211,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotRedirectToUnsafeURL() {
 
 	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "http://mydomain.local",
 	})
@@ -177,6 +239,16 @@ func (s *SecondFactorDuoPostSuite) TestShouldRegenerateSessionForPreventingSessi
 
 	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "http://mydomain.local",
 	})
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+)
+
+// SecondFactorRecoveryCodePost validate a recovery code provided by the user and consume it.
+func SecondFactorRecoveryCodePost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := signRecoveryCodeRequestBody{}
+	err := ctx.ParseBody(&bodyJSON)
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if isBannedFromSecondFactor(ctx, userSession.Username) {
+		return
+	}
+
+	codes, err := ctx.Providers.StorageProvider.LoadRecoveryCodes(ctx, userSession.Username)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to load recovery codes: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	var code *models.RecoveryCode
+
+	for i := range codes {
+		isValid, err := authentication.CheckPassword(normalizeRecoveryCode(bodyJSON.Code), codes[i].Hash)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error occurred during recovery code validation for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			return
+		}
+
+		if isValid {
+			code = &codes[i]
+			break
+		}
+	}
+
+	if code == nil {
+		markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeRecoveryCode, false) //nolint:errcheck // The failed attempt is more relevant.
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong recovery code for user %s", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	// Consuming the code fails if a concurrent request has already used it.
+	err = ctx.Providers.StorageProvider.ConsumeRecoveryCode(ctx, code.ID, ctx.Clock.Now())
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to consume recovery code of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeRecoveryCode, true)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	err = ctx.SaveSession(userSession)
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update the authentication level with recovery code: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	Handle2FAResponse(ctx, bodyJSON.TargetURL)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerSignRecoveryCodeSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerSignRecoveryCodeSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = authentication.OneFactor
+	s.mock.Ctx.SaveSession(userSession) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerSignRecoveryCodeSuite) setBody(code string) {
+	bodyBytes, err := json.Marshal(signRecoveryCodeRequestBody{
+		Code: code,
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldConsumeCodeAndRaiseAuthenticationLevel() {
+	first, err := hashRecoveryCode("AAAA-BBBB-CCCC")
+	s.Require().NoError(err)
+	second, err := hashRecoveryCode("DDDD-EEEE-FFFF")
+	s.Require().NoError(err)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodes(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.RecoveryCode{{ID: 1, Hash: first}, {ID: 2, Hash: second}}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		ConsumeRecoveryCode(gomock.Any(), gomock.Eq(2), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeRecoveryCode,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	s.setBody("dddd eeee ffff")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenCodeIsWrong() {
+	hash, err := hashRecoveryCode("AAAA-BBBB-CCCC")
+	s.Require().NoError(err)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodes(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.RecoveryCode{{ID: 1, Hash: hash}}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Type:       regulation.AuthTypeRecoveryCode,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	s.setBody("AAAA-BBBB-CCCD")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Wrong recovery code for user john", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenCodeHasAlreadyBeenConsumed() {
+	hash, err := hashRecoveryCode("AAAA-BBBB-CCCC")
+	s.Require().NoError(err)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodes(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.RecoveryCode{{ID: 1, Hash: hash}}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		ConsumeRecoveryCode(gomock.Any(), gomock.Eq(1), gomock.Any()).
+		Return(storage.ErrRecoveryCodeAlreadyUsed)
+
+	s.setBody("AAAA-BBBB-CCCC")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func TestRunHandlerSignRecoveryCodeSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSignRecoveryCodeSuite))
+}
+
+func TestShouldGenerateRecoveryCodes(t *testing.T) {
+	code, err := generateRecoveryCode()
+	require.NoError(t, err)
+
+	assert.Regexp(t, "^[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}$", code)
+	assert.Equal(t, "ABCDEFGHJKLM", normalizeRecoveryCode("abcd-efgh jklm"))
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
 )
 
 // SecondFactorTOTPPost validate the TOTP passcode provided by the user.
@@ -20,20 +23,60 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 
 		userSession := ctx.GetSession()
 
-		secret, err := ctx.Providers.StorageProvider.LoadTOTPSecret(userSession.Username)
+		if isBannedFromSecondFactor(ctx, userSession.Username) {
+			return
+		}
+
+		configurations, err := ctx.Providers.StorageProvider.LoadTOTPConfigurations(ctx, userSession.Username)
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to load TOTP secret: %s", err), mfaValidationFailedMessage)
 			return
 		}
 
-		isValid, err := totpVerifier.Verify(bodyJSON.Token, secret)
+		// The passcode is accepted if it has been generated by any of the devices registered by the user.
+		var (
+			configuration *models.TOTPConfiguration
+			step          uint64
+		)
+
+		for i := range configurations {
+			isValid, validStep, err := totpVerifier.Verify(bodyJSON.Token, configurations[i])
+			if err != nil {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error occurred during OTP validation for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+				return
+			}
+
+			if isValid {
+				configuration = &configurations[i]
+				step = validStep
+
+				break
+			}
+		}
+
+		if configuration == nil {
+			markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeTOTP, false) //nolint:errcheck // The failed attempt is more relevant.
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong passcode during TOTP validation for user %s", userSession.Username), mfaValidationFailedMessage)
+			return
+		}
+
+		// A passcode is rejected if a passcode of the same or a later time-step has already been accepted
+		// for this device, so that a passcode cannot be replayed.
+		err = ctx.Providers.StorageProvider.UpdateTOTPConfigurationSignIn(ctx, configuration.ID, step, ctx.Clock.Now())
+		if err == storage.ErrTOTPStepAlreadyUsed {
+			markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeTOTP, false) //nolint:errcheck // The failed attempt is more relevant.
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Replayed passcode during TOTP validation for user %s", userSession.Username), mfaValidationFailedMessage)
+			return
+		}
+
 		if err != nil {
-			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error occurred during OTP validation for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to record the usage of TOTP device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
 			return
 		}
 
-		if !isValid {
-			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong passcode during TOTP validation for user %s", userSession.Username), mfaValidationFailedMessage)
+		err = markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeTOTP, true)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
 			return
 		}
 
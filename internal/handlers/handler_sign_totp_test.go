@@ -2,15 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
 	"github.com/tstranex/u2f"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
 )
 
 type HandlerSignTOTPSuite struct {
@@ -24,7 +30,7 @@ func (s *HandlerSignTOTPSuite) SetupTest() {
 	userSession := s.mock.Ctx.GetSession()
 	userSession.Username = testUsername
 	userSession.U2FChallenge = &u2f.Challenge{}
-	userSession.U2FRegistration = &session.U2FRegistration{}
+	userSession.U2FRegistrations = []session.U2FRegistration{{}}
 	s.mock.Ctx.SaveSession(userSession) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 }
 
@@ -36,12 +42,26 @@ func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToDefaultURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Secret: "secret"})).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
 
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
 
@@ -61,12 +81,26 @@ func (s *HandlerSignTOTPSuite) TestShouldNotReturnRedirectURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Secret: "secret"})).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token: "abc",
@@ -82,12 +116,26 @@ func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToSafeTargetURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Secret: "secret"})).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token:     "abc",
@@ -106,12 +154,26 @@ func (s *HandlerSignTOTPSuite) TestShouldNotRedirectToUnsafeURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Secret: "secret"})).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token:     "abc",
@@ -128,12 +190,26 @@ func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFi
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Secret: "secret"})).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token: "abc",
@@ -152,6 +228,190 @@ func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFi
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
 }
 
+func (s *HandlerSignTOTPSuite) TestShouldAcceptPasscodeOfAnyRegisteredDevice() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{
+			{ID: 1, Username: testUsername, Description: "Phone", Secret: "phone"},
+			{ID: 2, Username: testUsername, Description: "Tablet", Secret: "tablet"},
+		}, nil)
+
+	gomock.InOrder(
+		verifier.EXPECT().
+			Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 1, Username: testUsername, Description: "Phone", Secret: "phone"})).
+			Return(false, uint64(0), nil),
+		verifier.EXPECT().
+			Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPConfiguration{ID: 2, Username: testUsername, Description: "Tablet", Secret: "tablet"})).
+			Return(true, uint64(1000), nil),
+	)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(2), gomock.Eq(uint64(1000)), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldFailWhenNoDeviceAcceptsPasscode() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{
+			{ID: 1, Username: testUsername, Description: "Phone", Secret: "phone"},
+			{ID: 2, Username: testUsername, Description: "Tablet", Secret: "tablet"},
+		}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Any()).
+		Return(false, uint64(0), nil).
+		Times(2)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Wrong passcode during TOTP validation for user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldFailWhenPasscodeIsReplayed() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPConfigurations(gomock.Any(), gomock.Any()).
+		Return([]models.TOTPConfiguration{{ID: 1, Username: testUsername, Secret: "secret"}}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Any()).
+		Return(true, uint64(1000), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPConfigurationSignIn(gomock.Any(), gomock.Eq(1), gomock.Eq(uint64(1000)), gomock.Any()).
+		Return(storage.ErrTOTPStepAlreadyUsed)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Replayed passcode during TOTP validation for user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldEndSessionWhenUserIsBannedFromSecondFactor() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		FindTime: "2m",
+		BanTime:  "5m",
+		SecondFactor: &schema.RegulationThresholdsConfiguration{
+			MaxRetries: 2,
+			FindTime:   "2m",
+			BanTime:    "5m",
+		},
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq(testUsername), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: testUsername, Successful: false, Type: regulation.AuthTypeTOTP, Time: s.mock.Clock.Now().Add(-10 * time.Second)},
+			{Username: testUsername, Successful: false, Type: regulation.AuthTypeTOTP, Time: s.mock.Clock.Now().Add(-20 * time.Second)},
+		}, nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), userBannedMessage)
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldEndSessionWhenRemoteIPIsBanned() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		FindTime: "2m",
+		BanTime:  "5m",
+		IP: &schema.RegulationIPConfiguration{
+			RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{
+				MaxRetries: 2,
+				FindTime:   "2m",
+				BanTime:    "5m",
+			},
+			IPv4PrefixLength: 24,
+			IPv6PrefixLength: 64,
+		},
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "192.168.1.20")
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogsByNetwork(gomock.Any(), gomock.Eq(&net.IPNet{IP: net.ParseIP("192.168.1.0").To4(), Mask: net.CIDRMask(24, 32)}), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: "bob", Successful: false, Type: regulation.AuthType1FA, RemoteIP: net.ParseIP("192.168.1.10"), Time: s.mock.Clock.Now().Add(-10 * time.Second)},
+			{Username: "harry", Successful: false, Type: regulation.AuthType1FA, RemoteIP: net.ParseIP("192.168.1.20"), Time: s.mock.Clock.Now().Add(-20 * time.Second)},
+		}, nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), userBannedMessage)
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+	s.Assert().Contains(s.mock.Hook.LastEntry().Message, "Remote IP 192.168.1.20 of user john is banned until")
+}
+
 func TestRunHandlerSignTOTPSuite(t *testing.T) {
 	suite.Run(t, new(HandlerSignTOTPSuite))
 }
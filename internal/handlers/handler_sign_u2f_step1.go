@@ -34,7 +34,7 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 	}
 
 	userSession := ctx.GetSession()
-	keyHandleBytes, publicKeyBytes, err := ctx.Providers.StorageProvider.LoadU2FDeviceHandle(userSession.Username)
+	devices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(ctx, userSession.Username)
 
 	if err != nil {
 		if err == storage.ErrNoU2FDeviceHandle {
@@ -47,18 +47,29 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	var registration u2f.Registration
-	registration.KeyHandle = keyHandleBytes
-	x, y := elliptic.Unmarshal(elliptic.P256(), publicKeyBytes)
-	registration.PubKey.Curve = elliptic.P256()
-	registration.PubKey.X = x
-	registration.PubKey.Y = y
-
-	// Save the challenge and registration for use in next request
-	userSession.U2FRegistration = &session.U2FRegistration{
-		KeyHandle: keyHandleBytes,
-		PublicKey: publicKeyBytes,
+	registrations := make([]u2f.Registration, 0, len(devices))
+	userSession.U2FRegistrations = make([]session.U2FRegistration, 0, len(devices))
+
+	for _, device := range devices {
+		var registration u2f.Registration
+		registration.KeyHandle = device.KeyHandle
+		x, y := elliptic.Unmarshal(elliptic.P256(), device.PublicKey)
+		registration.PubKey.Curve = elliptic.P256()
+		registration.PubKey.X = x
+		registration.PubKey.Y = y
+
+		registrations = append(registrations, registration)
+
+		// Save the registrations for use in next request.
+		userSession.U2FRegistrations = append(userSession.U2FRegistrations, session.U2FRegistration{
+			ID:        device.ID,
+			KeyHandle: device.KeyHandle,
+			PublicKey: device.PublicKey,
+			SignCount: device.SignCount,
+		})
 	}
+
+	// Save the challenge for use in next request.
 	userSession.U2FChallenge = challenge
 	err = ctx.SaveSession(userSession)
 
@@ -67,7 +78,7 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	signRequest := challenge.SignRequest([]u2f.Registration{registration})
+	signRequest := challenge.SignRequest(registrations)
 	err = ctx.SetJSONBody(signRequest)
 
 	if err != nil {
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
 )
 
 type HandlerSignU2FStep1Suite struct {
@@ -38,6 +45,40 @@ func (s *HandlerSignU2FStep1Suite) TestShouldRaiseWhenXForwardedHostIsMissing()
 	assert.Equal(s.T(), "Missing header X-Fowarded-Host", s.mock.Hook.LastEntry().Message)
 }
 
+func (s *HandlerSignU2FStep1Suite) TestShouldListAllRegisteredDevices() {
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Host", "login.example.com")
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	publicKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.U2FDevice{
+			{ID: 1, Username: testUsername, Description: "Primary", KeyHandle: []byte("primary"), PublicKey: publicKey},
+			{ID: 2, Username: testUsername, Description: "Backup", KeyHandle: []byte("backup"), PublicKey: publicKey},
+		}, nil)
+
+	SecondFactorU2FSignGet(s.mock.Ctx)
+
+	signRequest := u2f.WebSignRequest{}
+	s.mock.GetResponseData(s.T(), &signRequest)
+	s.Require().Len(signRequest.RegisteredKeys, 2)
+	assert.Equal(s.T(), base64.RawURLEncoding.EncodeToString([]byte("primary")), signRequest.RegisteredKeys[0].KeyHandle)
+	assert.Equal(s.T(), base64.RawURLEncoding.EncodeToString([]byte("backup")), signRequest.RegisteredKeys[1].KeyHandle)
+
+	userSession = s.mock.Ctx.GetSession()
+	s.Require().Len(userSession.U2FRegistrations, 2)
+	assert.Equal(s.T(), 2, userSession.U2FRegistrations[1].ID)
+}
+
 func TestShouldRunHandlerSignU2FStep1Suite(t *testing.T) {
 	suite.Run(t, new(HandlerSignU2FStep1Suite))
 }
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
 )
 
 // SecondFactorU2FSignPost handler for completing a signing request.
@@ -19,27 +24,53 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 		}
 
 		userSession := ctx.GetSession()
+
+		if isBannedFromSecondFactor(ctx, userSession.Username) {
+			return
+		}
+
 		if userSession.U2FChallenge == nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("U2F signing has not been initiated yet (no challenge)"), mfaValidationFailedMessage)
 			return
 		}
 
-		if userSession.U2FRegistration == nil {
+		if len(userSession.U2FRegistrations) == 0 {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("U2F signing has not been initiated yet (no registration)"), mfaValidationFailedMessage)
 			return
 		}
 
-		err = u2fVerifier.Verify(
-			userSession.U2FRegistration.KeyHandle,
-			userSession.U2FRegistration.PublicKey,
+		registration, err := findU2FRegistration(userSession.U2FRegistrations, requestBody.SignResponse.KeyHandle)
+		if err != nil {
+			markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeU2F, false) //nolint:errcheck // The failed attempt is more relevant.
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
+		signCount, err := u2fVerifier.Verify(
+			registration.KeyHandle,
+			registration.PublicKey,
+			registration.SignCount,
 			requestBody.SignResponse,
 			*userSession.U2FChallenge)
 
 		if err != nil {
+			markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeU2F, false) //nolint:errcheck // The failed attempt is more relevant.
 			ctx.Error(err, mfaValidationFailedMessage)
 			return
 		}
 
+		err = ctx.Providers.StorageProvider.UpdateU2FDeviceSignIn(ctx, registration.ID, signCount, ctx.Clock.Now())
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to record the usage of U2F device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			return
+		}
+
+		err = markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeU2F, true)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+			return
+		}
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -47,6 +78,7 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			return
 		}
 
+		userSession.U2FRegistrations = nil
 		userSession.AuthenticationLevel = authentication.TwoFactor
 		err = ctx.SaveSession(userSession)
 
@@ -58,3 +90,20 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 		Handle2FAResponse(ctx, requestBody.TargetURL)
 	}
 }
+
+// findU2FRegistration returns the registration of the device which signed the challenge given the websafe
+// base64 encoded key handle returned by the device.
+func findU2FRegistration(registrations []session.U2FRegistration, keyHandle string) (*session.U2FRegistration, error) {
+	keyHandleBytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(keyHandle, "="))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode U2F key handle: %s", err)
+	}
+
+	for i, registration := range registrations {
+		if bytes.Equal(registration.KeyHandle, keyHandleBytes) {
+			return &registrations[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("U2F key handle does not match any registered device")
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/session"
 )
 
@@ -24,7 +26,7 @@ func (s *HandlerSignU2FStep2Suite) SetupTest() {
 	userSession := s.mock.Ctx.GetSession()
 	userSession.Username = testUsername
 	userSession.U2FChallenge = &u2f.Challenge{}
-	userSession.U2FRegistration = &session.U2FRegistration{}
+	userSession.U2FRegistrations = []session.U2FRegistration{{}}
 	s.mock.Ctx.SaveSession(userSession) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 }
 
@@ -36,7 +38,21 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRedirectUserToDefaultURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(uint32(1), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
 		Return(nil)
 
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
@@ -57,7 +73,21 @@ func (s *HandlerSignU2FStep2Suite) TestShouldNotReturnRedirectURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(uint32(1), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
@@ -74,7 +104,21 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRedirectUserToSafeTargetURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(uint32(1), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
@@ -94,7 +138,21 @@ func (s *HandlerSignU2FStep2Suite) TestShouldNotRedirectToUnsafeURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(uint32(1), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
@@ -112,7 +170,21 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRegenerateSessionForPreventingSessi
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(uint32(1), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
@@ -132,6 +204,68 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRegenerateSessionForPreventingSessi
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
 }
 
+func (s *HandlerSignU2FStep2Suite) TestShouldRecordUsageOfSigningDevice() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.U2FRegistrations = []session.U2FRegistration{
+		{ID: 1, KeyHandle: []byte("primary")},
+		{ID: 2, KeyHandle: []byte("backup"), SignCount: 41},
+	}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	u2fVerifier.EXPECT().
+		Verify(gomock.Eq([]byte("backup")), gomock.Any(), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceSignIn(gomock.Any(), gomock.Eq(2), gomock.Eq(uint32(42)), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: "YmFja3Vw"},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerSignU2FStep2Suite) TestShouldFailWhenKeyHandleIsNotRegistered() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Type:       regulation.AuthTypeU2F,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: "YmFja3Vw"},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("U2F key handle does not match any registered device", s.mock.Hook.LastEntry().Message)
+}
+
 func TestRunHandlerSignU2FStep2Suite(t *testing.T) {
 	suite.Run(t, new(HandlerSignU2FStep2Suite))
 }
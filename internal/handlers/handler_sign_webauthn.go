@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+)
+
+// SecondFactorWebauthnAssertionGet handler for initiating an assertion ceremony.
+func SecondFactorWebauthnAssertionGet(ctx *middlewares.AutheliaCtx) {
+	w, err := newWebauthn(ctx, "")
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to configure Webauthn: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	user, err := loadWebauthnUser(ctx, userSession.Username, userSession.DisplayName)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	if len(user.WebAuthnCredentials()) == 0 {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("No Webauthn device found for user %s", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	var opts []webauthn.LoginOption
+
+	// Devices registered with the legacy U2F endpoints are scoped to the appid instead of the relying party ID.
+	if len(user.LegacyCredentials) > 0 {
+		opts = append(opts, webauthn.WithAssertionExtensions(protocol.AuthenticationExtensions{
+			"appid": webauthnAppID(ctx),
+		}))
+	}
+
+	assertion, sessionData, err := w.BeginLogin(user, opts...)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to create Webauthn assertion challenge: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.Webauthn = sessionData
+	err = ctx.SaveSession(userSession)
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to save Webauthn session data in session: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = ctx.SetJSONBody(assertion)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to set assertion challenge in body: %s", err), mfaValidationFailedMessage)
+		return
+	}
+}
+
+// SecondFactorWebauthnAssertionPost handler for completing an assertion ceremony.
+func SecondFactorWebauthnAssertionPost(ctx *middlewares.AutheliaCtx) {
+	var requestBody signWebauthnRequestBody
+
+	err := ctx.ParseBody(&requestBody)
+	if err != nil {
+		ctx.Error(err, mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if isBannedFromSecondFactor(ctx, userSession.Username) {
+		return
+	}
+
+	if userSession.Webauthn == nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Webauthn assertion has not been initiated yet"), mfaValidationFailedMessage)
+		return
+	}
+
+	sessionData := *userSession.Webauthn
+
+	// The challenge is consumed by this attempt whatever its outcome so that a failed assertion cannot be retried.
+	userSession.Webauthn = nil
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to clear Webauthn session data: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(ctx.PostBody()))
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to parse Webauthn assertion: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	// When the appid extension has been used by the client, the authenticator data is scoped to the appid.
+	rpID := ""
+	appIDHash := sha256.Sum256([]byte(webauthnAppID(ctx)))
+
+	if bytes.Equal(parsedResponse.Response.AuthenticatorData.RPIDHash, appIDHash[:]) {
+		rpID = webauthnAppID(ctx)
+	}
+
+	w, err := newWebauthn(ctx, rpID)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to configure Webauthn: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	user, err := loadWebauthnUser(ctx, userSession.Username, userSession.DisplayName)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	credential, err := w.ValidateLogin(user, sessionData, parsedResponse)
+	if err != nil {
+		markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeWebauthn, false) //nolint:errcheck // The failed attempt is more relevant.
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to validate Webauthn assertion: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	// A signature counter lower or equal to the stored one means the authenticator has likely been cloned.
+	if credential.Authenticator.CloneWarning {
+		markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeWebauthn, false) //nolint:errcheck // The failed attempt is more relevant.
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Webauthn device of user %s has a signature counter lower than expected, it might have been cloned", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	if device := user.deviceByKID(credential.ID); device != nil {
+		err = ctx.Providers.StorageProvider.UpdateWebauthnDeviceSignIn(ctx, device.ID, credential.Authenticator.SignCount, ctx.Clock.Now())
+	} else if device := user.u2fDeviceByKeyHandle(credential.ID); device != nil {
+		err = ctx.Providers.StorageProvider.UpdateU2FDeviceSignIn(ctx, device.ID, credential.Authenticator.SignCount, ctx.Clock.Now())
+	}
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update Webauthn device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = markSecondFactorAttempt(ctx, userSession.Username, regulation.AuthTypeWebauthn, true)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	err = ctx.SaveSession(userSession)
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update authentication level with Webauthn: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	Handle2FAResponse(ctx, requestBody.TargetURL)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerSignWebauthnSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerSignWebauthnSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Configuration.Webauthn = &schema.DefaultWebauthnConfiguration
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	require.NoError(s.T(), err)
+}
+
+func (s *HandlerSignWebauthnSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldRaiseWhenXForwardedProtoIsMissing() {
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	assert.Equal(s.T(), 401, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Unable to configure Webauthn: Missing header X-Fowarded-Proto", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldRaiseWhenNoDeviceIsRegistered() {
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Host", "login.example.com")
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoWebauthnDevice)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "No Webauthn device found for user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldRequestAppIDExtensionForLegacyDevices() {
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Add("X-Forwarded-Host", "login.example.com")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(s.T(), err)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.WebauthnDevice{{ID: 1, Username: testUsername, KID: []byte("kid")}}, nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq(testUsername)).
+		Return([]models.U2FDevice{{
+			ID:        1,
+			Username:  testUsername,
+			KeyHandle: []byte("handle"),
+			PublicKey: elliptic.Marshal(elliptic.P256(), key.X, key.Y),
+		}}, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+
+	userSession := s.mock.Ctx.GetSession()
+	require.NotNil(s.T(), userSession.Webauthn)
+	assert.Len(s.T(), userSession.Webauthn.AllowedCredentialIDs, 2)
+	assert.Contains(s.T(), string(s.mock.Ctx.Response.Body()), "\"appid\":\"https://login.example.com\"")
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldRaiseWhenAssertionHasNotBeenInitiated() {
+	s.mock.Ctx.Request.SetBodyString("{}")
+
+	SecondFactorWebauthnAssertionPost(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "Webauthn assertion has not been initiated yet", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldClearSessionDataWhenAssertionFails() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Webauthn = &webauthn.SessionData{Challenge: "challenge", UserID: []byte(testUsername)}
+	require.NoError(s.T(), s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.Request.SetBodyString("{}")
+
+	SecondFactorWebauthnAssertionPost(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Nil(s.T(), s.mock.Ctx.GetSession().Webauthn)
+}
+
+func TestShouldRunHandlerSignWebauthnSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSignWebauthnSuite))
+}
+
+func TestShouldConvertU2FDeviceToWebauthnCredential(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credential, err := u2fDeviceToWebauthnCredential([]byte("handle"), elliptic.Marshal(elliptic.P256(), key.X, key.Y))
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("handle"), credential.ID)
+	assert.Len(t, credential.PublicKey, 77)
+	assert.Equal(t, padCoordinate(key.X.Bytes()), credential.PublicKey[10:42])
+	assert.Equal(t, padCoordinate(key.Y.Bytes()), credential.PublicKey[45:77])
+
+	_, err = u2fDeviceToWebauthnCredential([]byte("handle"), []byte("abc"))
+	assert.EqualError(t, err, "Unable to decode the public key of the U2F device")
+}
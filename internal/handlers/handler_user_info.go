@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -13,17 +14,17 @@ import (
 	"github.com/authelia/authelia/internal/utils"
 )
 
-func loadInfo(username string, storageProvider storage.Provider, userInfo *UserInfo, logger *logrus.Entry) []error {
+func loadInfo(ctx context.Context, username string, storageProvider storage.Provider, userInfo *UserInfo, logger *logrus.Entry) []error {
 	var wg sync.WaitGroup
 
-	wg.Add(3)
+	wg.Add(4)
 
 	errors := make([]error, 0)
 
 	go func() {
 		defer wg.Done()
 
-		method, err := storageProvider.LoadPreferred2FAMethod(username)
+		method, err := storageProvider.LoadPreferred2FAMethod(ctx, username)
 		if err != nil {
 			errors = append(errors, err)
 			logger.Error(err)
@@ -41,7 +42,7 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 	go func() {
 		defer wg.Done()
 
-		_, _, err := storageProvider.LoadU2FDeviceHandle(username)
+		_, err := storageProvider.LoadU2FDevicesByUsername(ctx, username)
 		if err != nil {
 			if err == storage.ErrNoU2FDeviceHandle {
 				return
@@ -59,7 +60,7 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 	go func() {
 		defer wg.Done()
 
-		_, err := storageProvider.LoadTOTPSecret(username)
+		configurations, err := storageProvider.LoadTOTPConfigurations(ctx, username)
 		if err != nil {
 			if err == storage.ErrNoTOTPSecret {
 				return
@@ -72,6 +73,33 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 		}
 
 		userInfo.HasTOTP = true
+
+		for _, configuration := range configurations {
+			userInfo.TOTPDevices = append(userInfo.TOTPDevices, DeviceInfo{
+				ID:          configuration.ID,
+				Description: configuration.Description,
+				CreatedAt:   configuration.CreatedAt,
+				LastUsedAt:  configuration.LastUsedAt,
+			})
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		_, err := storageProvider.LoadWebauthnDevicesByUsername(ctx, username)
+		if err != nil {
+			if err == storage.ErrNoWebauthnDevice {
+				return
+			}
+
+			errors = append(errors, err)
+			logger.Error(err)
+
+			return
+		}
+
+		userInfo.HasWebauthn = true
 	}()
 
 	wg.Wait()
@@ -84,7 +112,7 @@ func UserInfoGet(ctx *middlewares.AutheliaCtx) {
 	userSession := ctx.GetSession()
 
 	userInfo := UserInfo{}
-	errors := loadInfo(userSession.Username, ctx.Providers.StorageProvider, &userInfo, ctx.Logger)
+	errors := loadInfo(ctx, userSession.Username, ctx.Providers.StorageProvider, &userInfo, ctx.Logger)
 
 	if len(errors) > 0 {
 		ctx.Error(fmt.Errorf("Unable to load user information"), operationFailedMessage)
@@ -118,7 +146,7 @@ func MethodPreferencePost(ctx *middlewares.AutheliaCtx) {
 
 	userSession := ctx.GetSession()
 	ctx.Logger.Debugf("Save new preferred 2FA method of user %s to %s", userSession.Username, bodyJSON.Method)
-	err = ctx.Providers.StorageProvider.SavePreferred2FAMethod(userSession.Username, bodyJSON.Method)
+	err = ctx.Providers.StorageProvider.SavePreferred2FAMethod(ctx, userSession.Username, bodyJSON.Method)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to save new preferred 2FA method: %s", err), operationFailedMessage)
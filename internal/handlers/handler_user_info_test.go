@@ -7,9 +7,11 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/storage"
 )
 
@@ -34,33 +36,44 @@ func (s *FetchSuite) TearDownTest() {
 func setPreferencesExpectations(preferences UserInfo, provider *storage.MockProvider) {
 	provider.
 		EXPECT().
-		LoadPreferred2FAMethod(gomock.Eq("john")).
+		LoadPreferred2FAMethod(gomock.Any(), gomock.Eq("john")).
 		Return(preferences.Method, nil)
 
 	if preferences.HasU2F {
 		u2fData := []byte("abc")
 		provider.
 			EXPECT().
-			LoadU2FDeviceHandle(gomock.Eq("john")).
-			Return(u2fData, u2fData, nil)
+			LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+			Return([]models.U2FDevice{{ID: 1, Username: "john", KeyHandle: u2fData, PublicKey: u2fData}}, nil)
 	} else {
 		provider.
 			EXPECT().
-			LoadU2FDeviceHandle(gomock.Eq("john")).
-			Return(nil, nil, storage.ErrNoU2FDeviceHandle)
+			LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+			Return(nil, storage.ErrNoU2FDeviceHandle)
 	}
 
 	if preferences.HasTOTP {
-		totpSecret := "secret"
 		provider.
 			EXPECT().
-			LoadTOTPSecret(gomock.Eq("john")).
-			Return(totpSecret, nil)
+			LoadTOTPConfigurations(gomock.Any(), gomock.Eq("john")).
+			Return([]models.TOTPConfiguration{{ID: 1, Username: "john", Description: "Mobile", Secret: "secret"}}, nil)
 	} else {
 		provider.
 			EXPECT().
-			LoadTOTPSecret(gomock.Eq("john")).
-			Return("", storage.ErrNoTOTPSecret)
+			LoadTOTPConfigurations(gomock.Any(), gomock.Eq("john")).
+			Return(nil, storage.ErrNoTOTPSecret)
+	}
+
+	if preferences.HasWebauthn {
+		provider.
+			EXPECT().
+			LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+			Return([]models.WebauthnDevice{{ID: 1, Username: "john"}}, nil)
+	} else {
+		provider.
+			EXPECT().
+			LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+			Return(nil, storage.ErrNoWebauthnDevice)
 	}
 }
 
@@ -84,6 +97,12 @@ func TestMethodSetToU2F(t *testing.T) {
 			HasU2F:  false,
 			HasTOTP: false,
 		},
+		{
+			Method:      "webauthn",
+			HasU2F:      false,
+			HasTOTP:     true,
+			HasWebauthn: true,
+		},
 	}
 
 	for _, expectedPreferences := range table {
@@ -111,6 +130,19 @@ func TestMethodSetToU2F(t *testing.T) {
 		t.Run("registered totp", func(t *testing.T) {
 			assert.Equal(t, expectedPreferences.HasTOTP, actualPreferences.HasTOTP)
 		})
+
+		t.Run("registered totp devices", func(t *testing.T) {
+			if expectedPreferences.HasTOTP {
+				require.Len(t, actualPreferences.TOTPDevices, 1)
+				assert.Equal(t, "Mobile", actualPreferences.TOTPDevices[0].Description)
+			} else {
+				assert.Len(t, actualPreferences.TOTPDevices, 0)
+			}
+		})
+
+		t.Run("registered webauthn", func(t *testing.T) {
+			assert.Equal(t, expectedPreferences.HasWebauthn, actualPreferences.HasWebauthn)
+		})
 		mock.Close()
 	}
 }
@@ -118,18 +150,23 @@ func TestMethodSetToU2F(t *testing.T) {
 func (s *FetchSuite) TestShouldGetDefaultPreferenceIfNotInDB() {
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadPreferred2FAMethod(gomock.Eq("john")).
+		LoadPreferred2FAMethod(gomock.Any(), gomock.Eq("john")).
 		Return("", nil)
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadU2FDeviceHandle(gomock.Eq("john")).
-		Return(nil, nil, storage.ErrNoU2FDeviceHandle)
+		LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadTOTPSecret(gomock.Eq("john")).
-		Return("", storage.ErrNoTOTPSecret)
+		LoadTOTPConfigurations(gomock.Any(), gomock.Eq("john")).
+		Return(nil, storage.ErrNoTOTPSecret)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq("john")).
+		Return(nil, storage.ErrNoWebauthnDevice)
 
 	UserInfoGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), UserInfo{Method: "totp"})
@@ -137,16 +174,20 @@ func (s *FetchSuite) TestShouldGetDefaultPreferenceIfNotInDB() {
 
 func (s *FetchSuite) TestShouldReturnError500WhenStorageFailsToLoad() {
 	s.mock.StorageProviderMock.EXPECT().
-		LoadPreferred2FAMethod(gomock.Eq("john")).
+		LoadPreferred2FAMethod(gomock.Any(), gomock.Eq("john")).
 		Return("", fmt.Errorf("Failure"))
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadU2FDeviceHandle(gomock.Eq("john"))
+		LoadU2FDevicesByUsername(gomock.Any(), gomock.Eq("john"))
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadTOTPConfigurations(gomock.Any(), gomock.Eq("john"))
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadTOTPSecret(gomock.Eq("john"))
+		LoadWebauthnDevicesByUsername(gomock.Any(), gomock.Eq("john"))
 
 	UserInfoGet(s.mock.Ctx)
 
@@ -209,14 +250,14 @@ func (s *SaveSuite) TestShouldReturnError500WhenBadMethodProvided() {
 	MethodPreferencePost(s.mock.Ctx)
 
 	s.mock.Assert200KO(s.T(), "Operation failed.")
-	assert.Equal(s.T(), "Unknown method 'abc', it should be one of totp, u2f, mobile_push", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), "Unknown method 'abc', it should be one of totp, u2f, mobile_push, webauthn", s.mock.Hook.LastEntry().Message)
 	assert.Equal(s.T(), logrus.ErrorLevel, s.mock.Hook.LastEntry().Level)
 }
 
 func (s *SaveSuite) TestShouldReturnError500WhenDatabaseFailsToSave() {
 	s.mock.Ctx.Request.SetBody([]byte("{\"method\":\"u2f\"}"))
 	s.mock.StorageProviderMock.EXPECT().
-		SavePreferred2FAMethod(gomock.Eq("john"), gomock.Eq("u2f")).
+		SavePreferred2FAMethod(gomock.Any(), gomock.Eq("john"), gomock.Eq("u2f")).
 		Return(fmt.Errorf("Failure"))
 
 	MethodPreferencePost(s.mock.Ctx)
@@ -229,7 +270,7 @@ func (s *SaveSuite) TestShouldReturnError500WhenDatabaseFailsToSave() {
 func (s *SaveSuite) TestShouldReturn200WhenMethodIsSuccessfullySaved() {
 	s.mock.Ctx.Request.SetBody([]byte("{\"method\":\"u2f\"}"))
 	s.mock.StorageProviderMock.EXPECT().
-		SavePreferred2FAMethod(gomock.Eq("john"), gomock.Eq("u2f")).
+		SavePreferred2FAMethod(gomock.Any(), gomock.Eq("john"), gomock.Eq("u2f")).
 		Return(nil)
 
 	MethodPreferencePost(s.mock.Ctx)
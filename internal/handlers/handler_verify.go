@@ -210,10 +210,12 @@ func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userS
 
 	err = verifySessionHasUpToDateProfile(ctx, targetURL, userSession, refreshProfile, refreshProfileInterval)
 	if err != nil {
-		if err == authentication.ErrUserNotFound {
+		if err == authentication.ErrUserNotFound || err == authentication.ErrUserDisabled {
+			ctx.Logger.Debugf("Destroying the session of user %s since the authentication backend returned: %s", userSession.Username, err)
+
 			err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 			if err != nil {
-				ctx.Logger.Error(fmt.Errorf("Unable to destroy user session after provider refresh didn't find the user: %s", err))
+				ctx.Logger.Error(fmt.Errorf("Unable to destroy user session after provider refresh didn't find the user or found it disabled: %s", err))
 			}
 
 			return userSession.Username, userSession.Groups, authentication.NotAuthenticated, err
@@ -317,7 +319,7 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 	ctx.Logger.Tracef("Checking if we need check the authentication backend for an updated profile for %s.", userSession.Username)
 
 	if refreshProfile && userSession.Username != "" && targetURL != nil &&
-		ctx.Providers.Authorizer.IsURLMatchingRuleWithGroupSubjects(*targetURL) &&
+		(isAccountStateChecked(ctx.Configuration.AuthenticationBackend) || ctx.Providers.Authorizer.IsURLMatchingRuleWithGroupSubjects(*targetURL)) &&
 		(refreshProfileInterval == schema.RefreshIntervalAlways || userSession.RefreshTTL.Before(ctx.Clock.Now())) {
 		ctx.Logger.Debugf("Checking the authentication backend for an updated profile for user %s", userSession.Username)
 		details, err := ctx.Providers.UserProvider.GetDetails(userSession.Username)
@@ -363,6 +365,12 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 	return nil
 }
 
+// isAccountStateChecked returns true if the authentication backend reads the state of the accounts, in which case the
+// profile is refreshed whatever the URL so that the sessions of the accounts disabled after login are destroyed.
+func isAccountStateChecked(cfg schema.AuthenticationBackendConfiguration) bool {
+	return cfg.Ldap != nil && cfg.Ldap.AccountState != "" && cfg.Ldap.AccountState != schema.LDAPAccountStateNone
+}
+
 func getProfileRefreshSettings(cfg schema.AuthenticationBackendConfiguration) (refresh bool, refreshInterval time.Duration) {
 	if cfg.Ldap != nil {
 		if cfg.RefreshInterval != schema.ProfileRefreshDisabled {
@@ -997,3 +997,36 @@ func TestShouldGetAddedUserGroupsFromBackend(t *testing.T) {
 	assert.Equal(t, "users", userSession.Groups[1])
 	assert.Equal(t, "grafana", userSession.Groups[2])
 }
+
+func TestShouldDestroySessionWhenUserIsDisabledInBackend(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	// The profile is refreshed even though two-factor.example.com has no group subject since the state of the
+	// accounts is checked.
+	mock.Ctx.Configuration.AuthenticationBackend.Ldap = &schema.LDAPAuthenticationBackendConfiguration{
+		AccountState: schema.LDAPAccountStateActiveDirectory,
+	}
+
+	mock.UserProviderMock.EXPECT().GetDetails("john").Return(nil, authentication.ErrUserDisabled).Times(1)
+
+	clock := mocks.TestingClock{}
+	clock.Set(time.Now())
+
+	userSession := mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.LastActivity = clock.Now().Unix()
+	userSession.RefreshTTL = clock.Now().Add(-1 * time.Minute)
+	err := mock.Ctx.SaveSession(userSession)
+	require.NoError(t, err)
+
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.com")
+	VerifyGet(verifyGetCfg)(mock.Ctx)
+	assert.Equal(t, 401, mock.Ctx.Response.StatusCode())
+
+	// The session has been destroyed.
+	newUserSession := mock.Ctx.GetSession()
+	assert.Equal(t, "", newUserSession.Username)
+	assert.Equal(t, authentication.NotAuthenticated, newUserSession.AuthenticationLevel)
+}
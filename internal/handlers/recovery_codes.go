@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"github.com/authelia/authelia/internal/authentication"
+)
+
+// recoveryCodesCount is the number of recovery codes generated for a user.
+const recoveryCodesCount = 10
+
+// recoveryCodeLength is the number of characters of a recovery code excluding the separator.
+const recoveryCodeLength = 12
+
+// recoveryCodeCharacters excludes characters which are easily confused with each other.
+var recoveryCodeCharacters = []rune("ABCDEFGHJKLMNPQRSTUVWXYZ23456789")
+
+// recoveryCodeHashIterations is the number of rounds of the SHA512 crypt hash of recovery codes. The codes
+// are random enough for a relatively low number of rounds and all the codes of a user might have to be
+// checked during a single authentication attempt.
+const recoveryCodeHashIterations = 10000
+
+const recoveryCodeHashSaltLength = 16
+
+// generateRecoveryCode generates a random recovery code formatted as XXXX-XXXX-XXXX.
+func generateRecoveryCode() (string, error) {
+	var code strings.Builder
+
+	max := big.NewInt(int64(len(recoveryCodeCharacters)))
+
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i > 0 && i%4 == 0 {
+			code.WriteRune('-')
+		}
+
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		code.WriteRune(recoveryCodeCharacters[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+// normalizeRecoveryCode removes the separators and whitespaces of a code typed by a user.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// hashRecoveryCode hashes a recovery code so that it can be stored.
+func hashRecoveryCode(code string) (string, error) {
+	return authentication.HashPassword(normalizeRecoveryCode(code), "", authentication.HashingAlgorithmSHA512,
+		recoveryCodeHashIterations, 0, 0, 0, recoveryCodeHashSaltLength)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+)
+
+// isBannedFromSecondFactor checks whether the user is banned from the second factor and replies accordingly
+// if it is the case. A banned user is logged out so that the session authenticated with the first factor
+// cannot be used anymore to brute force the second factor.
+func isBannedFromSecondFactor(ctx *middlewares.AutheliaCtx, username string) bool {
+	bannedUntil, err := ctx.Providers.Regulator.RegulateSecondFactor(ctx, username, ctx.RemoteIP())
+	if err == nil {
+		return false
+	}
+
+	if err == regulation.ErrUserIsBanned || err == regulation.ErrIPIsBanned {
+		destroyErr := ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
+		if destroyErr != nil {
+			ctx.Logger.Errorf("Unable to destroy the session of banned user %s: %s", username, destroyErr)
+		}
+
+		handleAuthenticationUnauthorized(ctx, bannedError(ctx, username, bannedUntil, err), userBannedMessage)
+
+		return true
+	}
+
+	handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regulate authentication: %s", err), mfaValidationFailedMessage)
+
+	return true
+}
+
+// markSecondFactorAttempt marks an attempt made with a second factor. When the attempt failed, the user
+// is logged out right away if it is the attempt leading to the ban.
+func markSecondFactorAttempt(ctx *middlewares.AutheliaCtx, username, authType string, successful bool) error {
+	ctx.Logger.Debugf("Mark %s authentication attempt made by user %s", authType, username)
+
+	err := ctx.Providers.Regulator.Mark(ctx, username, ctx.RemoteIP(), authType, successful)
+	if err != nil || successful {
+		return err
+	}
+
+	_, err = ctx.Providers.Regulator.RegulateSecondFactor(ctx, username, ctx.RemoteIP())
+	if err == regulation.ErrUserIsBanned || err == regulation.ErrIPIsBanned {
+		return ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
+	}
+
+	return nil
+}
+
+// bannedError returns the error logged when an attempt is rejected because of a ban.
+func bannedError(ctx *middlewares.AutheliaCtx, username string, bannedUntil time.Time, err error) error {
+	if err == regulation.ErrIPIsBanned {
+		return fmt.Errorf("Remote IP %s of user %s is banned until %s", ctx.RemoteIP(), username, bannedUntil)
+	}
+
+	return fmt.Errorf("User %s is banned until %s", username, bannedUntil)
+}
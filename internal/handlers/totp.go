@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/pquerna/otp"
-	"github.com/pquerna/otp/totp"
+	"github.com/pquerna/otp/hotp"
+
+	"github.com/authelia/authelia/internal/models"
 )
 
-// TOTPVerifier is the interface for verifying TOTPs.
+// TOTPVerifier is the interface for verifying TOTPs. It returns the time-step the passcode has been
+// generated for when it is valid.
 type TOTPVerifier interface {
-	Verify(token, secret string) (bool, error)
+	Verify(token string, configuration models.TOTPConfiguration) (bool, uint64, error)
 }
 
 // TOTPVerifierImpl the production implementation for TOTP verification.
@@ -18,14 +22,52 @@ type TOTPVerifierImpl struct {
 	Skew   uint
 }
 
-// Verify verifies TOTPs.
-func (tv *TOTPVerifierImpl) Verify(token, secret string) (bool, error) {
-	opts := totp.ValidateOpts{
-		Period:    tv.Period,
-		Skew:      tv.Skew,
-		Digits:    otp.DigitsSix,
-		Algorithm: otp.AlgorithmSHA1,
+// Verify verifies TOTPs against the parameters the device has been registered with.
+func (tv *TOTPVerifierImpl) Verify(token string, configuration models.TOTPConfiguration) (bool, uint64, error) {
+	algorithm, err := totpAlgorithm(configuration.Algorithm)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// Devices registered before the period was recorded use the configured period.
+	period := tv.Period
+	if configuration.Period > 0 {
+		period = uint(configuration.Period)
+	}
+
+	opts := hotp.ValidateOpts{
+		Digits:    otp.Digits(configuration.Digits),
+		Algorithm: algorithm,
+	}
+
+	current := time.Now().UTC().Unix() / int64(period)
+
+	// The latest time-steps are checked first so that the step recorded to prevent replays is the
+	// highest one the passcode is valid for.
+	for step := current + int64(tv.Skew); step >= current-int64(tv.Skew) && step >= 0; step-- {
+		valid, err := hotp.ValidateCustom(token, uint64(step), configuration.Secret, opts)
+		if err != nil {
+			return false, 0, err
+		}
+
+		if valid {
+			return true, uint64(step), nil
+		}
 	}
 
-	return totp.ValidateCustom(token, secret, time.Now().UTC(), opts)
+	return false, 0, nil
+}
+
+// totpAlgorithm returns the OTP hashing algorithm matching its name in the configuration.
+func totpAlgorithm(name string) (otp.Algorithm, error) {
+	switch name {
+	case "sha1":
+		return otp.AlgorithmSHA1, nil
+	case "sha256":
+		return otp.AlgorithmSHA256, nil
+	case "sha512":
+		return otp.AlgorithmSHA512, nil
+	default:
+		return otp.AlgorithmSHA1, fmt.Errorf("Unknown TOTP algorithm %s", name)
+	}
 }
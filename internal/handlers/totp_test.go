@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/models"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func TestShouldVerifyTOTPWithRegisteredParameters(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 1}
+
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, time.Now().UTC(), totp.ValidateOpts{
+		Period:    60,
+		Digits:    otp.DigitsEight,
+		Algorithm: otp.AlgorithmSHA256,
+	})
+	require.NoError(t, err)
+
+	valid, step, err := verifier.Verify(code, models.TOTPConfiguration{Secret: testTOTPSecret, Algorithm: "sha256", Digits: 8, Period: 60})
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.InDelta(t, time.Now().Unix()/60, step, 1)
+
+	valid, _, err = verifier.Verify(code, models.TOTPConfiguration{Secret: testTOTPSecret, Algorithm: "sha1", Digits: 8, Period: 60})
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestShouldVerifyTOTPWithConfiguredPeriodWhenNotRecorded(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 1}
+
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, time.Now().UTC(), totp.ValidateOpts{
+		Period:    30,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	require.NoError(t, err)
+
+	valid, _, err := verifier.Verify(code, models.TOTPConfiguration{Secret: testTOTPSecret, Algorithm: "sha1", Digits: 6})
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestShouldFailVerifyingTOTPWithUnknownAlgorithm(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 1}
+
+	_, _, err := verifier.Verify("123456", models.TOTPConfiguration{Secret: testTOTPSecret, Algorithm: "md5", Digits: 6})
+	assert.EqualError(t, err, "Unknown TOTP algorithm md5")
+}
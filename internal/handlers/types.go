@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/authentication"
@@ -24,6 +26,20 @@ type UserInfo struct {
 
 	// True if a TOTP device has been registered.
 	HasTOTP bool `json:"has_totp" valid:"required"`
+
+	// The TOTP devices registered by the user.
+	TOTPDevices []DeviceInfo `json:"totp_devices"`
+
+	// True if a Webauthn device has been registered.
+	HasWebauthn bool `json:"has_webauthn" valid:"required"`
+}
+
+// DeviceInfo is the model of a second factor device registered by a user.
+type DeviceInfo struct {
+	ID          int        `json:"id"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
 }
 
 // signTOTPRequestBody model of the request body received by TOTP authentication endpoint.
@@ -32,12 +48,43 @@ type signTOTPRequestBody struct {
 	TargetURL string `json:"targetURL"`
 }
 
+// signRecoveryCodeRequestBody model of the request body received by recovery code authentication endpoint.
+type signRecoveryCodeRequestBody struct {
+	Code      string `json:"code" valid:"required"`
+	TargetURL string `json:"targetURL"`
+}
+
 // signU2FRequestBody model of the request body of U2F authentication endpoint.
 type signU2FRequestBody struct {
 	SignResponse u2f.SignResponse `json:"signResponse"`
 	TargetURL    string           `json:"targetURL"`
 }
 
+// signWebauthnRequestBody model of the request body of Webauthn assertion endpoint. The assertion of
+// the authenticator is parsed separately from the same body.
+type signWebauthnRequestBody struct {
+	TargetURL string `json:"targetURL"`
+}
+
+// registerWebauthnRequestBody model of the request body of Webauthn attestation endpoint. The attestation
+// of the authenticator is parsed separately from the same body.
+type registerWebauthnRequestBody struct {
+	Description string `json:"description" valid:"length(0|30)"`
+}
+
+// registerTOTPRequestBody model of the request body of TOTP registration endpoint. The identity verification
+// token is parsed separately from the same body.
+type registerTOTPRequestBody struct {
+	Description string `json:"description" valid:"length(0|30)"`
+}
+
+// registerU2FRequestBody model of the request body of U2F registration endpoint.
+type registerU2FRequestBody struct {
+	u2f.RegisterResponse
+
+	Description string `json:"description" valid:"length(0|30)"`
+}
+
 type signDuoRequestBody struct {
 	TargetURL string `json:"targetURL"`
 }
@@ -64,6 +111,11 @@ type TOTPKeyResponse struct {
 	OTPAuthURL   string `json:"otpauth_url"`
 }
 
+// RecoveryCodesResponse is the model of response that is sent to the client upon successful identity verification.
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
 // StateResponse represents the response sent by the state endpoint.
 type StateResponse struct {
 	Username              string               `json:"username"`
@@ -6,17 +6,19 @@ import (
 	"github.com/tstranex/u2f"
 )
 
-// U2FVerifier is the interface for verifying U2F keys.
+// U2FVerifier is the interface for verifying U2F keys. The signature counter returned by the device is returned so
+// that it can be checked against at the next sign in.
 type U2FVerifier interface {
-	Verify(keyHandle []byte, publicKey []byte, signResponse u2f.SignResponse, challenge u2f.Challenge) error
+	Verify(keyHandle []byte, publicKey []byte, signCount uint32, signResponse u2f.SignResponse, challenge u2f.Challenge) (uint32, error)
 }
 
 // U2FVerifierImpl the production implementation for U2F key verification.
 type U2FVerifierImpl struct{}
 
-// Verify verifies U2F keys.
-func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte,
-	signResponse u2f.SignResponse, challenge u2f.Challenge) error {
+// Verify verifies U2F keys. It fails when the signature counter is lower than the one of the previous sign in since
+// the device might have been cloned.
+func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte, signCount uint32,
+	signResponse u2f.SignResponse, challenge u2f.Challenge) (uint32, error) {
 	var registration u2f.Registration
 	registration.KeyHandle = keyHandle
 	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
@@ -24,9 +26,5 @@ func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte,
 	registration.PubKey.X = x
 	registration.PubKey.Y = y
 
-	// TODO(c.michaud): store the counter to help detecting cloned U2F keys.
-	_, err := registration.Authenticate(
-		signResponse, challenge, 0)
-
-	return err
+	return registration.Authenticate(signResponse, challenge, signCount)
 }
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"net"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// webauthnUser is the representation of an Authelia user expected by the Webauthn library.
+type webauthnUser struct {
+	Username    string
+	DisplayName string
+
+	// Devices registered through the Webauthn endpoints.
+	Devices []models.WebauthnDevice
+
+	// Devices registered through the legacy U2F endpoints and their credential representation.
+	U2FDevices        []models.U2FDevice
+	LegacyCredentials []webauthn.Credential
+}
+
+// WebAuthnID implements webauthn.User.
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(u.Username)
+}
+
+// WebAuthnName implements webauthn.User.
+func (u *webauthnUser) WebAuthnName() string {
+	return u.Username
+}
+
+// WebAuthnDisplayName implements webauthn.User.
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.DisplayName == "" {
+		return u.Username
+	}
+
+	return u.DisplayName
+}
+
+// WebAuthnIcon implements webauthn.User.
+func (u *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials implements webauthn.User.
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Devices)+len(u.LegacyCredentials))
+
+	for _, device := range u.Devices {
+		credentials = append(credentials, webauthn.Credential{
+			ID:              device.KID,
+			PublicKey:       device.PublicKey,
+			AttestationType: device.AttestationType,
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    device.AAGUID,
+				SignCount: device.SignCount,
+			},
+		})
+	}
+
+	return append(credentials, u.LegacyCredentials...)
+}
+
+// credentialDescriptors returns the descriptors of all the credentials of the user.
+func (u *webauthnUser) credentialDescriptors() []protocol.CredentialDescriptor {
+	credentials := u.WebAuthnCredentials()
+	descriptors := make([]protocol.CredentialDescriptor, len(credentials))
+
+	for i, credential := range credentials {
+		descriptors[i] = protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: credential.ID,
+		}
+	}
+
+	return descriptors
+}
+
+// deviceByKID returns the Webauthn device having the given credential ID or nil if it is a legacy device.
+func (u *webauthnUser) deviceByKID(kid []byte) *models.WebauthnDevice {
+	for i, device := range u.Devices {
+		if string(device.KID) == string(kid) {
+			return &u.Devices[i]
+		}
+	}
+
+	return nil
+}
+
+// u2fDeviceByKeyHandle returns the legacy U2F device having the given key handle or nil if there is none.
+func (u *webauthnUser) u2fDeviceByKeyHandle(keyHandle []byte) *models.U2FDevice {
+	for i, device := range u.U2FDevices {
+		if string(device.KeyHandle) == string(keyHandle) {
+			return &u.U2FDevices[i]
+		}
+	}
+
+	return nil
+}
+
+// loadWebauthnUser loads the Webauthn and legacy U2F devices of a user from the storage.
+func loadWebauthnUser(ctx *middlewares.AutheliaCtx, username, displayName string) (*webauthnUser, error) {
+	user := &webauthnUser{
+		Username:    username,
+		DisplayName: displayName,
+	}
+
+	devices, err := ctx.Providers.StorageProvider.LoadWebauthnDevicesByUsername(ctx, username)
+	if err != nil && err != storage.ErrNoWebauthnDevice {
+		return nil, fmt.Errorf("Unable to load Webauthn devices: %s", err)
+	}
+
+	user.Devices = devices
+
+	u2fDevices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(ctx, username)
+	if err != nil && err != storage.ErrNoU2FDeviceHandle {
+		return nil, fmt.Errorf("Unable to load U2F devices: %s", err)
+	}
+
+	user.U2FDevices = u2fDevices
+
+	for _, device := range u2fDevices {
+		credential, err := u2fDeviceToWebauthnCredential(device.KeyHandle, device.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Authenticator.SignCount = device.SignCount
+		user.LegacyCredentials = append(user.LegacyCredentials, *credential)
+	}
+
+	return user, nil
+}
+
+// u2fDeviceToWebauthnCredential converts a device registered with the legacy U2F endpoints into a
+// Webauthn credential. The raw P-256 public key is encoded into its COSE representation.
+func u2fDeviceToWebauthnCredential(keyHandle, publicKey []byte) (*webauthn.Credential, error) {
+	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
+	if x == nil {
+		return nil, fmt.Errorf("Unable to decode the public key of the U2F device")
+	}
+
+	// COSE_Key map {1: 2 (EC2), 3: -7 (ES256), -1: 1 (P-256), -2: x, -3: y}.
+	cose := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	cose = append(cose, padCoordinate(x.Bytes())...)
+	cose = append(cose, 0x22, 0x58, 0x20)
+	cose = append(cose, padCoordinate(y.Bytes())...)
+
+	return &webauthn.Credential{
+		ID:              keyHandle,
+		PublicKey:       cose,
+		AttestationType: "fido-u2f",
+	}, nil
+}
+
+func padCoordinate(b []byte) []byte {
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+
+	return padded
+}
+
+// webauthnAppID returns the U2F appid of the legacy devices registered by the user.
+func webauthnAppID(ctx *middlewares.AutheliaCtx) string {
+	return fmt.Sprintf("%s://%s", ctx.XForwardedProto(), ctx.XForwardedHost())
+}
+
+// newWebauthn creates the Webauthn relying party of the domain the request comes from. When rpID is empty,
+// the relying party ID is the host of the request.
+func newWebauthn(ctx *middlewares.AutheliaCtx, rpID string) (*webauthn.WebAuthn, error) {
+	if ctx.XForwardedProto() == nil {
+		return nil, errMissingXForwardedProto
+	}
+
+	if ctx.XForwardedHost() == nil {
+		return nil, errMissingXForwardedHost
+	}
+
+	if rpID == "" {
+		rpID = string(ctx.XForwardedHost())
+
+		if host, _, err := net.SplitHostPort(rpID); err == nil {
+			rpID = host
+		}
+	}
+
+	configuration := ctx.Configuration.Webauthn
+
+	timeout, err := utils.ParseDurationString(configuration.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPDisplayName:         configuration.DisplayName,
+		RPID:                  rpID,
+		RPOrigin:              webauthnAppID(ctx),
+		AttestationPreference: protocol.ConveyancePreference(configuration.ConveyancePreference),
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			UserVerification: protocol.UserVerificationRequirement(configuration.UserVerification),
+		},
+		Timeout: int(timeout.Milliseconds()),
+	})
+}
@@ -28,10 +28,12 @@ func IdentityVerificationStart(args IdentityVerificationStartArgs) RequestHandle
 			return
 		}
 
+		expiresAt := time.Now().Add(5 * time.Minute)
+
 		// Create the claim with the action to sign it.
 		claims := &IdentityVerificationClaim{
 			jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(5 * time.Minute).Unix(),
+				ExpiresAt: expiresAt.Unix(),
 				Issuer:    jwtIssuer,
 			},
 			args.ActionClaim,
@@ -45,7 +47,7 @@ func IdentityVerificationStart(args IdentityVerificationStartArgs) RequestHandle
 			return
 		}
 
-		err = ctx.Providers.StorageProvider.SaveIdentityVerificationToken(ss)
+		err = ctx.Providers.StorageProvider.SaveIdentityVerificationToken(ctx, ss, expiresAt)
 		if err != nil {
 			ctx.Error(err, operationFailedMessage)
 			return
@@ -110,7 +112,7 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 			return
 		}
 
-		found, err := ctx.Providers.StorageProvider.FindIdentityVerificationToken(finishBody.Token)
+		found, err := ctx.Providers.StorageProvider.FindIdentityVerificationToken(ctx, finishBody.Token)
 
 		if err != nil {
 			ctx.Error(err, operationFailedMessage)
@@ -167,7 +169,7 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 		}
 
 		// TODO(c.michaud): find a way to garbage collect unused tokens.
-		err = ctx.Providers.StorageProvider.RemoveIdentityVerificationToken(finishBody.Token)
+		err = ctx.Providers.StorageProvider.RemoveIdentityVerificationToken(ctx, finishBody.Token)
 		if err != nil {
 			ctx.Error(err, operationFailedMessage)
 			return
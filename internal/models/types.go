@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"net"
+	"time"
+)
 
 // AuthenticationAttempt represent an authentication attempt.
 type AuthenticationAttempt struct {
@@ -8,6 +11,105 @@ type AuthenticationAttempt struct {
 	Username string
 	// Successful true if the attempt was successful.
 	Successful bool
+	// The factor the user tried to authenticate with, i.e. 1FA or one of the second factor methods.
+	Type string
+	// The IP address the attempt has been made from, if known.
+	RemoteIP net.IP
 	// The time of the attempt.
 	Time time.Time
 }
+
+// TOTPConfiguration represents a TOTP device registered by a user.
+type TOTPConfiguration struct {
+	// The identifier of the configuration in the storage backend.
+	ID int
+	// The user who registered the device.
+	Username string
+	// The name given to the device by the user.
+	Description string
+	// The base32 encoded secret shared with the device.
+	Secret string
+	// The hashing algorithm used to generate the codes, i.e. sha1, sha256 or sha512.
+	Algorithm string
+	// The number of digits of the codes.
+	Digits int
+	// The period in seconds of the codes. Zero for the devices registered before the period was recorded,
+	// in which case the configured period applies.
+	Period int
+	// The time the device has been registered.
+	CreatedAt time.Time
+	// The time the device has last been used to authenticate, if it has ever been used.
+	LastUsedAt *time.Time
+}
+
+// U2FDevice represents a U2F device registered by a user.
+type U2FDevice struct {
+	// The identifier of the device in the storage backend.
+	ID int
+	// The user who registered the device.
+	Username string
+	// The name given to the device by the user.
+	Description string
+	// The key handle returned by the device upon registration.
+	KeyHandle []byte
+	// The raw P-256 public key of the device.
+	PublicKey []byte
+	// The last signature counter returned by the device.
+	SignCount uint32
+	// The time the device has been registered.
+	CreatedAt time.Time
+	// The time the device has last been used to authenticate, if it has ever been used.
+	LastUsedAt *time.Time
+}
+
+// WebauthnDevice represents a WebAuthn credential registered by a user.
+type WebauthnDevice struct {
+	// The identifier of the device in the storage backend.
+	ID int
+	// The user who registered the device.
+	Username string
+	// The name given to the device by the user.
+	Description string
+	// The credential ID returned by the authenticator.
+	KID []byte
+	// The COSE encoded public key of the credential.
+	PublicKey []byte
+	// The attestation format used by the authenticator during registration.
+	AttestationType string
+	// The AAGUID identifying the model of the authenticator.
+	AAGUID []byte
+	// The last signature counter returned by the authenticator.
+	SignCount uint32
+	// The time the device has been registered.
+	CreatedAt time.Time
+	// The time the device has last been used to authenticate, if it has ever been used.
+	LastUsedAt *time.Time
+}
+
+// RecoveryCode represents a single-use code allowing a user to pass the second factor.
+type RecoveryCode struct {
+	// The identifier of the code in the storage backend.
+	ID int
+	// The user the code has been generated for.
+	Username string
+	// The hash of the code.
+	Hash string
+	// The time the code has been generated.
+	CreatedAt time.Time
+	// The time the code has been used, if it has ever been used.
+	UsedAt *time.Time
+}
+
+// User represents a user of the SQL authentication backend.
+type User struct {
+	// The name the user signs in with.
+	Username string
+	// The hash of the password of the user.
+	HashedPassword string
+	// The name of the user displayed in the portal.
+	DisplayName string
+	// The email address of the user, if any.
+	Email string
+	// The groups the user belongs to.
+	Groups []string
+}
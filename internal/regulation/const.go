@@ -4,3 +4,32 @@ import "fmt"
 
 // ErrUserIsBanned user is banned error message.
 var ErrUserIsBanned = fmt.Errorf("User is banned")
+
+// ErrIPIsBanned remote IP is banned error message.
+var ErrIPIsBanned = fmt.Errorf("Remote IP is banned")
+
+const (
+	// AuthType1FA the type of the attempts made with the first factor.
+	AuthType1FA = "1FA"
+	// AuthTypeTOTP the type of the attempts made with a one-time password.
+	AuthTypeTOTP = "TOTP"
+	// AuthTypeU2F the type of the attempts made with a U2F security key.
+	AuthTypeU2F = "U2F"
+	// AuthTypeWebauthn the type of the attempts made with a Webauthn security key.
+	AuthTypeWebauthn = "Webauthn"
+	// AuthTypeDuo the type of the attempts made with a Duo push notification.
+	AuthTypeDuo = "Duo"
+	// AuthTypeRecoveryCode the type of the attempts made with a recovery code.
+	AuthTypeRecoveryCode = "RecoveryCode"
+	// AuthTypeUnban the type of the marks recording a manual unban of a user or a remote IP.
+	AuthTypeUnban = "Unban"
+)
+
+const (
+	// BanTypeFirstFactor the type of the bans of a user from the first factor.
+	BanTypeFirstFactor = "first_factor"
+	// BanTypeSecondFactor the type of the bans of a user from the second factor.
+	BanTypeSecondFactor = "second_factor"
+	// BanTypeIP the type of the bans of a network.
+	BanTypeIP = "ip"
+)
@@ -1,7 +1,9 @@
 package regulation
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
@@ -16,85 +18,346 @@ func NewRegulator(configuration *schema.RegulationConfiguration, provider storag
 	regulator.clock = clock
 
 	if configuration != nil {
-		findTime, err := utils.ParseDurationString(configuration.FindTime)
-		if err != nil {
-			panic(err)
-		}
+		regulator.firstFactor = newThresholds(configuration.MaxRetries, configuration.FindTime, configuration.BanTime)
 
-		banTime, err := utils.ParseDurationString(configuration.BanTime)
-		if err != nil {
-			panic(err)
+		if configuration.SecondFactor != nil {
+			regulator.secondFactor = newThresholds(configuration.SecondFactor.MaxRetries,
+				configuration.SecondFactor.FindTime, configuration.SecondFactor.BanTime)
 		}
 
-		if findTime > banTime {
-			panic(fmt.Errorf("find_time cannot be greater than ban_time"))
+		if configuration.IP != nil {
+			regulator.ip = ipThresholds{
+				thresholds: newThresholds(configuration.IP.MaxRetries, configuration.IP.FindTime, configuration.IP.BanTime),
+				ipv4Mask:   net.CIDRMask(configuration.IP.IPv4PrefixLength, 8*net.IPv4len),
+				ipv6Mask:   net.CIDRMask(configuration.IP.IPv6PrefixLength, 8*net.IPv6len),
+			}
 		}
 
-		// Set regulator enabled only if MaxRetries is not 0.
-		regulator.enabled = configuration.MaxRetries > 0
-		regulator.maxRetries = configuration.MaxRetries
-		regulator.findTime = findTime
-		regulator.banTime = banTime
+		if configuration.Escalation != nil {
+			escalation := newEscalation(configuration.Escalation)
+
+			regulator.firstFactor.escalation = escalation
+			regulator.secondFactor.escalation = escalation
+			regulator.ip.escalation = escalation
+		}
 	}
 
 	return regulator
 }
 
-// Mark mark an authentication attempt.
+func newThresholds(maxRetries int, findTimeString, banTimeString string) thresholds {
+	findTime, err := utils.ParseDurationString(findTimeString)
+	if err != nil {
+		panic(err)
+	}
+
+	banTime, err := utils.ParseDurationString(banTimeString)
+	if err != nil {
+		panic(err)
+	}
+
+	if findTime > banTime {
+		panic(fmt.Errorf("find_time cannot be greater than ban_time"))
+	}
+
+	// Set regulation enabled only if MaxRetries is not 0.
+	return thresholds{
+		enabled:    maxRetries > 0,
+		maxRetries: maxRetries,
+		findTime:   findTime,
+		banTime:    banTime,
+	}
+}
+
+func newEscalation(configuration *schema.RegulationEscalationConfiguration) escalation {
+	window, err := utils.ParseDurationString(configuration.Window)
+	if err != nil {
+		panic(err)
+	}
+
+	maxBanTime, err := utils.ParseDurationString(configuration.MaxBanTime)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set escalation enabled only if the factor actually increases the ban time.
+	return escalation{
+		enabled:    configuration.Factor > 1,
+		factor:     configuration.Factor,
+		window:     window,
+		maxBanTime: maxBanTime,
+	}
+}
+
+// Mark mark an authentication attempt made with the given type of factor from the given remote IP.
 // We split Mark and Regulate in order to avoid timing attacks.
-func (r *Regulator) Mark(username string, successful bool) error {
-	return r.storageProvider.AppendAuthenticationLog(models.AuthenticationAttempt{
+func (r *Regulator) Mark(ctx context.Context, username string, remoteIP net.IP, authType string, successful bool) error {
+	return r.storageProvider.AppendAuthenticationLog(ctx, models.AuthenticationAttempt{
 		Username:   username,
 		Successful: successful,
+		Type:       authType,
+		RemoteIP:   remoteIP,
 		Time:       r.clock.Now(),
 	})
 }
 
-// Regulate regulate the authentication attempts for a given user.
-// This method returns ErrUserIsBanned if the user is banned along with the time until when
-// the user is banned.
-func (r *Regulator) Regulate(username string) (time.Time, error) {
+// Unban record a manual unban of the user. The failed attempts made by the user before the unban
+// are not taken into account anymore.
+func (r *Regulator) Unban(ctx context.Context, username string) error {
+	return r.Mark(ctx, username, nil, AuthTypeUnban, true)
+}
+
+// UnbanIP record a manual unban of the remote IP. The failed attempts made from the network of the
+// remote IP before the unban are not taken into account anymore.
+func (r *Regulator) UnbanIP(ctx context.Context, remoteIP net.IP) error {
+	return r.Mark(ctx, "", remoteIP, AuthTypeUnban, true)
+}
+
+// Regulate regulate the first factor authentication attempts for a given user and remote IP.
+// This method returns ErrUserIsBanned if the user is banned or ErrIPIsBanned if the network of the
+// remote IP is banned along with the time until when the ban applies.
+func (r *Regulator) Regulate(ctx context.Context, username string, remoteIP net.IP) (time.Time, error) {
+	bannedUntil, err := r.regulate(ctx, username, r.firstFactor, isFirstFactorAttempt)
+	if err != nil {
+		return bannedUntil, err
+	}
+
+	return r.regulateIP(ctx, remoteIP)
+}
+
+// RegulateSecondFactor regulate the second factor authentication attempts for a given user and remote IP.
+// This method returns ErrUserIsBanned if the user is banned or ErrIPIsBanned if the network of the
+// remote IP is banned along with the time until when the ban applies.
+func (r *Regulator) RegulateSecondFactor(ctx context.Context, username string, remoteIP net.IP) (time.Time, error) {
+	bannedUntil, err := r.regulate(ctx, username, r.secondFactor, isSecondFactorAttempt)
+	if err != nil {
+		return bannedUntil, err
+	}
+
+	return r.regulateIP(ctx, remoteIP)
+}
+
+func (r *Regulator) regulate(ctx context.Context, username string, thresholds thresholds, isRegulated func(attempt models.AuthenticationAttempt) bool) (time.Time, error) {
 	// If there is regulation configuration, no regulation applies.
-	if !r.enabled {
+	if !thresholds.enabled {
 		return time.Time{}, nil
 	}
 
 	now := r.clock.Now()
 
-	// TODO(c.michaud): make sure FindTime < BanTime.
-	attempts, err := r.storageProvider.LoadLatestAuthenticationLogs(username, now.Add(-r.banTime))
+	attempts, err := r.storageProvider.LoadLatestAuthenticationLogs(ctx, username, now.Add(-thresholds.lookBack()))
 
+	// The attempt is rejected when the previous attempts cannot be loaded, otherwise slowing the storage down would
+	// be enough to disable the regulation.
 	if err != nil {
-		return time.Time{}, nil
+		return time.Time{}, fmt.Errorf("Unable to load the authentication logs of user %s: %s", username, err)
 	}
 
-	latestFailedAttempts := make([]models.AuthenticationAttempt, 0, r.maxRetries)
+	regulatedAttempts := make([]models.AuthenticationAttempt, 0, len(attempts))
 
 	for _, attempt := range attempts {
-		if attempt.Successful || len(latestFailedAttempts) >= r.maxRetries {
-			// We stop appending failed attempts once we find the first successful attempts or we reach
-			// the configured number of retries, meaning the user is already banned.
+		// The attempts made before a manual unban are not taken into account.
+		if attempt.Type == AuthTypeUnban {
 			break
-		} else {
-			latestFailedAttempts = append(latestFailedAttempts, attempt)
 		}
+
+		if isRegulated(attempt) {
+			regulatedAttempts = append(regulatedAttempts, attempt)
+		}
+	}
+
+	if bannedUntil := thresholds.bannedUntil(regulatedAttempts); bannedUntil.After(now) {
+		return bannedUntil, ErrUserIsBanned
 	}
 
-	// If the number of failed attempts within the ban time is less than the max number of retries
-	// then the user is not banned.
-	if len(latestFailedAttempts) < r.maxRetries {
+	return time.Time{}, nil
+}
+
+// regulateIP regulate the failed attempts made from the network of the remote IP, whatever the
+// username and the factor. Successful attempts do not reset the count since they may have been
+// made by any user of the network, including the attacker with their own account.
+func (r *Regulator) regulateIP(ctx context.Context, remoteIP net.IP) (time.Time, error) {
+	if !r.ip.enabled || remoteIP == nil {
 		return time.Time{}, nil
 	}
 
-	// Now we compute the time between the latest attempt and the MaxRetry-th one. If it's
-	// within the FindTime then it means that the user has been banned.
-	durationBetweenLatestAttempts := latestFailedAttempts[0].Time.Sub(
-		latestFailedAttempts[r.maxRetries-1].Time)
+	return r.regulateNetwork(ctx, r.network(remoteIP))
+}
 
-	if durationBetweenLatestAttempts < r.findTime {
-		bannedUntil := latestFailedAttempts[0].Time.Add(r.banTime)
-		return bannedUntil, ErrUserIsBanned
+func (r *Regulator) regulateNetwork(ctx context.Context, network *net.IPNet) (time.Time, error) {
+	now := r.clock.Now()
+
+	attempts, err := r.storageProvider.LoadLatestAuthenticationLogsByNetwork(ctx, network, now.Add(-r.ip.lookBack()))
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Unable to load the authentication logs of network %s: %s", network, err)
+	}
+
+	failedAttempts := make([]models.AuthenticationAttempt, 0, len(attempts))
+
+	for _, attempt := range attempts {
+		if attempt.Type == AuthTypeUnban {
+			break
+		}
+
+		if !attempt.Successful {
+			failedAttempts = append(failedAttempts, attempt)
+		}
+	}
+
+	if bannedUntil := r.ip.bannedUntil(failedAttempts); bannedUntil.After(now) {
+		return bannedUntil, ErrIPIsBanned
 	}
 
 	return time.Time{}, nil
 }
+
+// network returns the network the remote IP belongs to according to the configured prefix lengths.
+func (r *Regulator) network(remoteIP net.IP) *net.IPNet {
+	mask := r.ip.ipv6Mask
+	if remoteIP.To4() != nil {
+		mask = r.ip.ipv4Mask
+	}
+
+	return &net.IPNet{IP: remoteIP.Mask(mask), Mask: mask}
+}
+
+// ListBans list the bans currently applying to the users and the networks which made failed
+// attempts recently.
+func (r *Regulator) ListBans(ctx context.Context) ([]Ban, error) {
+	var lookBack time.Duration
+
+	for _, t := range []thresholds{r.firstFactor, r.secondFactor, r.ip.thresholds} {
+		if t.enabled && t.lookBack() > lookBack {
+			lookBack = t.lookBack()
+		}
+	}
+
+	if lookBack == 0 {
+		return nil, nil
+	}
+
+	attempts, err := r.storageProvider.LoadAuthenticationLogs(ctx, r.clock.Now().Add(-lookBack))
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]Ban, 0)
+	checkedUsers := make(map[string]bool)
+	checkedNetworks := make(map[string]bool)
+
+	for _, attempt := range attempts {
+		// Only failed attempts can lead to a ban.
+		if attempt.Successful {
+			continue
+		}
+
+		if attempt.Username != "" && !checkedUsers[attempt.Username] {
+			checkedUsers[attempt.Username] = true
+
+			if bannedUntil, err := r.regulate(ctx, attempt.Username, r.firstFactor, isFirstFactorAttempt); err == ErrUserIsBanned {
+				bans = append(bans, Ban{Username: attempt.Username, Type: BanTypeFirstFactor, Until: bannedUntil})
+			}
+
+			if bannedUntil, err := r.regulate(ctx, attempt.Username, r.secondFactor, isSecondFactorAttempt); err == ErrUserIsBanned {
+				bans = append(bans, Ban{Username: attempt.Username, Type: BanTypeSecondFactor, Until: bannedUntil})
+			}
+		}
+
+		if r.ip.enabled && attempt.RemoteIP != nil {
+			network := r.network(attempt.RemoteIP)
+			if checkedNetworks[network.String()] {
+				continue
+			}
+
+			checkedNetworks[network.String()] = true
+
+			if bannedUntil, err := r.regulateNetwork(ctx, network); err == ErrIPIsBanned {
+				bans = append(bans, Ban{Network: network, Type: BanTypeIP, Until: bannedUntil})
+			}
+		}
+	}
+
+	return bans, nil
+}
+
+func isFirstFactorAttempt(attempt models.AuthenticationAttempt) bool {
+	return attempt.Type == AuthType1FA
+}
+
+func isSecondFactorAttempt(attempt models.AuthenticationAttempt) bool {
+	return attempt.Type != AuthType1FA
+}
+
+// bannedUntil replays the attempts, ordered from the most recent, in order to find the bans they led to
+// and returns the time until when the latest ban applies. A successful attempt resets the count of failed
+// attempts but not the count of bans used to escalate the ban time.
+func (t thresholds) bannedUntil(attempts []models.AuthenticationAttempt) time.Time {
+	var (
+		bannedUntil    time.Time
+		bans           []time.Time
+		failedAttempts []time.Time
+	)
+
+	for i := len(attempts) - 1; i >= 0; i-- {
+		attempt := attempts[i]
+
+		if attempt.Successful {
+			failedAttempts = failedAttempts[:0]
+			continue
+		}
+
+		failedAttempts = append(failedAttempts, attempt.Time)
+
+		if len(failedAttempts) < t.maxRetries {
+			continue
+		}
+
+		// The user is banned if the max number of retries has been done within the find time.
+		if attempt.Time.Sub(failedAttempts[len(failedAttempts)-t.maxRetries]) < t.findTime {
+			bannedUntil = attempt.Time.Add(t.escalatedBanTime(bans, attempt.Time))
+			bans = append(bans, attempt.Time)
+			failedAttempts = failedAttempts[:0]
+		}
+	}
+
+	return bannedUntil
+}
+
+// escalatedBanTime computes the duration of a ban given the previous bans. Each previous ban within the
+// escalation window multiplies the ban time by the escalation factor, up to the max ban time.
+func (t thresholds) escalatedBanTime(previousBans []time.Time, at time.Time) time.Duration {
+	banTime := t.banTime
+
+	if !t.escalation.enabled {
+		return banTime
+	}
+
+	for _, ban := range previousBans {
+		if at.Sub(ban) > t.escalation.window {
+			continue
+		}
+
+		banTime *= time.Duration(t.escalation.factor)
+
+		if banTime >= t.escalation.maxBanTime {
+			if t.escalation.maxBanTime < t.banTime {
+				return t.banTime
+			}
+
+			return t.escalation.maxBanTime
+		}
+	}
+
+	return banTime
+}
+
+// lookBack returns the duration of the history of attempts needed to compute the current ban.
+func (t thresholds) lookBack() time.Duration {
+	if !t.escalation.enabled {
+		return t.banTime
+	}
+
+	// The history must include the bans within the escalation window before the current ban.
+	return t.escalation.window + t.escalation.maxBanTime
+}
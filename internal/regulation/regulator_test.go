@@ -1,6 +1,9 @@
 package regulation_test
 
 import (
+	"context"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -45,20 +48,57 @@ func (s *RegulatorSuite) TestShouldNotThrowWhenUserIsLegitimate() {
 		{
 			Username:   "john",
 			Successful: true,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-4 * time.Minute),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.NoError(s.T(), err)
 }
 
+func (s *RegulatorSuite) TestShouldFailClosedWhenAuthenticationLogsCannotBeLoaded() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(nil, errors.New("context deadline exceeded"))
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.EqualError(s.T(), err, "Unable to load the authentication logs of user john: context deadline exceeded")
+}
+
+func (s *RegulatorSuite) TestShouldFailClosedWhenNetworkAuthenticationLogsCannotBeLoaded() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(nil, nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogsByNetwork(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("context deadline exceeded"))
+
+	s.configuration.IP = &schema.RegulationIPConfiguration{
+		RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{
+			MaxRetries: 2,
+			FindTime:   "30",
+			BanTime:    "60",
+		},
+		IPv4PrefixLength: 24,
+		IPv6PrefixLength: 64,
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", net.ParseIP("192.168.1.10"))
+	assert.EqualError(s.T(), err, "Unable to load the authentication logs of network 192.168.1.0/24: context deadline exceeded")
+}
+
 // This test checks the case in which a user failed to authenticate many times but always
 // with a certain amount of time larger than FindTime. Meaning the user should not be banned.
 func (s *RegulatorSuite) TestShouldNotThrowWhenFailedAuthenticationNotInFindTime() {
@@ -66,27 +106,30 @@ func (s *RegulatorSuite) TestShouldNotThrowWhenFailedAuthenticationNotInFindTime
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-1 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-90 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-180 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.NoError(s.T(), err)
 }
 
@@ -97,32 +140,36 @@ func (s *RegulatorSuite) TestShouldBanUserIfLatestAttemptsAreWithinFinTime() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-1 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-4 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-6 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-180 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
 }
 
@@ -135,27 +182,30 @@ func (s *RegulatorSuite) TestShouldCheckUserIsStillBanned() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-31 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-34 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-36 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
 }
 
@@ -164,22 +214,24 @@ func (s *RegulatorSuite) TestShouldCheckUserIsNotYetBanned() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-34 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-36 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.NoError(s.T(), err)
 }
 
@@ -188,6 +240,7 @@ func (s *RegulatorSuite) TestShouldCheckUserWasAboutToBeBanned() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-14 * time.Second),
 		},
 		// more than 30 seconds elapsed between this auth and the preceding one.
@@ -196,22 +249,24 @@ func (s *RegulatorSuite) TestShouldCheckUserWasAboutToBeBanned() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-94 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-96 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.NoError(s.T(), err)
 }
 
@@ -220,11 +275,13 @@ func (s *RegulatorSuite) TestShouldCheckRegulationHasBeenResetOnSuccessfulAttemp
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-90 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: true,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-93 * time.Second),
 		},
 		// The user was almost banned but he did a successful attempt. Therefore, even if the next
@@ -232,23 +289,351 @@ func (s *RegulatorSuite) TestShouldCheckRegulationHasBeenResetOnSuccessfulAttemp
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-94 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-96 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(attemptsInDB, nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.NoError(s.T(), err)
+}
+
+func (s *RegulatorSuite) TestShouldBanUserOnSecondFactorWithSeparateThresholds() {
+	attemptsInDB := []models.AuthenticationAttempt{
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthTypeTOTP,
+			Time:       s.clock.Now().Add(-10 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			Time:       s.clock.Now().Add(-15 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthTypeU2F,
+			Time:       s.clock.Now().Add(-20 * time.Second),
+		},
+	}
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(attemptsInDB, nil).
+		Times(2)
+
+	s.configuration.SecondFactor = &schema.RegulationThresholdsConfiguration{
+		MaxRetries: 2,
+		FindTime:   "30",
+		BanTime:    "60",
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	// The attempts made with a second factor do not count towards the ban of the first factor.
+	_, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.NoError(s.T(), err)
+
+	bannedUntil, err := regulator.RegulateSecondFactor(context.Background(), "john", nil)
+	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
+	assert.Equal(s.T(), s.clock.Now().Add(50*time.Second), bannedUntil)
+}
+
+func (s *RegulatorSuite) TestShouldNotRegulateSecondFactorWhenNotConfigured() {
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.RegulateSecondFactor(context.Background(), "john", nil)
+	assert.NoError(s.T(), err)
+}
+
+func (s *RegulatorSuite) TestShouldBanRemoteIPNetworkWhateverTheUsername() {
+	attemptsInDB := []models.AuthenticationAttempt{
+		{
+			Username:   "harry",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   net.ParseIP("192.168.1.12"),
+			Time:       s.clock.Now().Add(-5 * time.Second),
+		},
+		// Successful attempts made from the network do not reset the count.
+		{
+			Username:   "bob",
+			Successful: true,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   net.ParseIP("192.168.1.11"),
+			Time:       s.clock.Now().Add(-8 * time.Second),
+		},
+		{
+			Username:   "bob",
+			Successful: false,
+			Type:       regulation.AuthTypeTOTP,
+			RemoteIP:   net.ParseIP("192.168.1.11"),
+			Time:       s.clock.Now().Add(-10 * time.Second),
+		},
+	}
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(nil, nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogsByNetwork(gomock.Any(),
+			gomock.Eq(&net.IPNet{IP: net.ParseIP("192.168.1.0").To4(), Mask: net.CIDRMask(24, 32)}),
+			gomock.Eq(s.clock.Now().Add(-60*time.Second))).
+		Return(attemptsInDB, nil)
+
+	s.configuration.IP = &schema.RegulationIPConfiguration{
+		RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{
+			MaxRetries: 2,
+			FindTime:   "30",
+			BanTime:    "60",
+		},
+		IPv4PrefixLength: 24,
+		IPv6PrefixLength: 64,
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	bannedUntil, err := regulator.Regulate(context.Background(), "john", net.ParseIP("192.168.1.10"))
+	assert.Equal(s.T(), regulation.ErrIPIsBanned, err)
+	assert.Equal(s.T(), s.clock.Now().Add(55*time.Second), bannedUntil)
+}
+
+func (s *RegulatorSuite) TestShouldRegulateIPv6Network() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(nil, nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogsByNetwork(gomock.Any(),
+			gomock.Eq(&net.IPNet{IP: net.ParseIP("2001:db8:1:2::"), Mask: net.CIDRMask(64, 128)}), gomock.Any()).
+		Return(nil, nil)
+
+	s.configuration.IP = &schema.RegulationIPConfiguration{
+		RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{
+			MaxRetries: 2,
+			FindTime:   "30",
+			BanTime:    "60",
+		},
+		IPv4PrefixLength: 32,
+		IPv6PrefixLength: 64,
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", net.ParseIP("2001:db8:1:2:3:4:5:6"))
+	assert.NoError(s.T(), err)
+}
+
+func (s *RegulatorSuite) TestShouldNotRegulateUnknownRemoteIP() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(nil, nil)
+
+	s.configuration.IP = &schema.RegulationIPConfiguration{
+		RegulationThresholdsConfiguration: schema.RegulationThresholdsConfiguration{
+			MaxRetries: 2,
+			FindTime:   "30",
+			BanTime:    "60",
+		},
+		IPv4PrefixLength: 32,
+		IPv6PrefixLength: 64,
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.NoError(s.T(), err)
+}
+
+func (s *RegulatorSuite) TestShouldIgnoreFailedAttemptsBeforeManualUnban() {
+	attemptsInDB := []models.AuthenticationAttempt{
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			Time:       s.clock.Now().Add(-1 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: true,
+			Type:       regulation.AuthTypeUnban,
+			Time:       s.clock.Now().Add(-2 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			Time:       s.clock.Now().Add(-4 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			Time:       s.clock.Now().Add(-6 * time.Second),
+		},
+	}
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(attemptsInDB, nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.NoError(s.T(), err)
+}
+
+func (s *RegulatorSuite) TestShouldRecordManualUnbans() {
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Username:   "john",
+			Successful: true,
+			Type:       regulation.AuthTypeUnban,
+			Time:       s.clock.Now(),
+		})).
+		Return(nil)
+
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any(), gomock.Eq(models.AuthenticationAttempt{
+			Successful: true,
+			Type:       regulation.AuthTypeUnban,
+			RemoteIP:   net.ParseIP("192.168.1.10"),
+			Time:       s.clock.Now(),
+		})).
+		Return(nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	assert.NoError(s.T(), regulator.Unban(context.Background(), "john"))
+	assert.NoError(s.T(), regulator.UnbanIP(context.Background(), net.ParseIP("192.168.1.10")))
+}
+
+func (s *RegulatorSuite) TestShouldListBans() {
+	attemptsInDB := []models.AuthenticationAttempt{
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   net.ParseIP("192.168.1.10"),
+			Time:       s.clock.Now().Add(-1 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   net.ParseIP("192.168.1.10"),
+			Time:       s.clock.Now().Add(-4 * time.Second),
+		},
+		{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			RemoteIP:   net.ParseIP("192.168.1.10"),
+			Time:       s.clock.Now().Add(-6 * time.Second),
+		},
+	}
+
+	s.storageMock.EXPECT().
+		LoadAuthenticationLogs(gomock.Any(), gomock.Eq(s.clock.Now().Add(-180*time.Second))).
+		Return(attemptsInDB, nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
 
-	_, err := regulator.Regulate("john")
+	bans, err := regulator.ListBans(context.Background())
 	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []regulation.Ban{
+		{Username: "john", Type: regulation.BanTypeFirstFactor, Until: s.clock.Now().Add(179 * time.Second)},
+	}, bans)
+}
+
+func (s *RegulatorSuite) repeatOffenderAttempts() []models.AuthenticationAttempt {
+	attemptsInDB := []models.AuthenticationAttempt{}
+
+	// The user has been banned twice before being banned for a third time now.
+	for _, seconds := range []int{8, 9, 10, 698, 699, 700, 998, 999, 1000} {
+		attemptsInDB = append(attemptsInDB, models.AuthenticationAttempt{
+			Username:   "john",
+			Successful: false,
+			Type:       regulation.AuthType1FA,
+			Time:       s.clock.Now().Add(-time.Duration(seconds) * time.Second),
+		})
+	}
+
+	return attemptsInDB
+}
+
+func (s *RegulatorSuite) TestShouldEscalateBanTimeOfRepeatOffenders() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Eq(s.clock.Now().Add(-2*time.Hour))).
+		Return(s.repeatOffenderAttempts(), nil)
+
+	s.configuration.Escalation = &schema.RegulationEscalationConfiguration{
+		Factor:     2,
+		Window:     "1h",
+		MaxBanTime: "1h",
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	bannedUntil, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
+	assert.Equal(s.T(), s.clock.Now().Add(712*time.Second), bannedUntil)
+}
+
+func (s *RegulatorSuite) TestShouldCapEscalatedBanTime() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(s.repeatOffenderAttempts(), nil)
+
+	s.configuration.Escalation = &schema.RegulationEscalationConfiguration{
+		Factor:     2,
+		Window:     "1h",
+		MaxBanTime: "10m",
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	bannedUntil, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
+	assert.Equal(s.T(), s.clock.Now().Add(592*time.Second), bannedUntil)
+}
+
+func (s *RegulatorSuite) TestShouldNotEscalateBanTimeOutsideOfWindow() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
+		Return(s.repeatOffenderAttempts(), nil)
+
+	s.configuration.Escalation = &schema.RegulationEscalationConfiguration{
+		Factor:     2,
+		Window:     "5m",
+		MaxBanTime: "1h",
+	}
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	bannedUntil, err := regulator.Regulate(context.Background(), "john", nil)
+	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
+	assert.Equal(s.T(), s.clock.Now().Add(172*time.Second), bannedUntil)
 }
 
 func TestRunRegulatorSuite(t *testing.T) {
@@ -262,22 +647,25 @@ func (s *RegulatorSuite) TestShouldHaveRegulatorDisabled() {
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-31 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-34 * time.Second),
 		},
 		{
 			Username:   "john",
 			Successful: false,
+			Type:       regulation.AuthType1FA,
 			Time:       s.clock.Now().Add(-36 * time.Second),
 		},
 	}
 
 	s.storageMock.EXPECT().
-		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		LoadLatestAuthenticationLogs(gomock.Any(), gomock.Eq("john"), gomock.Any()).
 		Return(attemptsInDB, nil)
 
 	// Check Disabled Functionality
@@ -288,7 +676,7 @@ func (s *RegulatorSuite) TestShouldHaveRegulatorDisabled() {
 	}
 
 	regulator := regulation.NewRegulator(&configuration, s.storageMock, &s.clock)
-	_, err := regulator.Regulate("john")
+	_, err := regulator.Regulate(context.Background(), "john", nil)
 	assert.NoError(s.T(), err)
 
 	// Check Enabled Functionality
@@ -299,6 +687,6 @@ func (s *RegulatorSuite) TestShouldHaveRegulatorDisabled() {
 	}
 
 	regulator = regulation.NewRegulator(&configuration, s.storageMock, &s.clock)
-	_, err = regulator.Regulate("john")
+	_, err = regulator.Regulate(context.Background(), "john", nil)
 	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
 }
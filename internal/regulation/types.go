@@ -1,6 +1,7 @@
 package regulation
 
 import (
+	"net"
 	"time"
 
 	"github.com/authelia/authelia/internal/storage"
@@ -9,6 +10,32 @@ import (
 
 // Regulator an authentication regulator preventing attackers to brute force the service.
 type Regulator struct {
+	// The thresholds applying to the attempts made with the first factor.
+	firstFactor thresholds
+	// The thresholds applying to the attempts made with a second factor.
+	secondFactor thresholds
+	// The thresholds applying to the failed attempts made from a same network, whatever the username.
+	ip ipThresholds
+
+	storageProvider storage.Provider
+
+	clock utils.Clock
+}
+
+// Ban a ban currently applying to a user or a network.
+type Ban struct {
+	// The banned user, empty when a network is banned.
+	Username string
+	// The banned network, nil when a user is banned.
+	Network *net.IPNet
+	// The type of the ban, i.e. BanTypeFirstFactor, BanTypeSecondFactor or BanTypeIP.
+	Type string
+	// The time until when the ban applies.
+	Until time.Time
+}
+
+// thresholds the thresholds beyond which a user is banned.
+type thresholds struct {
 	// Is the regulation enabled.
 	enabled bool
 	// The number of failed authentication attempt before banning the user
@@ -17,8 +44,27 @@ type Regulator struct {
 	findTime time.Duration
 	// If a user has been banned, this duration is the timelapse during which the user is banned.
 	banTime time.Duration
+	// The escalation of the ban time of repeat offenders.
+	escalation escalation
+}
 
-	storageProvider storage.Provider
+// escalation the policy escalating the ban time of the users banned several times.
+type escalation struct {
+	// Is the escalation enabled.
+	enabled bool
+	// The factor the ban time is multiplied by for each previous ban within the window.
+	factor int
+	// The duration before a ban during which the previous bans are counted.
+	window time.Duration
+	// The ceiling of the escalated ban time.
+	maxBanTime time.Duration
+}
 
-	clock utils.Clock
+// ipThresholds the thresholds beyond which a network is banned.
+type ipThresholds struct {
+	thresholds
+
+	// The masks giving the network an IPv4 or IPv6 address belongs to.
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
 }
@@ -93,6 +93,29 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	r.POST("/api/secondfactor/u2f/sign", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.SecondFactorU2FSignPost(&handlers.U2FVerifierImpl{}))))
 
+	// Webauthn related endpoints.
+	r.POST("/api/secondfactor/webauthn/identity/start", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnIdentityStart)))
+	r.POST("/api/secondfactor/webauthn/identity/finish", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnIdentityFinish)))
+
+	r.POST("/api/secondfactor/webauthn/attestation", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAttestationPost)))
+
+	r.GET("/api/secondfactor/webauthn/assertion", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAssertionGet)))
+	r.POST("/api/secondfactor/webauthn/assertion", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAssertionPost)))
+
+	// Recovery codes related endpoints.
+	r.POST("/api/secondfactor/recovery_codes/identity/start", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodesIdentityStart)))
+	r.POST("/api/secondfactor/recovery_codes/identity/finish", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodesIdentityFinish)))
+
+	r.POST("/api/secondfactor/recovery_code", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodePost)))
+
 	// Configure DUO api endpoint only if configuration exists.
 	if configuration.DuoAPI != nil {
 		var duoAPI duo.API
@@ -3,6 +3,7 @@ package session
 import (
 	"time"
 
+	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/redis"
 	"github.com/tstranex/u2f"
@@ -19,8 +20,10 @@ type ProviderConfig struct {
 
 // U2FRegistration is a serializable version of a U2F registration.
 type U2FRegistration struct {
+	ID        int
 	KeyHandle []byte
 	PublicKey []byte
+	SignCount uint32
 }
 
 // UserSession is the structure representing the session of a user.
@@ -38,9 +41,13 @@ type UserSession struct {
 	// The challenge generated in first step of U2F registration (after identity verification) or authentication.
 	// This is used reused in the second phase to check that the challenge has been completed.
 	U2FChallenge *u2f.Challenge
-	// The registration representing a U2F device in DB set after identity verification.
+	// The registrations representing the U2F devices of the user in DB set in first phase of a U2F authentication.
 	// This is used in second phase of a U2F authentication.
-	U2FRegistration *U2FRegistration
+	U2FRegistrations []U2FRegistration
+
+	// The session data generated at the beginning of a Webauthn registration or assertion ceremony.
+	// This is used in the second phase to check that the ceremony has been completed.
+	Webauthn *webauthn.SessionData
 
 	// This boolean is set to true after identity verification and checked
 	// while doing the query actually updating the password.
@@ -5,9 +5,36 @@ import "fmt"
 // Keep table names in lower case because some DB does not support upper case.
 const preferencesTableName = "user_preferences"
 const identityVerificationTokensTableName = "identity_verification_tokens"
-const totpSecretsTableName = "totp_secrets"
-const u2fDeviceHandlesTableName = "u2f_devices"
+const totpConfigurationsTableName = "totp_configurations"
+const legacyTOTPSecretsTableName = "totp_secrets"
+const u2fDeviceHandlesTableName = "u2f_device_handles"
+const legacyU2FDevicesTableName = "u2f_devices"
 const authenticationLogsTableName = "authentication_logs"
+const webauthnDevicesTableName = "webauthn_devices"
+const recoveryCodesTableName = "recovery_codes"
+const schemaMigrationsTableName = "schema_migrations"
+
+// migrationsLockID the key of the advisory lock taken while the schema is migrated, "authelia" encoded in hexadecimal.
+const migrationsLockID int64 = 0x61757468656c6961
+
+// migrationsLockTimeout the number of seconds an instance waits for another one to migrate the schema.
+const migrationsLockTimeout = 300
+const usersTableName = "users"
+const userGroupsTableName = "user_groups"
+
+// SQLCreateSchemaMigrationsTable common SQL query to create schema_migrations table holding the versions of the
+// migrations applied to the schema.
+var SQLCreateSchemaMigrationsTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	version INTEGER PRIMARY KEY,
+	applied_at INTEGER NOT NULL
+)`, schemaMigrationsTableName)
+
+// SQLGetSchemaVersion common SQL query to get the version of the schema, 0 when no migration has been applied.
+var SQLGetSchemaVersion = fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", schemaMigrationsTableName)
+
+// SQLDropTable common SQL query to drop a table given its name.
+const SQLDropTable = "DROP TABLE IF EXISTS %s"
 
 // SQLCreateUserPreferencesTable common SQL query to create user_preferences table.
 var SQLCreateUserPreferencesTable = fmt.Sprintf(`
@@ -21,24 +48,160 @@ var SQLCreateIdentityVerificationTokensTable = fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s (token VARCHAR(512))
 `, identityVerificationTokensTableName)
 
-// SQLCreateTOTPSecretsTable common SQL query to create totp_secrets table.
-var SQLCreateTOTPSecretsTable = fmt.Sprintf(`
+// SQLCreateLegacyTOTPSecretsTable common SQL query to create totp_secrets table which used to hold a single
+// secret per user. Its content is moved to totp_configurations table upon initialization.
+var SQLCreateLegacyTOTPSecretsTable = fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))
-`, totpSecretsTableName)
+`, legacyTOTPSecretsTableName)
 
-// SQLCreateU2FDeviceHandlesTable common SQL query to create u2f_device_handles table.
-var SQLCreateU2FDeviceHandlesTable = fmt.Sprintf(`
+// SQLCreateTOTPConfigurationsTable common SQL query to create totp_configurations table.
+var SQLCreateTOTPConfigurationsTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(100) NOT NULL,
+	description VARCHAR(30) NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	algorithm VARCHAR(6) NOT NULL,
+	digits INTEGER NOT NULL,
+	period INTEGER NOT NULL,
+	last_step BIGINT,
+	created_at INTEGER NOT NULL,
+	last_used_at INTEGER,
+	INDEX totp_usr_idx (username)
+)`, totpConfigurationsTableName)
+
+// SQLDeleteLegacyTOTPSecrets common SQL query to empty totp_secrets table once its content has been moved.
+var SQLDeleteLegacyTOTPSecrets = fmt.Sprintf("DELETE FROM %s", legacyTOTPSecretsTableName)
+
+// SQLCreateLegacyU2FDevicesTable common SQL query to create u2f_devices table which used to hold a single
+// device per user. Its content is moved to u2f_device_handles table upon initialization.
+var SQLCreateLegacyU2FDevicesTable = fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s (
 	username VARCHAR(100) PRIMARY KEY,
 	keyHandle TEXT,
 	publicKey TEXT
+)`, legacyU2FDevicesTableName)
+
+// SQLCreateU2FDeviceHandlesTable common SQL query to create u2f_device_handles table.
+var SQLCreateU2FDeviceHandlesTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(100) NOT NULL,
+	description VARCHAR(30) NOT NULL,
+	key_handle TEXT NOT NULL,
+	public_key TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	last_used_at INTEGER,
+	INDEX u2f_usr_idx (username)
 )`, u2fDeviceHandlesTableName)
 
+// SQLDeleteLegacyU2FDevices common SQL query to empty u2f_devices table once its content has been moved.
+var SQLDeleteLegacyU2FDevices = fmt.Sprintf("DELETE FROM %s", legacyU2FDevicesTableName)
+
 // SQLCreateAuthenticationLogsTable common SQL query to create authentication_logs table.
 var SQLCreateAuthenticationLogsTable = fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s (
 	username VARCHAR(100),
 	successful BOOL,
+	auth_type VARCHAR(32) NOT NULL DEFAULT '1FA',
+	remote_ip VARCHAR(32),
 	time INTEGER,
-	INDEX usr_time_idx (username, time)
+	INDEX usr_time_idx (username, time),
+	INDEX ip_time_idx (remote_ip, time)
 )`, authenticationLogsTableName)
+
+// SQLAddAuthenticationLogsAuthTypeColumn common SQL query to add the auth_type column to the authentication_logs
+// table created by a previous version. The attempts logged before have all been made with the first factor.
+var SQLAddAuthenticationLogsAuthTypeColumn = fmt.Sprintf("ALTER TABLE %s ADD COLUMN auth_type VARCHAR(32) NOT NULL DEFAULT '1FA'", authenticationLogsTableName)
+
+// SQLAddU2FDeviceHandlesSignCountColumn common SQL query to add the sign_count column to the u2f_device_handles
+// table created by a previous version. The counters of the devices used before are unknown, hence they start at 0.
+var SQLAddU2FDeviceHandlesSignCountColumn = fmt.Sprintf("ALTER TABLE %s ADD COLUMN sign_count BIGINT NOT NULL DEFAULT 0", u2fDeviceHandlesTableName)
+
+// SQLAddIdentityVerificationTokensExpiresAtColumn common SQL query to add the expires_at column to the
+// identity_verification_tokens table created by a previous version.
+var SQLAddIdentityVerificationTokensExpiresAtColumn = fmt.Sprintf("ALTER TABLE %s ADD COLUMN expires_at INTEGER", identityVerificationTokensTableName)
+
+// SQLAddAuthenticationLogsRemoteIPColumn common SQL query to add the remote_ip column to the authentication_logs
+// table created by a previous version. The remote IP is stored as the hexadecimal encoding of its 16 bytes
+// representation so that the attempts made from a network can be looked up with a range.
+var SQLAddAuthenticationLogsRemoteIPColumn = fmt.Sprintf("ALTER TABLE %s ADD COLUMN remote_ip VARCHAR(32)", authenticationLogsTableName)
+
+// SQLCreateWebauthnDevicesTable common SQL query to create webauthn_devices table.
+var SQLCreateWebauthnDevicesTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(100) NOT NULL,
+	description VARCHAR(30) NOT NULL,
+	kid VARCHAR(512) NOT NULL,
+	public_key TEXT NOT NULL,
+	attestation_type VARCHAR(32) NOT NULL,
+	aaguid VARCHAR(64) NOT NULL,
+	sign_count BIGINT NOT NULL,
+	created_at INTEGER NOT NULL,
+	last_used_at INTEGER,
+	INDEX webauthn_usr_idx (username)
+)`, webauthnDevicesTableName)
+
+// SQLCreateRecoveryCodesTable common SQL query to create recovery_codes table.
+var SQLCreateRecoveryCodesTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(100) NOT NULL,
+	code_hash VARCHAR(255) NOT NULL,
+	created_at INTEGER NOT NULL,
+	used_at INTEGER,
+	INDEX recovery_usr_idx (username)
+)`, recoveryCodesTableName)
+
+// SQLCreateUsersTable common SQL query to create users table holding the users of the SQL authentication backend.
+var SQLCreateUsersTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	username VARCHAR(100) PRIMARY KEY,
+	password VARCHAR(255) NOT NULL,
+	display_name VARCHAR(100) NOT NULL,
+	email VARCHAR(255)
+)`, usersTableName)
+
+// SQLCreateUserGroupsTable common SQL query to create user_groups table holding the groups the users of the SQL
+// authentication backend belong to.
+var SQLCreateUserGroupsTable = fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	username VARCHAR(100) NOT NULL,
+	group_name VARCHAR(100) NOT NULL,
+	PRIMARY KEY (username, group_name)
+)`, userGroupsTableName)
+
+// SQLCountRows common SQL query to count the rows of a table given its name.
+const SQLCountRows = "SELECT COUNT(*) FROM %s"
+
+// SQLExportPreferences common SQL query to export user_preferences table.
+var SQLExportPreferences = fmt.Sprintf("SELECT username, second_factor_method FROM %s ORDER BY username", preferencesTableName)
+
+// SQLExportIdentityVerificationTokens common SQL query to export identity_verification_tokens table.
+var SQLExportIdentityVerificationTokens = fmt.Sprintf("SELECT token, expires_at FROM %s", identityVerificationTokensTableName)
+
+// SQLExportTOTPConfigurations common SQL query to export totp_configurations table.
+var SQLExportTOTPConfigurations = fmt.Sprintf("SELECT username, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s ORDER BY id", totpConfigurationsTableName)
+
+// SQLExportU2FDevices common SQL query to export u2f_device_handles table.
+var SQLExportU2FDevices = fmt.Sprintf("SELECT username, description, key_handle, public_key, sign_count, created_at, last_used_at FROM %s ORDER BY id", u2fDeviceHandlesTableName)
+
+// SQLExportWebauthnDevices common SQL query to export webauthn_devices table.
+var SQLExportWebauthnDevices = fmt.Sprintf("SELECT username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at FROM %s ORDER BY id", webauthnDevicesTableName)
+
+// SQLExportRecoveryCodes common SQL query to export recovery_codes table.
+var SQLExportRecoveryCodes = fmt.Sprintf("SELECT username, code_hash, created_at, used_at FROM %s ORDER BY id", recoveryCodesTableName)
+
+// SQLExportAuthenticationLogs common SQL query to export authentication_logs table.
+var SQLExportAuthenticationLogs = fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s ORDER BY time", authenticationLogsTableName)
+
+// SQLExportUsers common SQL query to export users table.
+var SQLExportUsers = fmt.Sprintf("SELECT username, password, display_name, email FROM %s ORDER BY username", usersTableName)
+
+// SQLExportUserGroups common SQL query to export user_groups table.
+var SQLExportUserGroups = fmt.Sprintf("SELECT username, group_name FROM %s ORDER BY username, group_name", userGroupsTableName)
+
+// SQLGetColumnValues common SQL query to select the values of a column of a table along with the IDs of the rows
+// given the name of the column and the name of the table.
+const SQLGetColumnValues = "SELECT id, %s FROM %s"
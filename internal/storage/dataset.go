@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DatasetVersion the version of the format of the datasets.
+const DatasetVersion = 4
+
+// Dataset holds all the data persisted in a storage backend in a portable format. The binary values are
+// kept base64 encoded as they are stored while the secrets are decrypted so that the dataset can be imported
+// into a storage backend configured with another encryption key.
+type Dataset struct {
+	Version                    int                               `json:"version" yaml:"version"`
+	Preferences                []PreferencesRecord               `json:"preferences" yaml:"preferences"`
+	IdentityVerificationTokens []IdentityVerificationTokenRecord `json:"identity_verification_tokens" yaml:"identity_verification_tokens"`
+	TOTPConfigurations         []TOTPConfigurationRecord         `json:"totp_configurations" yaml:"totp_configurations"`
+	U2FDevices                 []U2FDeviceRecord                 `json:"u2f_devices" yaml:"u2f_devices"`
+	WebauthnDevices            []WebauthnDeviceRecord            `json:"webauthn_devices" yaml:"webauthn_devices"`
+	RecoveryCodes              []RecoveryCodeRecord              `json:"recovery_codes" yaml:"recovery_codes"`
+	AuthenticationLogs         []AuthenticationLogRecord         `json:"authentication_logs" yaml:"authentication_logs"`
+	Users                      []UserRecord                      `json:"users" yaml:"users"`
+	UserGroups                 []UserGroupRecord                 `json:"user_groups" yaml:"user_groups"`
+}
+
+// PreferencesRecord the preferences of a user.
+type PreferencesRecord struct {
+	Username           string `json:"username" yaml:"username"`
+	SecondFactorMethod string `json:"second_factor_method" yaml:"second_factor_method"`
+}
+
+// IdentityVerificationTokenRecord an identity verification token which has not been used yet.
+type IdentityVerificationTokenRecord struct {
+	Token     string     `json:"token" yaml:"token"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// identityVerificationTokenRecord is an alias of IdentityVerificationTokenRecord without its unmarshal methods.
+type identityVerificationTokenRecord IdentityVerificationTokenRecord
+
+// UnmarshalJSON unmarshals a token record or a bare token as exported in the datasets of version 1.
+func (r *IdentityVerificationTokenRecord) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Token); err == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, (*identityVerificationTokenRecord)(r))
+}
+
+// UnmarshalYAML unmarshals a token record or a bare token as exported in the datasets of version 1.
+func (r *IdentityVerificationTokenRecord) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&r.Token); err == nil {
+		return nil
+	}
+
+	return unmarshal((*identityVerificationTokenRecord)(r))
+}
+
+// TOTPConfigurationRecord a TOTP device registered by a user.
+type TOTPConfigurationRecord struct {
+	Username    string     `json:"username" yaml:"username"`
+	Description string     `json:"description" yaml:"description"`
+	Secret      string     `json:"secret" yaml:"secret"`
+	Algorithm   string     `json:"algorithm" yaml:"algorithm"`
+	Digits      int        `json:"digits" yaml:"digits"`
+	Period      int        `json:"period" yaml:"period"`
+	LastStep    *int64     `json:"last_step,omitempty" yaml:"last_step,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" yaml:"last_used_at,omitempty"`
+}
+
+// U2FDeviceRecord a U2F device registered by a user.
+type U2FDeviceRecord struct {
+	Username    string     `json:"username" yaml:"username"`
+	Description string     `json:"description" yaml:"description"`
+	KeyHandle   string     `json:"key_handle" yaml:"key_handle"`
+	PublicKey   string     `json:"public_key" yaml:"public_key"`
+	SignCount   uint32     `json:"sign_count" yaml:"sign_count"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" yaml:"last_used_at,omitempty"`
+}
+
+// WebauthnDeviceRecord a WebAuthn credential registered by a user.
+type WebauthnDeviceRecord struct {
+	Username        string     `json:"username" yaml:"username"`
+	Description     string     `json:"description" yaml:"description"`
+	KID             string     `json:"kid" yaml:"kid"`
+	PublicKey       string     `json:"public_key" yaml:"public_key"`
+	AttestationType string     `json:"attestation_type" yaml:"attestation_type"`
+	AAGUID          string     `json:"aaguid" yaml:"aaguid"`
+	SignCount       uint32     `json:"sign_count" yaml:"sign_count"`
+	CreatedAt       time.Time  `json:"created_at" yaml:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty" yaml:"last_used_at,omitempty"`
+}
+
+// RecoveryCodeRecord a recovery code generated for a user.
+type RecoveryCodeRecord struct {
+	Username  string     `json:"username" yaml:"username"`
+	CodeHash  string     `json:"code_hash" yaml:"code_hash"`
+	CreatedAt time.Time  `json:"created_at" yaml:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" yaml:"used_at,omitempty"`
+}
+
+// AuthenticationLogRecord an authentication attempt.
+type AuthenticationLogRecord struct {
+	Username   string    `json:"username" yaml:"username"`
+	Successful bool      `json:"successful" yaml:"successful"`
+	AuthType   string    `json:"auth_type" yaml:"auth_type"`
+	RemoteIP   net.IP    `json:"remote_ip,omitempty" yaml:"remote_ip,omitempty"`
+	Time       time.Time `json:"time" yaml:"time"`
+}
+
+// UserRecord a user of the SQL authentication backend.
+type UserRecord struct {
+	Username    string `json:"username" yaml:"username"`
+	Password    string `json:"password" yaml:"password"`
+	DisplayName string `json:"display_name" yaml:"display_name"`
+	Email       string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// UserGroupRecord the membership of a user of the SQL authentication backend to a group.
+type UserGroupRecord struct {
+	Username string `json:"username" yaml:"username"`
+	Group    string `json:"group" yaml:"group"`
+}
+
+// ExportDataset exports all the data of the storage backend.
+func (p *SQLProvider) ExportDataset() (*Dataset, error) {
+	dataset := &Dataset{Version: DatasetVersion}
+
+	exports := []struct {
+		table string
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		{preferencesTableName, SQLExportPreferences, func(rows *sql.Rows) error {
+			var (
+				record PreferencesRecord
+				method sql.NullString
+			)
+
+			err := rows.Scan(&record.Username, &method)
+			record.SecondFactorMethod = method.String
+			dataset.Preferences = append(dataset.Preferences, record)
+
+			return err
+		}},
+		{identityVerificationTokensTableName, SQLExportIdentityVerificationTokens, func(rows *sql.Rows) error {
+			var (
+				record    IdentityVerificationTokenRecord
+				expiresAt sql.NullInt64
+			)
+
+			err := rows.Scan(&record.Token, &expiresAt)
+			record.ExpiresAt = fromNullUnixTime(expiresAt)
+			dataset.IdentityVerificationTokens = append(dataset.IdentityVerificationTokens, record)
+
+			return err
+		}},
+		{totpConfigurationsTableName, SQLExportTOTPConfigurations, func(rows *sql.Rows) error {
+			var (
+				record               TOTPConfigurationRecord
+				lastStep, lastUsedAt sql.NullInt64
+				createdAt            int64
+			)
+
+			err := rows.Scan(&record.Username, &record.Description, &record.Secret, &record.Algorithm,
+				&record.Digits, &record.Period, &lastStep, &createdAt, &lastUsedAt)
+			if err != nil {
+				return err
+			}
+
+			if lastStep.Valid {
+				record.LastStep = &lastStep.Int64
+			}
+
+			record.CreatedAt = time.Unix(createdAt, 0)
+			record.LastUsedAt = fromNullUnixTime(lastUsedAt)
+			record.Secret, err = p.decrypt(record.Secret)
+			dataset.TOTPConfigurations = append(dataset.TOTPConfigurations, record)
+
+			return err
+		}},
+		{u2fDeviceHandlesTableName, SQLExportU2FDevices, func(rows *sql.Rows) error {
+			var (
+				record     U2FDeviceRecord
+				createdAt  int64
+				lastUsedAt sql.NullInt64
+			)
+
+			err := rows.Scan(&record.Username, &record.Description, &record.KeyHandle, &record.PublicKey,
+				&record.SignCount, &createdAt, &lastUsedAt)
+			if err != nil {
+				return err
+			}
+
+			record.CreatedAt = time.Unix(createdAt, 0)
+			record.LastUsedAt = fromNullUnixTime(lastUsedAt)
+			record.PublicKey, err = p.decrypt(record.PublicKey)
+			dataset.U2FDevices = append(dataset.U2FDevices, record)
+
+			return err
+		}},
+		{webauthnDevicesTableName, SQLExportWebauthnDevices, func(rows *sql.Rows) error {
+			var (
+				record     WebauthnDeviceRecord
+				createdAt  int64
+				lastUsedAt sql.NullInt64
+			)
+
+			err := rows.Scan(&record.Username, &record.Description, &record.KID, &record.PublicKey,
+				&record.AttestationType, &record.AAGUID, &record.SignCount, &createdAt, &lastUsedAt)
+			if err != nil {
+				return err
+			}
+
+			record.CreatedAt = time.Unix(createdAt, 0)
+			record.LastUsedAt = fromNullUnixTime(lastUsedAt)
+			record.PublicKey, err = p.decrypt(record.PublicKey)
+			dataset.WebauthnDevices = append(dataset.WebauthnDevices, record)
+
+			return err
+		}},
+		{recoveryCodesTableName, SQLExportRecoveryCodes, func(rows *sql.Rows) error {
+			var (
+				record    RecoveryCodeRecord
+				createdAt int64
+				usedAt    sql.NullInt64
+			)
+
+			err := rows.Scan(&record.Username, &record.CodeHash, &createdAt, &usedAt)
+			record.CreatedAt = time.Unix(createdAt, 0)
+			record.UsedAt = fromNullUnixTime(usedAt)
+			dataset.RecoveryCodes = append(dataset.RecoveryCodes, record)
+
+			return err
+		}},
+		{authenticationLogsTableName, SQLExportAuthenticationLogs, func(rows *sql.Rows) error {
+			var (
+				record   AuthenticationLogRecord
+				remoteIP sql.NullString
+				t        int64
+			)
+
+			err := rows.Scan(&record.Username, &record.Successful, &record.AuthType, &remoteIP, &t)
+			record.RemoteIP = decodeIP(remoteIP)
+			record.Time = time.Unix(t, 0)
+			dataset.AuthenticationLogs = append(dataset.AuthenticationLogs, record)
+
+			return err
+		}},
+		{usersTableName, SQLExportUsers, func(rows *sql.Rows) error {
+			var (
+				record UserRecord
+				email  sql.NullString
+			)
+
+			err := rows.Scan(&record.Username, &record.Password, &record.DisplayName, &email)
+			record.Email = email.String
+			dataset.Users = append(dataset.Users, record)
+
+			return err
+		}},
+		{userGroupsTableName, SQLExportUserGroups, func(rows *sql.Rows) error {
+			var record UserGroupRecord
+
+			err := rows.Scan(&record.Username, &record.Group)
+			dataset.UserGroups = append(dataset.UserGroups, record)
+
+			return err
+		}},
+	}
+
+	for _, export := range exports {
+		if err := p.exportTable(export.query, export.scan); err != nil {
+			return nil, fmt.Errorf("Unable to export table %s: %v", export.table, err)
+		}
+	}
+
+	return dataset, nil
+}
+
+func (p *SQLProvider) exportTable(query string, scan func(rows *sql.Rows) error) error {
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ImportDataset imports a dataset into the storage backend. The backend must not hold any data yet so that the
+// imported data is not mixed with existing data. The datasets exported by previous versions are imported as well,
+// the data they are missing being defaulted.
+func (p *SQLProvider) ImportDataset(dataset *Dataset) error {
+	if dataset.Version < 1 || dataset.Version > DatasetVersion {
+		return fmt.Errorf("Unable to import a dataset of version %d, the supported versions are 1 to %d", dataset.Version, DatasetVersion)
+	}
+
+	for _, table := range []string{preferencesTableName, identityVerificationTokensTableName, totpConfigurationsTableName,
+		u2fDeviceHandlesTableName, webauthnDevicesTableName, recoveryCodesTableName, authenticationLogsTableName,
+		usersTableName, userGroupsTableName} {
+		var count int
+
+		if err := p.db.QueryRow(fmt.Sprintf(SQLCountRows, table)).Scan(&count); err != nil {
+			return fmt.Errorf("Unable to count the rows of table %s: %v", table, err)
+		}
+
+		if count > 0 {
+			return fmt.Errorf("Unable to import into a storage backend holding data, table %s is not empty", table)
+		}
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := p.importDataset(tx, dataset); err != nil {
+		tx.Rollback() //nolint:errcheck // The error of the import is more relevant.
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *SQLProvider) importDataset(tx *sql.Tx, dataset *Dataset) error {
+	for _, record := range dataset.Preferences {
+		if _, err := tx.Exec(p.sqlUpsertSecondFactorPreference, record.Username, record.SecondFactorMethod); err != nil {
+			return fmt.Errorf("Unable to import the preferences of user %s: %v", record.Username, err)
+		}
+	}
+
+	// The expiration time of the tokens exported before version 2 is unknown, hence they are kept for a while as
+	// done when the schema is migrated.
+	legacyExpiresAt := time.Now().Add(legacyIdentityVerificationTokensRetention)
+
+	for _, record := range dataset.IdentityVerificationTokens {
+		if record.ExpiresAt == nil {
+			record.ExpiresAt = &legacyExpiresAt
+		}
+
+		if _, err := tx.Exec(p.sqlInsertIdentityVerificationToken, record.Token, toNullUnixTime(record.ExpiresAt)); err != nil {
+			return fmt.Errorf("Unable to import identity verification token: %v", err)
+		}
+	}
+
+	if err := p.importDevices(tx, dataset); err != nil {
+		return err
+	}
+
+	for _, record := range dataset.RecoveryCodes {
+		if _, err := tx.Exec(p.sqlImportRecoveryCode, record.Username, record.CodeHash, record.CreatedAt.Unix(),
+			toNullUnixTime(record.UsedAt)); err != nil {
+			return fmt.Errorf("Unable to import the recovery code of user %s: %v", record.Username, err)
+		}
+	}
+
+	for _, record := range dataset.AuthenticationLogs {
+		if _, err := tx.Exec(p.sqlInsertAuthenticationLog, record.Username, record.Successful, record.AuthType,
+			encodeIP(record.RemoteIP), record.Time.Unix()); err != nil {
+			return fmt.Errorf("Unable to import the authentication log of user %s: %v", record.Username, err)
+		}
+	}
+
+	return p.importUsers(tx, dataset)
+}
+
+func (p *SQLProvider) importDevices(tx *sql.Tx, dataset *Dataset) error {
+	for _, record := range dataset.TOTPConfigurations {
+		lastStep := sql.NullInt64{}
+		if record.LastStep != nil {
+			lastStep = sql.NullInt64{Int64: *record.LastStep, Valid: true}
+		}
+
+		secret, err := p.encrypt(record.Secret)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt the TOTP secret of user %s: %v", record.Username, err)
+		}
+
+		if _, err = tx.Exec(p.sqlImportTOTPConfiguration, record.Username, record.Description, secret,
+			record.Algorithm, record.Digits, record.Period, lastStep, record.CreatedAt.Unix(),
+			toNullUnixTime(record.LastUsedAt)); err != nil {
+			return fmt.Errorf("Unable to import the TOTP configuration of user %s: %v", record.Username, err)
+		}
+	}
+
+	for _, record := range dataset.U2FDevices {
+		publicKey, err := p.encrypt(record.PublicKey)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt the public key of the U2F device of user %s: %v", record.Username, err)
+		}
+
+		if _, err = tx.Exec(p.sqlImportU2FDevice, record.Username, record.Description, record.KeyHandle,
+			publicKey, record.SignCount, record.CreatedAt.Unix(), toNullUnixTime(record.LastUsedAt)); err != nil {
+			return fmt.Errorf("Unable to import the U2F device of user %s: %v", record.Username, err)
+		}
+	}
+
+	for _, record := range dataset.WebauthnDevices {
+		publicKey, err := p.encrypt(record.PublicKey)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt the public key of the WebAuthn device of user %s: %v", record.Username, err)
+		}
+
+		if _, err = tx.Exec(p.sqlImportWebauthnDevice, record.Username, record.Description, record.KID,
+			publicKey, record.AttestationType, record.AAGUID, record.SignCount, record.CreatedAt.Unix(),
+			toNullUnixTime(record.LastUsedAt)); err != nil {
+			return fmt.Errorf("Unable to import the WebAuthn device of user %s: %v", record.Username, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *SQLProvider) importUsers(tx *sql.Tx, dataset *Dataset) error {
+	for _, record := range dataset.Users {
+		email := sql.NullString{String: record.Email, Valid: record.Email != ""}
+
+		if _, err := tx.Exec(p.sqlInsertUser, record.Username, record.Password, record.DisplayName, email); err != nil {
+			return fmt.Errorf("Unable to import user %s: %v", record.Username, err)
+		}
+	}
+
+	for _, record := range dataset.UserGroups {
+		if _, err := tx.Exec(p.sqlInsertUserGroup, record.Username, record.Group); err != nil {
+			return fmt.Errorf("Unable to import group %s of user %s: %v", record.Group, record.Username, err)
+		}
+	}
+
+	return nil
+}
+
+func fromNullUnixTime(value sql.NullInt64) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	t := time.Unix(value.Int64, 0)
+
+	return &t
+}
+
+func toNullUnixTime(value *time.Time) sql.NullInt64 {
+	if value == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: value.Unix(), Valid: true}
+}
@@ -0,0 +1,84 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// datasetVersion1 is a dataset as exported before the expiration time of the identity verification tokens and the
+// users were exported.
+const datasetVersion1 = `{
+  "version": 1,
+  "preferences": [
+    {"username": "john", "second_factor_method": "totp"}
+  ],
+  "identity_verification_tokens": ["abc"],
+  "totp_configurations": [
+    {"username": "john", "description": "Phone", "secret": "JBSWY3DPEHPK3PXP", "algorithm": "SHA1", "digits": 6,
+      "period": 30, "created_at": "2020-06-01T10:00:00Z"}
+  ],
+  "u2f_devices": null,
+  "webauthn_devices": null,
+  "recovery_codes": null,
+  "authentication_logs": [
+    {"username": "john", "successful": true, "auth_type": "1FA", "time": "2020-06-01T10:00:00Z"}
+  ]
+}`
+
+func newDatasetProvider(t *testing.T) storage.DatasetProvider {
+	dir, err := ioutil.TempDir("", "dataset")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	provider, err := storage.NewDatasetProvider(schema.StorageConfiguration{
+		EncryptionKey: "a_very_important_secret",
+		Local:         &schema.LocalStorageConfiguration{Path: filepath.Join(dir, "db.sqlite3")},
+	})
+	require.NoError(t, err)
+
+	return provider
+}
+
+func TestShouldImportDatasetOfVersion1(t *testing.T) {
+	dataset := &storage.Dataset{}
+	require.NoError(t, json.Unmarshal([]byte(datasetVersion1), dataset))
+
+	provider := newDatasetProvider(t)
+	require.NoError(t, provider.ImportDataset(dataset))
+
+	exported, err := provider.ExportDataset()
+	require.NoError(t, err)
+
+	assert.Equal(t, storage.DatasetVersion, exported.Version)
+	assert.Equal(t, dataset.Preferences, exported.Preferences)
+
+	require.Len(t, exported.TOTPConfigurations, 1)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", exported.TOTPConfigurations[0].Secret)
+
+	require.Len(t, exported.AuthenticationLogs, 1)
+	assert.Equal(t, "john", exported.AuthenticationLogs[0].Username)
+
+	// The tokens exported without their expiration time are kept for a while.
+	require.Len(t, exported.IdentityVerificationTokens, 1)
+	assert.Equal(t, "abc", exported.IdentityVerificationTokens[0].Token)
+	require.NotNil(t, exported.IdentityVerificationTokens[0].ExpiresAt)
+	assert.True(t, exported.IdentityVerificationTokens[0].ExpiresAt.After(time.Now()))
+}
+
+func TestShouldRejectDatasetOfUnknownVersion(t *testing.T) {
+	provider := newDatasetProvider(t)
+
+	err := provider.ImportDataset(&storage.Dataset{Version: storage.DatasetVersion + 1})
+	assert.EqualError(t, err, "Unable to import a dataset of version 5, the supported versions are 1 to 4")
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// encryptedValuePrefix the prefix of the values encrypted with the encryption key. The values without this prefix
+// were stored before the encryption key was configured and are read as is.
+const encryptedValuePrefix = "$aes256gcm$"
+
+var errNoEncryptionKey = errors.New("No encryption key is configured")
+
+// encryptedColumn a column of a table holding secrets encrypted with the encryption key.
+type encryptedColumn struct {
+	table  string
+	column string
+}
+
+var encryptedColumns = []encryptedColumn{
+	{totpConfigurationsTableName, "secret"},
+	{u2fDeviceHandlesTableName, "public_key"},
+	{webauthnDevicesTableName, "public_key"},
+}
+
+// newEncryptionKey derives the AES-256 key from the configured encryption key, nil if none is configured.
+func newEncryptionKey(key string) *[32]byte {
+	if key == "" {
+		return nil
+	}
+
+	encryptionKey := sha256.Sum256([]byte(key))
+
+	return &encryptionKey
+}
+
+// encrypt encrypts a value with the encryption key. The value is stored as is if no encryption key is configured.
+func (p *SQLProvider) encrypt(value string) (string, error) {
+	if p.encryptionKey == nil {
+		return value, nil
+	}
+
+	return encryptValue(value, p.encryptionKey)
+}
+
+// decrypt decrypts a value stored by encrypt.
+func (p *SQLProvider) decrypt(value string) (string, error) {
+	plaintext, err := decryptStoredValue(value, p.encryptionKey)
+	if err == errNoEncryptionKey {
+		return "", fmt.Errorf("Unable to decrypt a value stored encrypted: %v", err)
+	}
+
+	return plaintext, err
+}
+
+// decryptStoredValue decrypts a value stored encrypted with the key, the values stored in plain text are returned as
+// is. It returns errNoEncryptionKey if the value is encrypted and no key is given.
+func decryptStoredValue(value string, key *[32]byte) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+
+	if key == nil {
+		return "", errNoEncryptionKey
+	}
+
+	return decryptValue(value, key)
+}
+
+// reencryptValue encrypts with the key a value stored in plain text or encrypted with the previous key. It returns
+// false if the value is already encrypted with the key, in which case it is left untouched.
+func reencryptValue(value string, key, previousKey *[32]byte) (string, bool, error) {
+	if strings.HasPrefix(value, encryptedValuePrefix) {
+		if _, err := decryptValue(value, key); err == nil {
+			return value, false, nil
+		}
+
+		if previousKey == nil {
+			return "", false, errors.New("The value is not encrypted with the encryption key and no previous encryption key is given")
+		}
+
+		var err error
+
+		if value, err = decryptValue(value, previousKey); err != nil {
+			return "", false, fmt.Errorf("Unable to decrypt the value with the previous encryption key: %v", err)
+		}
+	}
+
+	encrypted, err := encryptValue(value, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	return encrypted, true, nil
+}
+
+func encryptValue(value string, key *[32]byte) (string, error) {
+	ciphertext, err := utils.Encrypt([]byte(value), key)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func decryptValue(value string, key *[32]byte) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := utils.Decrypt(ciphertext, key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// EncryptSecrets encrypts the secrets stored in plain text and re-encrypts the secrets encrypted with the previous
+// encryption key, if any, with the configured encryption key. It returns the number of secrets updated.
+func (p *SQLProvider) EncryptSecrets(previousEncryptionKey string) (int, error) {
+	if p.encryptionKey == nil {
+		return 0, errNoEncryptionKey
+	}
+
+	previousKey := newEncryptionKey(previousEncryptionKey)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, c := range encryptedColumns {
+		n, err := p.encryptColumn(tx, c, previousKey)
+		if err != nil {
+			tx.Rollback() //nolint:errcheck // The error of the encryption is more relevant.
+			return 0, fmt.Errorf("Unable to encrypt column %s of table %s: %v", c.column, c.table, err)
+		}
+
+		count += n
+	}
+
+	return count, tx.Commit()
+}
+
+func (p *SQLProvider) encryptColumn(tx *sql.Tx, c encryptedColumn, previousKey *[32]byte) (int, error) {
+	values, err := loadColumnValues(tx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for id, value := range values {
+		encrypted, updated, err := reencryptValue(value, p.encryptionKey, previousKey)
+		if err != nil {
+			return 0, fmt.Errorf("Unable to encrypt the value of row %d: %v", id, err)
+		}
+
+		if !updated {
+			continue
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf(p.sqlUpdateEncryptedValue, c.table, c.column), encrypted, id); err != nil {
+			return 0, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// loadColumnValues loads the values of a column by row ID. The rows are all read before being updated since
+// some drivers do not support executing queries while reading rows.
+func loadColumnValues(tx *sql.Tx, c encryptedColumn) (map[int]string, error) {
+	rows, err := tx.Query(fmt.Sprintf(SQLGetColumnValues, c.column, c.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[int]string)
+
+	for rows.Next() {
+		var (
+			id    int
+			value string
+		)
+
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+
+		values[id] = value
+	}
+
+	return values, rows.Err()
+}
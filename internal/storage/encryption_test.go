@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReencryptValues(t *testing.T) {
+	key := newEncryptionKey("a_very_important_secret")
+	previousKey := newEncryptionKey("a_previous_secret")
+	wrongKey := newEncryptionKey("a_wrong_secret")
+
+	encryptedWithKey, err := encryptValue("JBSWY3DPEHPK3PXP", key)
+	require.NoError(t, err)
+
+	encryptedWithPreviousKey, err := encryptValue("JBSWY3DPEHPK3PXP", previousKey)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		value       string
+		previousKey *[32]byte
+		updated     bool
+		err         string
+	}{
+		{"plain text value", "JBSWY3DPEHPK3PXP", nil, true, ""},
+		{"value encrypted with the key", encryptedWithKey, previousKey, false, ""},
+		{"value encrypted with the previous key", encryptedWithPreviousKey, previousKey, true, ""},
+		{"value encrypted with another key", encryptedWithPreviousKey, wrongKey, false,
+			"Unable to decrypt the value with the previous encryption key: cipher: message authentication failed"},
+		{"value encrypted without previous key", encryptedWithPreviousKey, nil, false,
+			"The value is not encrypted with the encryption key and no previous encryption key is given"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, updated, err := reencryptValue(tc.value, key, tc.previousKey)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.updated, updated)
+			assert.True(t, strings.HasPrefix(encrypted, encryptedValuePrefix))
+
+			if !tc.updated {
+				assert.Equal(t, tc.value, encrypted)
+			}
+
+			plaintext, err := decryptStoredValue(encrypted, key)
+			require.NoError(t, err)
+			assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+		})
+	}
+}
+
+func TestShouldDecryptStoredValues(t *testing.T) {
+	key := newEncryptionKey("a_very_important_secret")
+
+	encrypted, err := encryptValue("JBSWY3DPEHPK3PXP", key)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		value string
+		key   *[32]byte
+		err   error
+	}{
+		{"plain text value with key", "JBSWY3DPEHPK3PXP", key, nil},
+		{"plain text value without key", "JBSWY3DPEHPK3PXP", nil, nil},
+		{"encrypted value with key", encrypted, key, nil},
+		{"encrypted value without key", encrypted, nil, errNoEncryptionKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plaintext, err := decryptStoredValue(tc.value, tc.key)
+			if tc.err != nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+		})
+	}
+}
+
+func TestShouldNotEncryptSecretsWithoutEncryptionKey(t *testing.T) {
+	provider := &SQLProvider{}
+
+	_, err := provider.EncryptSecrets("a_previous_secret")
+	assert.Equal(t, errNoEncryptionKey, err)
+}
@@ -6,6 +6,16 @@ var (
 	// ErrNoU2FDeviceHandle error thrown when no U2F device handle has been found in DB.
 	ErrNoU2FDeviceHandle = errors.New("No U2F device handle found")
 
-	// ErrNoTOTPSecret error thrown when no TOTP secret has been found in DB
+	// ErrNoTOTPSecret error thrown when no TOTP configuration has been found in DB
 	ErrNoTOTPSecret = errors.New("No TOTP secret registered")
+	// ErrTOTPStepAlreadyUsed error thrown when a TOTP passcode of the same or a later time-step has already been accepted.
+	ErrTOTPStepAlreadyUsed = errors.New("TOTP passcode has already been used")
+	// ErrNoRecoveryCode error thrown when no unused recovery code has been found in DB.
+	ErrNoRecoveryCode = errors.New("No recovery code found")
+	// ErrRecoveryCodeAlreadyUsed error thrown when a recovery code has already been consumed.
+	ErrRecoveryCodeAlreadyUsed = errors.New("Recovery code has already been used")
+	// ErrNoWebauthnDevice error thrown when no WebAuthn device has been found in DB.
+	ErrNoWebauthnDevice = errors.New("No WebAuthn device found")
+	// ErrNoUser error thrown when no user has been found in DB.
+	ErrNoUser = errors.New("No user found")
 )
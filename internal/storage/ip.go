@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"net"
+)
+
+// encodeIP encodes an IP address as the hexadecimal representation of its 16 bytes form. The addresses of
+// a network are therefore contiguous once encoded.
+func encodeIP(ip net.IP) sql.NullString {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: hex.EncodeToString(ip16), Valid: true}
+}
+
+// decodeIP decodes an IP address encoded with encodeIP.
+func decodeIP(value sql.NullString) net.IP {
+	if !value.Valid {
+		return nil
+	}
+
+	ip, err := hex.DecodeString(value.String)
+	if err != nil || len(ip) != net.IPv6len {
+		return nil
+	}
+
+	return net.IP(ip)
+}
+
+// encodeNetwork returns the encoded first and last addresses of a network.
+func encodeNetwork(network *net.IPNet) (first string, last string) {
+	ip := network.IP.To16()
+	mask := network.Mask
+
+	// IPv4 addresses are mapped into the IPv6 address space in their 16 bytes form.
+	if len(mask) == net.IPv4len {
+		mask = append(net.CIDRMask(96, 128)[:12], mask...)
+	}
+
+	firstIP := make(net.IP, net.IPv6len)
+	lastIP := make(net.IP, net.IPv6len)
+
+	for i := range ip {
+		firstIP[i] = ip[i] & mask[i]
+		lastIP[i] = ip[i] | ^mask[i]
+	}
+
+	return encodeIP(firstIP).String, encodeIP(lastIP).String
+}
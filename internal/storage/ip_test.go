@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"database/sql"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEncodeIP(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ip       net.IP
+		expected sql.NullString
+	}{
+		{"IPv4", net.ParseIP("192.168.1.10").To4(), sql.NullString{String: "00000000000000000000ffffc0a8010a", Valid: true}},
+		{"IPv4-mapped IPv6", net.ParseIP("::ffff:192.168.1.10"), sql.NullString{String: "00000000000000000000ffffc0a8010a", Valid: true}},
+		{"IPv6", net.ParseIP("2001:db8::1"), sql.NullString{String: "20010db8000000000000000000000001", Valid: true}},
+		{"nil", nil, sql.NullString{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := encodeIP(tc.ip)
+			assert.Equal(t, tc.expected, encoded)
+
+			if tc.ip == nil {
+				assert.Nil(t, decodeIP(encoded))
+				return
+			}
+
+			assert.True(t, tc.ip.Equal(decodeIP(encoded)))
+		})
+	}
+}
+
+func TestShouldNotDecodeMalformedIP(t *testing.T) {
+	assert.Nil(t, decodeIP(sql.NullString{String: "c0a8010a", Valid: true}))
+	assert.Nil(t, decodeIP(sql.NullString{String: "not an ip", Valid: true}))
+}
+
+func TestShouldEncodeNetwork(t *testing.T) {
+	testCases := []struct {
+		cidr  string
+		first string
+		last  string
+	}{
+		{"0.0.0.0/0", "00000000000000000000ffff00000000", "00000000000000000000ffffffffffff"},
+		{"::/0", "00000000000000000000000000000000", "ffffffffffffffffffffffffffffffff"},
+		{"192.168.1.0/24", "00000000000000000000ffffc0a80100", "00000000000000000000ffffc0a801ff"},
+		{"192.168.1.10/32", "00000000000000000000ffffc0a8010a", "00000000000000000000ffffc0a8010a"},
+		{"2001:db8::/32", "20010db8000000000000000000000000", "20010db8ffffffffffffffffffffffff"},
+		{"2001:db8:1:2::/64", "20010db8000100020000000000000000", "20010db800010002ffffffffffffffff"},
+		{"2001:db8::1/128", "20010db8000000000000000000000001", "20010db8000000000000000000000001"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cidr, func(t *testing.T) {
+			_, network, err := net.ParseCIDR(tc.cidr)
+			require.NoError(t, err)
+
+			first, last := encodeNetwork(network)
+			assert.Equal(t, tc.first, first)
+			assert.Equal(t, tc.last, last)
+		})
+	}
+}
+
+func TestShouldEncodeAddressesOfNetworkBetweenItsBounds(t *testing.T) {
+	_, network, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	first, last := encodeNetwork(network)
+
+	inside := encodeIP(net.ParseIP("192.168.1.77")).String
+	assert.True(t, first <= inside && inside <= last)
+
+	for _, ip := range []string{"192.168.0.255", "192.168.2.0", "::ffff:c0a8:0200", "2001:db8::1"} {
+		outside := encodeIP(net.ParseIP(ip)).String
+		assert.False(t, first <= outside && outside <= last, ip)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// Janitor periodically prunes the data of the storage backend which is not needed anymore. The data is pruned
+// with idempotent deletions, hence several instances sharing the same database can run their janitor concurrently.
+type Janitor struct {
+	provider Provider
+	clock    utils.Clock
+
+	interval                    time.Duration
+	authenticationLogsRetention time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewJanitor creates a janitor of the storage backend pruning the data according to the retention configuration.
+func NewJanitor(configuration schema.StorageRetentionConfiguration, provider Provider, clock utils.Clock) *Janitor {
+	interval, err := utils.ParseDurationString(configuration.Interval)
+	if err != nil {
+		panic(err)
+	}
+
+	authenticationLogsRetention, err := utils.ParseDurationString(configuration.AuthenticationLogs)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Janitor{
+		provider:                    provider,
+		clock:                       clock,
+		interval:                    interval,
+		authenticationLogsRetention: authenticationLogsRetention,
+		ctx:                         ctx,
+		cancel:                      cancel,
+	}
+}
+
+// Start prunes the data right away and then at each interval in the background until the janitor is stopped.
+// The janitor does nothing if the interval is 0.
+func (j *Janitor) Start() {
+	if j.interval == 0 {
+		return
+	}
+
+	go func() {
+		for {
+			j.Prune()
+
+			select {
+			case <-j.clock.After(j.interval):
+			case <-j.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the pruning of the data started by Start and cancels the pruning in progress, if any.
+func (j *Janitor) Stop() {
+	j.cancel()
+}
+
+// Prune deletes the expired identity verification tokens and the authentication logs older than the retention,
+// if any. The errors are logged since the pruning is retried at the next interval.
+func (j *Janitor) Prune() {
+	logger := logging.Logger()
+	now := j.clock.Now()
+
+	count, err := j.provider.PruneExpiredIdentityVerificationTokens(j.ctx, now)
+	if err != nil {
+		logger.Errorf("Unable to prune the expired identity verification tokens: %v", err)
+	} else if count > 0 {
+		logger.Debugf("Pruned %d expired identity verification tokens", count)
+	}
+
+	if j.authenticationLogsRetention == 0 {
+		return
+	}
+
+	count, err = j.provider.PruneAuthenticationLogs(j.ctx, now.Add(-j.authenticationLogsRetention))
+	if err != nil {
+		logger.Errorf("Unable to prune the authentication logs: %v", err)
+	} else if count > 0 {
+		logger.Debugf("Pruned %d authentication logs older than %s", count, j.authenticationLogsRetention)
+	}
+}
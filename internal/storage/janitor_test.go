@@ -0,0 +1,60 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+func TestShouldPruneExpiredTokensAndOldAuthenticationLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := storage.NewMockProvider(ctrl)
+	clock := &mocks.TestingClock{}
+	clock.Set(time.Unix(1600000000, 0))
+
+	janitor := storage.NewJanitor(schema.StorageRetentionConfiguration{Interval: "1h", AuthenticationLogs: "30d"}, provider, clock)
+
+	provider.EXPECT().PruneExpiredIdentityVerificationTokens(gomock.Any(), time.Unix(1600000000, 0)).Return(int64(2), nil)
+	provider.EXPECT().PruneAuthenticationLogs(gomock.Any(), time.Unix(1600000000, 0).Add(-30*24*time.Hour)).Return(int64(10), nil)
+
+	janitor.Prune()
+}
+
+func TestShouldKeepAuthenticationLogsWhenRetentionIsZero(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := storage.NewMockProvider(ctrl)
+	clock := &mocks.TestingClock{}
+	clock.Set(time.Unix(1600000000, 0))
+
+	janitor := storage.NewJanitor(schema.StorageRetentionConfiguration{Interval: "1h", AuthenticationLogs: "0"}, provider, clock)
+
+	provider.EXPECT().PruneExpiredIdentityVerificationTokens(gomock.Any(), time.Unix(1600000000, 0)).Return(int64(0), nil)
+
+	janitor.Prune()
+}
+
+func TestShouldPruneAuthenticationLogsWhenPruningTokensFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := storage.NewMockProvider(ctrl)
+	clock := &mocks.TestingClock{}
+	clock.Set(time.Unix(1600000000, 0))
+
+	janitor := storage.NewJanitor(schema.StorageRetentionConfiguration{Interval: "1h", AuthenticationLogs: "1d"}, provider, clock)
+
+	provider.EXPECT().PruneExpiredIdentityVerificationTokens(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("failed"))
+	provider.EXPECT().PruneAuthenticationLogs(gomock.Any(), time.Unix(1600000000, 0).Add(-24*time.Hour)).Return(int64(0), nil)
+
+	janitor.Prune()
+}
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// legacyIdentityVerificationTokensRetention the time the identity verification tokens issued before their
+// expiration time was recorded are kept for.
+const legacyIdentityVerificationTokensRetention = 24 * time.Hour
+
+// SchemaMigration describes a versioned change of the schema of the database.
+type SchemaMigration struct {
+	Version     int
+	Description string
+}
+
+// migration a versioned change of the schema along with the functions applying and reverting it.
+type migration struct {
+	SchemaMigration
+
+	up   func() error
+	down func() error
+}
+
+// migrations returns the migrations of the schema ordered by version. The dialect specific statements are the
+// ones of the provider.
+func (p *SQLProvider) migrations() []migration {
+	return []migration{
+		{
+			SchemaMigration: SchemaMigration{Version: 1, Description: "Create the initial schema"},
+			up:              p.createInitialSchema,
+			down: p.execStatements(dropTables(preferencesTableName, identityVerificationTokensTableName,
+				totpConfigurationsTableName, legacyTOTPSecretsTableName, u2fDeviceHandlesTableName,
+				legacyU2FDevicesTableName, authenticationLogsTableName, webauthnDevicesTableName,
+				recoveryCodesTableName)...),
+		},
+		{
+			SchemaMigration: SchemaMigration{Version: 2, Description: "Drop the legacy tables whose content has been moved"},
+			up:              p.execStatements(dropTables(legacyTOTPSecretsTableName, legacyU2FDevicesTableName)...),
+			down:            p.execStatements(p.sqlCreateLegacyTOTPSecretsTable, p.sqlCreateLegacyU2FDevicesTable),
+		},
+		{
+			// Reverting this migration fails if some encrypted secrets do not fit the narrower column anymore.
+			SchemaMigration: SchemaMigration{Version: 3, Description: "Widen the column of the TOTP secrets to hold encrypted secrets"},
+			up:              p.execStatements(p.sqlWidenTOTPSecretColumn),
+			down:            p.execStatements(p.sqlNarrowTOTPSecretColumn),
+		},
+		{
+			SchemaMigration: SchemaMigration{Version: 4, Description: "Record the expiration time of the identity verification tokens and index the time of the authentication logs"},
+			up:              p.prepareRetention,
+			down:            p.execStatements(p.sqlDropAuthenticationLogsTimeIndex, p.sqlDropIdentityVerificationTokensExpiresAtColumn),
+		},
+		{
+			SchemaMigration: SchemaMigration{Version: 5, Description: "Create the tables of the users of the SQL authentication backend"},
+			up:              p.execStatements(p.sqlCreateUsersTable, p.sqlCreateUserGroupsTable),
+			down:            p.execStatements(dropTables(usersTableName, userGroupsTableName)...),
+		},
+		{
+			SchemaMigration: SchemaMigration{Version: 6, Description: "Record the signature counter of the U2F devices"},
+			up: func() error {
+				return p.addColumnIfMissing(u2fDeviceHandlesTableName, "sign_count", SQLAddU2FDeviceHandlesSignCountColumn)
+			},
+			down: p.execStatements(p.sqlDropU2FDeviceHandlesSignCountColumn),
+		},
+	}
+}
+
+// SchemaMigrations returns the migrations of the schema known by this version of Authelia ordered by version.
+func (p *SQLProvider) SchemaMigrations() []SchemaMigration {
+	migrations := p.migrations()
+	schemaMigrations := make([]SchemaMigration, 0, len(migrations))
+
+	for _, m := range migrations {
+		schemaMigrations = append(schemaMigrations, m.SchemaMigration)
+	}
+
+	return schemaMigrations
+}
+
+// LatestSchemaVersion returns the latest version of the schema known by this version of Authelia.
+func (p *SQLProvider) LatestSchemaVersion() int {
+	migrations := p.migrations()
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the current version of the schema of the database, 0 if no migration has been applied.
+func (p *SQLProvider) SchemaVersion() (int, error) {
+	var version int
+
+	err := p.db.QueryRow(p.sqlGetSchemaVersion).Scan(&version)
+
+	return version, err
+}
+
+// lockMigrations takes an advisory lock so that the instances starting concurrently do not apply the same migrations
+// at the same time, and returns the function releasing it. The lock belongs to the connection it has been taken with,
+// hence this connection is set aside until the lock is released.
+func (p *SQLProvider) lockMigrations() (func(), error) {
+	if p.sqlLockMigrations == "" {
+		return func() {}, nil
+	}
+
+	ctx := context.Background()
+
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to lock the migrations of the schema: %v", err)
+	}
+
+	var locked sql.NullInt64
+
+	err = conn.QueryRowContext(ctx, p.sqlLockMigrations).Scan(&locked)
+	if err == nil && (!locked.Valid || locked.Int64 != 1) {
+		err = errors.New("the lock is held by another instance")
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Unable to lock the migrations of the schema: %v", err)
+	}
+
+	return func() {
+		conn.ExecContext(ctx, p.sqlUnlockMigrations) //nolint:errcheck // The lock is released when the connection is closed anyway.
+		conn.Close()
+	}, nil
+}
+
+// MigrateSchemaUp applies the migrations of the schema up to the target version.
+func (p *SQLProvider) MigrateSchemaUp(target int) error {
+	unlock, err := p.lockMigrations()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return p.migrateSchemaUp(target)
+}
+
+func (p *SQLProvider) migrateSchemaUp(target int) error {
+	current, err := p.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if target <= current || target > p.LatestSchemaVersion() {
+		return fmt.Errorf("Unable to migrate the schema up from version %d to version %d, the latest version is %d", current, target, p.LatestSchemaVersion())
+	}
+
+	for _, m := range p.migrations() {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+
+		if err := m.up(); err != nil {
+			return fmt.Errorf("Unable to apply migration %d (%s): %v", m.Version, m.Description, err)
+		}
+
+		if _, err := p.db.Exec(p.sqlInsertSchemaMigration, m.Version, time.Now().Unix()); err != nil {
+			// Another instance might have applied the same migration concurrently.
+			if version, lookupErr := p.SchemaVersion(); lookupErr == nil && version >= m.Version {
+				continue
+			}
+
+			return fmt.Errorf("Unable to record migration %d (%s): %v", m.Version, m.Description, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateSchemaDown reverts the migrations of the schema down to the target version.
+func (p *SQLProvider) MigrateSchemaDown(target int) error {
+	unlock, err := p.lockMigrations()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	current, err := p.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if target < 0 || target >= current {
+		return fmt.Errorf("Unable to migrate the schema down from version %d to version %d", current, target)
+	}
+
+	if current > p.LatestSchemaVersion() {
+		return fmt.Errorf("Unable to migrate the schema down from version %d since this version of Authelia only knows versions up to %d", current, p.LatestSchemaVersion())
+	}
+
+	migrations := p.migrations()
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+
+		if err := m.down(); err != nil {
+			return fmt.Errorf("Unable to revert migration %d (%s): %v", m.Version, m.Description, err)
+		}
+
+		if _, err := p.db.Exec(p.sqlDeleteSchemaMigration, m.Version); err != nil {
+			return fmt.Errorf("Unable to record the revert of migration %d (%s): %v", m.Version, m.Description, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateToLatestSchemaVersion applies the migrations the schema of the database is missing. It fails if the
+// schema is newer than the latest version known by this version of Authelia. The version is read under the lock so
+// that the migrations applied by another instance in the meantime are not applied again.
+func (p *SQLProvider) migrateToLatestSchemaVersion() error {
+	unlock, err := p.lockMigrations()
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	current, err := p.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	latest := p.LatestSchemaVersion()
+
+	switch {
+	case current > latest:
+		return fmt.Errorf("The schema version %d of the database is newer than the latest version %d known by this version of Authelia, "+
+			"upgrade Authelia or migrate the schema down with the version of Authelia which migrated it", current, latest)
+	case current < latest:
+		return p.migrateSchemaUp(latest)
+	}
+
+	return nil
+}
+
+// prepareRetention adds the columns and the indexes needed to prune the data which is not needed anymore. The
+// column may be left in place by a revert of the migration on some dialects, hence it is only added if missing.
+func (p *SQLProvider) prepareRetention() error {
+	err := p.addColumnIfMissing(identityVerificationTokensTableName, "expires_at", SQLAddIdentityVerificationTokensExpiresAtColumn)
+	if err != nil {
+		return err
+	}
+
+	// The expiration time of the tokens issued before this migration is unknown, hence they are kept for a while.
+	_, err = p.db.Exec(p.sqlSetLegacyIdentityVerificationTokensExpiration, time.Now().Add(legacyIdentityVerificationTokensRetention).Unix())
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlCreateAuthenticationLogsTimeIndex)
+
+	return err
+}
+
+func (p *SQLProvider) execStatements(statements ...string) func() error {
+	return func() error {
+		for _, statement := range statements {
+			// The statements not needed by a dialect are left empty.
+			if statement == "" {
+				continue
+			}
+
+			if _, err := p.db.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func dropTables(tables ...string) []string {
+	statements := make([]string, 0, len(tables))
+
+	for _, table := range tables {
+		statements = append(statements, fmt.Sprintf(SQLDropTable, table))
+	}
+
+	return statements
+}
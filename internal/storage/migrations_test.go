@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// baselineSQLiteSchema the schema of the SQLite databases created before the schema was versioned.
+var baselineSQLiteSchema = []string{
+	"CREATE TABLE user_preferences (username VARCHAR(100) PRIMARY KEY, second_factor_method VARCHAR(11))",
+	"CREATE TABLE identity_verification_tokens (token VARCHAR(512))",
+	"CREATE TABLE totp_secrets (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))",
+	"CREATE TABLE u2f_devices (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)",
+	"CREATE TABLE authentication_logs (username VARCHAR(100), successful BOOL, time INTEGER)",
+	"CREATE INDEX usr_time_idx ON authentication_logs (username, time)",
+}
+
+func newTestDatabasePath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "migrations")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "db.sqlite3")
+}
+
+func listTables(t *testing.T, p *SQLProvider) []string {
+	rows, err := p.db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	tables := make([]string, 0)
+
+	for rows.Next() {
+		var table string
+
+		require.NoError(t, rows.Scan(&table))
+
+		tables = append(tables, table)
+	}
+
+	require.NoError(t, rows.Err())
+
+	return tables
+}
+
+var latestTables = []string{authenticationLogsTableName, identityVerificationTokensTableName, recoveryCodesTableName,
+	schemaMigrationsTableName, totpConfigurationsTableName, u2fDeviceHandlesTableName, userGroupsTableName,
+	preferencesTableName, usersTableName, webauthnDevicesTableName}
+
+func TestShouldMigrateSchemaUpFromEmptyDatabase(t *testing.T) {
+	provider := newSQLiteProvider(newTestDatabasePath(t))
+
+	version, err := provider.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+
+	version, err = provider.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, provider.LatestSchemaVersion(), version)
+	assert.ElementsMatch(t, latestTables, listTables(t, &provider.SQLProvider))
+
+	// Migrating again is a no-op.
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+}
+
+func TestShouldMigrateSchemaUpFromBaselineDatabase(t *testing.T) {
+	path := newTestDatabasePath(t)
+	keyHandle := []byte("key-handle")
+	publicKey := []byte("public-key")
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+
+	for _, statement := range baselineSQLiteSchema {
+		_, err = db.Exec(statement)
+		require.NoError(t, err)
+	}
+
+	_, err = db.Exec("INSERT INTO totp_secrets (username, secret) VALUES ('john', 'JBSWY3DPEHPK3PXP')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO u2f_devices (username, keyHandle, publicKey) VALUES (?, ?, ?)", "john",
+		base64.StdEncoding.EncodeToString(keyHandle), base64.StdEncoding.EncodeToString(publicKey))
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO identity_verification_tokens (token) VALUES ('abc')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO authentication_logs (username, successful, time) VALUES ('john', 1, ?)", time.Now().Unix())
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	provider := newSQLiteProvider(path)
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+
+	assert.ElementsMatch(t, latestTables, listTables(t, &provider.SQLProvider))
+
+	ctx := context.Background()
+
+	configurations, err := provider.LoadTOTPConfigurations(ctx, "john")
+	require.NoError(t, err)
+	require.Len(t, configurations, 1)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", configurations[0].Secret)
+
+	devices, err := provider.LoadU2FDevicesByUsername(ctx, "john")
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, keyHandle, devices[0].KeyHandle)
+	assert.Equal(t, publicKey, devices[0].PublicKey)
+	assert.Equal(t, uint32(0), devices[0].SignCount)
+
+	// The tokens issued before their expiration time was recorded are kept for a while.
+	found, err := provider.FindIdentityVerificationToken(ctx, "abc")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	pruned, err := provider.PruneExpiredIdentityVerificationTokens(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pruned)
+
+	attempts, err := provider.LoadLatestAuthenticationLogs(ctx, "john", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.True(t, attempts[0].Successful)
+}
+
+func TestShouldMigrateSchemaDownAndUpAgain(t *testing.T) {
+	provider := newSQLiteProvider(newTestDatabasePath(t))
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+
+	require.NoError(t, provider.MigrateSchemaDown(0))
+
+	version, err := provider.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Equal(t, []string{schemaMigrationsTableName}, listTables(t, &provider.SQLProvider))
+
+	require.NoError(t, provider.MigrateSchemaUp(provider.LatestSchemaVersion()))
+
+	version, err = provider.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, provider.LatestSchemaVersion(), version)
+	assert.ElementsMatch(t, latestTables, listTables(t, &provider.SQLProvider))
+}
+
+func TestShouldNotMigrateSchemaToUnknownVersion(t *testing.T) {
+	provider := newSQLiteProvider(newTestDatabasePath(t))
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+
+	latest := provider.LatestSchemaVersion()
+
+	assert.EqualError(t, provider.MigrateSchemaUp(latest+1), fmt.Sprintf(
+		"Unable to migrate the schema up from version %d to version %d, the latest version is %d", latest, latest+1, latest))
+	assert.EqualError(t, provider.MigrateSchemaDown(latest), fmt.Sprintf(
+		"Unable to migrate the schema down from version %d to version %d", latest, latest))
+}
+
+func TestShouldRefuseToStartWhenSchemaIsNewer(t *testing.T) {
+	provider := newSQLiteProvider(newTestDatabasePath(t))
+	require.NoError(t, provider.migrateToLatestSchemaVersion())
+
+	latest := provider.LatestSchemaVersion()
+
+	_, err := provider.db.Exec(provider.sqlInsertSchemaMigration, latest+1, time.Now().Unix())
+	require.NoError(t, err)
+
+	assert.EqualError(t, provider.migrateToLatestSchemaVersion(), fmt.Sprintf("The schema version %d of the database is newer "+
+		"than the latest version %d known by this version of Authelia, upgrade Authelia or migrate the schema down with "+
+		"the version of Authelia which migrated it", latest+1, latest))
+	assert.EqualError(t, provider.MigrateSchemaDown(0), fmt.Sprintf("Unable to migrate the schema down from version %d "+
+		"since this version of Authelia only knows versions up to %d", latest+1, latest))
+}
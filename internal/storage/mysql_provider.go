@@ -3,11 +3,13 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 
-	_ "github.com/go-sql-driver/mysql" // Load the MySQL Driver used in the connection string.
+	"github.com/go-sql-driver/mysql" // Load the MySQL Driver used in the connection string.
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // MySQLProvider is a MySQL provider.
@@ -15,8 +17,18 @@ type MySQLProvider struct {
 	SQLProvider
 }
 
-// NewMySQLProvider a MySQL provider.
+// NewMySQLProvider a MySQL provider whose schema is migrated to the latest version.
 func NewMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProvider {
+	provider := newMySQLProvider(configuration)
+
+	if err := provider.migrateToLatestSchemaVersion(); err != nil {
+		logging.Logger().Fatalf("Unable to initialize SQL database: %v", err)
+	}
+
+	return provider
+}
+
+func newMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProvider {
 	connectionString := configuration.Username
 
 	if configuration.Password != "" {
@@ -32,9 +44,39 @@ func NewMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProv
 		address += fmt.Sprintf(":%d", configuration.Port)
 	}
 
-	connectionString += fmt.Sprintf("tcp(%s)", address)
-	if configuration.Database != "" {
-		connectionString += fmt.Sprintf("/%s", configuration.Database)
+	connectionString += fmt.Sprintf("tcp(%s)/%s", address, configuration.Database)
+
+	queryTimeout, err := utils.ParseDurationString(configuration.QueryTimeout)
+	if err != nil {
+		logging.Logger().Fatalf("Unable to configure the connections to SQL database: %v", err)
+	}
+
+	params := url.Values{}
+
+	// The timeouts of the reads and the writes bound the time a query can hang on the connection.
+	if queryTimeout > 0 {
+		params.Set("readTimeout", queryTimeout.String())
+		params.Set("writeTimeout", queryTimeout.String())
+	}
+
+	if configuration.TLS != nil {
+		tlsConfig, err := newTLSConfig(*configuration.TLS, configuration.Host)
+		if err != nil {
+			logging.Logger().Fatalf("Unable to configure TLS of SQL database: %v", err)
+		}
+
+		// Several databases might be used by the same process, e.g. when migrating the data between backends.
+		tlsConfigName := fmt.Sprintf("authelia-%s", address)
+
+		if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+			logging.Logger().Fatalf("Unable to configure TLS of SQL database: %v", err)
+		}
+
+		params.Set("tls", tlsConfigName)
+	}
+
+	if len(params) > 0 {
+		connectionString += "?" + params.Encode()
 	}
 
 	db, err := sql.Open("mysql", connectionString)
@@ -42,30 +84,90 @@ func NewMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProv
 		logging.Logger().Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
+	if err := configureConnectionPool(db, configuration.SQLStorageConfiguration); err != nil {
+		logging.Logger().Fatalf("Unable to configure the connections to SQL database: %v", err)
+	}
+
 	provider := MySQLProvider{
 		SQLProvider{
+			timeout: queryTimeout,
+
+			sqlCreateSchemaMigrationsTable:           SQLCreateSchemaMigrationsTable,
 			sqlCreateUserPreferencesTable:            SQLCreateUserPreferencesTable,
 			sqlCreateIdentityVerificationTokensTable: SQLCreateIdentityVerificationTokensTable,
-			sqlCreateTOTPSecretsTable:                SQLCreateTOTPSecretsTable,
+			sqlCreateLegacyTOTPSecretsTable:          SQLCreateLegacyTOTPSecretsTable,
+			sqlCreateTOTPConfigurationsTable:         SQLCreateTOTPConfigurationsTable,
+			sqlCreateLegacyU2FDevicesTable:           SQLCreateLegacyU2FDevicesTable,
 			sqlCreateU2FDeviceHandlesTable:           SQLCreateU2FDeviceHandlesTable,
 			sqlCreateAuthenticationLogsTable:         SQLCreateAuthenticationLogsTable,
+			sqlAddAuthenticationLogsRemoteIPColumn:   fmt.Sprintf("%s, ADD INDEX ip_time_idx (remote_ip, time)", SQLAddAuthenticationLogsRemoteIPColumn),
+			sqlCreateWebauthnDevicesTable:            SQLCreateWebauthnDevicesTable,
+			sqlCreateRecoveryCodesTable:              SQLCreateRecoveryCodesTable,
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", preferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", preferencesTableName),
 
-			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
-			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
-			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
-
-			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
-			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
-			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
-
-			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
-			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
-
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlTestIdentityVerificationTokenExistence:        fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
+			sqlInsertIdentityVerificationToken:               fmt.Sprintf("INSERT INTO %s (token, expires_at) VALUES (?, ?)", identityVerificationTokensTableName),
+			sqlDeleteIdentityVerificationToken:               fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
+			sqlDeleteExpiredIdentityVerificationTokens:       fmt.Sprintf("DELETE FROM %s WHERE expires_at<?", identityVerificationTokensTableName),
+			sqlSetLegacyIdentityVerificationTokensExpiration: fmt.Sprintf("UPDATE %s SET expires_at=? WHERE expires_at IS NULL", identityVerificationTokensTableName),
+
+			sqlGetTOTPConfigurationsByUsername: fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", totpConfigurationsTableName),
+			sqlInsertTOTPConfiguration:         fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)", totpConfigurationsTableName),
+			sqlUpdateTOTPConfigurationSignIn:   fmt.Sprintf("UPDATE %s SET last_step=?, last_used_at=? WHERE id=? AND (last_step IS NULL OR last_step < ?)", totpConfigurationsTableName),
+			sqlDeleteTOTPConfigurations:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpConfigurationsTableName),
+			sqlMigrateLegacyTOTPSecrets:        fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) SELECT username, 'Mobile', secret, 'sha1', 6, 0, ? FROM %s", totpConfigurationsTableName, legacyTOTPSecretsTableName),
+
+			sqlGetU2FDevicesByUsername: fmt.Sprintf("SELECT id, description, key_handle, public_key, sign_count, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", u2fDeviceHandlesTableName),
+			sqlInsertU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) VALUES (?, ?, ?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlUpdateU2FDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=?, last_used_at=? WHERE id=?", u2fDeviceHandlesTableName),
+			sqlMigrateLegacyU2FDevices: fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) SELECT username, 'Security Key', keyHandle, publicKey, ? FROM %s", u2fDeviceHandlesTableName, legacyU2FDevicesTableName),
+
+			sqlGetWebauthnDevicesByUsername: fmt.Sprintf("SELECT id, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", webauthnDevicesTableName),
+			sqlInsertWebauthnDevice:         fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", webauthnDevicesTableName),
+			sqlUpdateWebauthnDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=?, last_used_at=? WHERE id=?", webauthnDevicesTableName),
+
+			sqlGetUnusedRecoveryCodesByUsername: fmt.Sprintf("SELECT id, code_hash, created_at FROM %s WHERE username=? AND used_at IS NULL ORDER BY id", recoveryCodesTableName),
+			sqlInsertRecoveryCode:               fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at) VALUES (?, ?, ?)", recoveryCodesTableName),
+			sqlDeleteRecoveryCodes:              fmt.Sprintf("DELETE FROM %s WHERE username=?", recoveryCodesTableName),
+			sqlConsumeRecoveryCode:              fmt.Sprintf("UPDATE %s SET used_at=? WHERE id=? AND used_at IS NULL", recoveryCodesTableName),
+
+			sqlImportTOTPConfiguration: fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, last_step, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", totpConfigurationsTableName),
+			sqlImportU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, sign_count, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlImportWebauthnDevice:    fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", webauthnDevicesTableName),
+			sqlImportRecoveryCode:      fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at, used_at) VALUES (?, ?, ?, ?)", recoveryCodesTableName),
+
+			sqlGetSchemaVersion:      SQLGetSchemaVersion,
+			sqlInsertSchemaMigration: fmt.Sprintf("INSERT INTO %s (version, applied_at) VALUES (?, ?)", schemaMigrationsTableName),
+			sqlDeleteSchemaMigration: fmt.Sprintf("DELETE FROM %s WHERE version=?", schemaMigrationsTableName),
+			sqlLockMigrations:        fmt.Sprintf("SELECT GET_LOCK('%x', %d)", migrationsLockID, migrationsLockTimeout),
+			sqlUnlockMigrations:      fmt.Sprintf("SELECT RELEASE_LOCK('%x')", migrationsLockID),
+
+			sqlWidenTOTPSecretColumn:  fmt.Sprintf("ALTER TABLE %s MODIFY secret TEXT NOT NULL", totpConfigurationsTableName),
+			sqlNarrowTOTPSecretColumn: fmt.Sprintf("ALTER TABLE %s MODIFY secret VARCHAR(255) NOT NULL", totpConfigurationsTableName),
+			sqlUpdateEncryptedValue:   "UPDATE %s SET %s=? WHERE id=?",
+
+			sqlCreateAuthenticationLogsTimeIndex:             fmt.Sprintf("CREATE INDEX time_idx ON %s (time)", authenticationLogsTableName),
+			sqlDropAuthenticationLogsTimeIndex:               fmt.Sprintf("DROP INDEX time_idx ON %s", authenticationLogsTableName),
+			sqlDropIdentityVerificationTokensExpiresAtColumn: fmt.Sprintf("ALTER TABLE %s DROP COLUMN expires_at", identityVerificationTokensTableName),
+
+			sqlDropU2FDeviceHandlesSignCountColumn: fmt.Sprintf("ALTER TABLE %s DROP COLUMN sign_count", u2fDeviceHandlesTableName),
+
+			sqlCreateUsersTable:      SQLCreateUsersTable,
+			sqlCreateUserGroupsTable: SQLCreateUserGroupsTable,
+
+			sqlGetUserByUsername:       fmt.Sprintf("SELECT password, display_name, email FROM %s WHERE username=?", usersTableName),
+			sqlGetUserGroupsByUsername: fmt.Sprintf("SELECT group_name FROM %s WHERE username=? ORDER BY group_name", userGroupsTableName),
+			sqlUpdateUserPassword:      fmt.Sprintf("UPDATE %s SET password=? WHERE username=?", usersTableName),
+			sqlInsertUser:              fmt.Sprintf("INSERT INTO %s (username, password, display_name, email) VALUES (?, ?, ?, ?)", usersTableName),
+			sqlInsertUserGroup:         fmt.Sprintf("INSERT INTO %s (username, group_name) VALUES (?, ?)", userGroupsTableName),
+
+			sqlInsertAuthenticationLog:              fmt.Sprintf("INSERT INTO %s (username, successful, auth_type, remote_ip, time) VALUES (?, ?, ?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:          fmt.Sprintf("SELECT successful, auth_type, remote_ip, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogsByNetwork: fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>? AND remote_ip BETWEEN ? AND ? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:                fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>? ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteAuthenticationLogs:             fmt.Sprintf("DELETE FROM %s WHERE time<?", authenticationLogsTableName),
 		},
 	}
 	if err := provider.initialize(db); err != nil {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // PostgreSQLProvider is a PostgreSQL provider.
@@ -16,8 +17,18 @@ type PostgreSQLProvider struct {
 	SQLProvider
 }
 
-// NewPostgreSQLProvider a PostgreSQL provider.
+// NewPostgreSQLProvider a PostgreSQL provider whose schema is migrated to the latest version.
 func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration) *PostgreSQLProvider {
+	provider := newPostgreSQLProvider(configuration)
+
+	if err := provider.migrateToLatestSchemaVersion(); err != nil {
+		logging.Logger().Fatalf("Unable to initialize SQL database: %v", err)
+	}
+
+	return provider
+}
+
+func newPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration) *PostgreSQLProvider {
 	args := make([]string, 0)
 	if configuration.Username != "" {
 		args = append(args, fmt.Sprintf("user='%s'", configuration.Username))
@@ -43,6 +54,26 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 		args = append(args, fmt.Sprintf("sslmode=%s", configuration.SSLMode))
 	}
 
+	if configuration.TLS != nil {
+		if configuration.TLS.TrustedCert != "" {
+			args = append(args, fmt.Sprintf("sslrootcert='%s'", configuration.TLS.TrustedCert))
+		}
+
+		if configuration.TLS.Cert != "" {
+			args = append(args, fmt.Sprintf("sslcert='%s'", configuration.TLS.Cert), fmt.Sprintf("sslkey='%s'", configuration.TLS.Key))
+		}
+	}
+
+	// The statement timeout is a run-time parameter of the server given in milliseconds.
+	queryTimeout, err := utils.ParseDurationString(configuration.QueryTimeout)
+	if err != nil {
+		logging.Logger().Fatalf("Unable to configure the connections to SQL database: %v", err)
+	}
+
+	if queryTimeout > 0 {
+		args = append(args, fmt.Sprintf("statement_timeout=%d", queryTimeout.Milliseconds()))
+	}
+
 	connectionString := strings.Join(args, " ")
 
 	db, err := sql.Open("postgres", connectionString)
@@ -50,31 +81,96 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 		logging.Logger().Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
+	if err := configureConnectionPool(db, configuration.SQLStorageConfiguration); err != nil {
+		logging.Logger().Fatalf("Unable to configure the connections to SQL database: %v", err)
+	}
+
 	provider := PostgreSQLProvider{
 		SQLProvider{
+			timeout: queryTimeout,
+
+			sqlCreateSchemaMigrationsTable:           SQLCreateSchemaMigrationsTable,
 			sqlCreateUserPreferencesTable:            SQLCreateUserPreferencesTable,
 			sqlCreateIdentityVerificationTokensTable: SQLCreateIdentityVerificationTokensTable,
-			sqlCreateTOTPSecretsTable:                SQLCreateTOTPSecretsTable,
-			sqlCreateU2FDeviceHandlesTable:           SQLCreateU2FDeviceHandlesTable,
-			sqlCreateAuthenticationLogsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100), successful BOOL, time INTEGER)", authenticationLogsTableName),
+			sqlCreateLegacyTOTPSecretsTable:          SQLCreateLegacyTOTPSecretsTable,
+			sqlCreateTOTPConfigurationsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id SERIAL PRIMARY KEY, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, secret VARCHAR(255) NOT NULL, algorithm VARCHAR(6) NOT NULL, digits INTEGER NOT NULL, period INTEGER NOT NULL, last_step BIGINT, created_at INTEGER NOT NULL, last_used_at INTEGER)", totpConfigurationsTableName),
+			sqlCreateTOTPConfigurationsUsernameIndex: fmt.Sprintf("CREATE INDEX IF NOT EXISTS totp_usr_idx ON %s (username)", totpConfigurationsTableName),
+			sqlCreateLegacyU2FDevicesTable:           SQLCreateLegacyU2FDevicesTable,
+			sqlCreateU2FDeviceHandlesTable:           fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id SERIAL PRIMARY KEY, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, key_handle TEXT NOT NULL, public_key TEXT NOT NULL, created_at INTEGER NOT NULL, last_used_at INTEGER)", u2fDeviceHandlesTableName),
+			sqlCreateU2FDeviceHandlesUsernameIndex:   fmt.Sprintf("CREATE INDEX IF NOT EXISTS u2f_usr_idx ON %s (username)", u2fDeviceHandlesTableName),
+			sqlCreateAuthenticationLogsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100), successful BOOL, auth_type VARCHAR(32) NOT NULL DEFAULT '1FA', remote_ip VARCHAR(32), time INTEGER)", authenticationLogsTableName),
 			sqlCreateAuthenticationLogsUserTimeIndex: fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s (username, time)", authenticationLogsTableName),
+			sqlCreateAuthenticationLogsIPTimeIndex:   fmt.Sprintf("CREATE INDEX IF NOT EXISTS ip_time_idx ON %s (remote_ip, time)", authenticationLogsTableName),
+			sqlAddAuthenticationLogsRemoteIPColumn:   SQLAddAuthenticationLogsRemoteIPColumn,
+			sqlCreateWebauthnDevicesTable:            fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id SERIAL PRIMARY KEY, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, kid VARCHAR(512) NOT NULL, public_key TEXT NOT NULL, attestation_type VARCHAR(32) NOT NULL, aaguid VARCHAR(64) NOT NULL, sign_count BIGINT NOT NULL, created_at INTEGER NOT NULL, last_used_at INTEGER)", webauthnDevicesTableName),
+			sqlCreateWebauthnDevicesUsernameIndex:    fmt.Sprintf("CREATE INDEX IF NOT EXISTS webauthn_usr_idx ON %s (username)", webauthnDevicesTableName),
+			sqlCreateRecoveryCodesTable:              fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id SERIAL PRIMARY KEY, username VARCHAR(100) NOT NULL, code_hash VARCHAR(255) NOT NULL, created_at INTEGER NOT NULL, used_at INTEGER)", recoveryCodesTableName),
+			sqlCreateRecoveryCodesUsernameIndex:      fmt.Sprintf("CREATE INDEX IF NOT EXISTS recovery_usr_idx ON %s (username)", recoveryCodesTableName),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=$1", preferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("INSERT INTO %s (username, second_factor_method) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET second_factor_method=$2", preferencesTableName),
 
-			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=$1)", identityVerificationTokensTableName),
-			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES ($1)", identityVerificationTokensTableName),
-			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=$1", identityVerificationTokensTableName),
-
-			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=$1", totpSecretsTableName),
-			sqlUpsertTOTPSecret:        fmt.Sprintf("INSERT INTO %s (username, secret) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET secret=$2", totpSecretsTableName),
-			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpSecretsTableName),
-
-			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=$1", u2fDeviceHandlesTableName),
-			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("INSERT INTO %s (username, keyHandle, publicKey) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET keyHandle=$2, publicKey=$3", u2fDeviceHandlesTableName),
-
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES ($1, $2, $3)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlTestIdentityVerificationTokenExistence:        fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=$1)", identityVerificationTokensTableName),
+			sqlInsertIdentityVerificationToken:               fmt.Sprintf("INSERT INTO %s (token, expires_at) VALUES ($1, $2)", identityVerificationTokensTableName),
+			sqlDeleteIdentityVerificationToken:               fmt.Sprintf("DELETE FROM %s WHERE token=$1", identityVerificationTokensTableName),
+			sqlDeleteExpiredIdentityVerificationTokens:       fmt.Sprintf("DELETE FROM %s WHERE expires_at<$1", identityVerificationTokensTableName),
+			sqlSetLegacyIdentityVerificationTokensExpiration: fmt.Sprintf("UPDATE %s SET expires_at=$1 WHERE expires_at IS NULL", identityVerificationTokensTableName),
+
+			sqlGetTOTPConfigurationsByUsername: fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, created_at, last_used_at FROM %s WHERE username=$1 ORDER BY id", totpConfigurationsTableName),
+			sqlInsertTOTPConfiguration:         fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", totpConfigurationsTableName),
+			sqlUpdateTOTPConfigurationSignIn:   fmt.Sprintf("UPDATE %s SET last_step=$1, last_used_at=$2 WHERE id=$3 AND (last_step IS NULL OR last_step < $4)", totpConfigurationsTableName),
+			sqlDeleteTOTPConfigurations:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpConfigurationsTableName),
+			sqlMigrateLegacyTOTPSecrets:        fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) SELECT username, 'Mobile', secret, 'sha1', 6, 0, CAST($1 AS INTEGER) FROM %s", totpConfigurationsTableName, legacyTOTPSecretsTableName),
+
+			sqlGetU2FDevicesByUsername: fmt.Sprintf("SELECT id, description, key_handle, public_key, sign_count, created_at, last_used_at FROM %s WHERE username=$1 ORDER BY id", u2fDeviceHandlesTableName),
+			sqlInsertU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) VALUES ($1, $2, $3, $4, $5)", u2fDeviceHandlesTableName),
+			sqlUpdateU2FDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=$1, last_used_at=$2 WHERE id=$3", u2fDeviceHandlesTableName),
+			sqlMigrateLegacyU2FDevices: fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) SELECT username, 'Security Key', keyHandle, publicKey, CAST($1 AS INTEGER) FROM %s", u2fDeviceHandlesTableName, legacyU2FDevicesTableName),
+
+			sqlGetWebauthnDevicesByUsername: fmt.Sprintf("SELECT id, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at FROM %s WHERE username=$1 ORDER BY id", webauthnDevicesTableName),
+			sqlInsertWebauthnDevice:         fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", webauthnDevicesTableName),
+			sqlUpdateWebauthnDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=$1, last_used_at=$2 WHERE id=$3", webauthnDevicesTableName),
+
+			sqlGetUnusedRecoveryCodesByUsername: fmt.Sprintf("SELECT id, code_hash, created_at FROM %s WHERE username=$1 AND used_at IS NULL ORDER BY id", recoveryCodesTableName),
+			sqlInsertRecoveryCode:               fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at) VALUES ($1, $2, $3)", recoveryCodesTableName),
+			sqlDeleteRecoveryCodes:              fmt.Sprintf("DELETE FROM %s WHERE username=$1", recoveryCodesTableName),
+			sqlConsumeRecoveryCode:              fmt.Sprintf("UPDATE %s SET used_at=$1 WHERE id=$2 AND used_at IS NULL", recoveryCodesTableName),
+
+			sqlImportTOTPConfiguration: fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, last_step, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", totpConfigurationsTableName),
+			sqlImportU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, sign_count, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", u2fDeviceHandlesTableName),
+			sqlImportWebauthnDevice:    fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", webauthnDevicesTableName),
+			sqlImportRecoveryCode:      fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at, used_at) VALUES ($1, $2, $3, $4)", recoveryCodesTableName),
+
+			sqlGetSchemaVersion:      SQLGetSchemaVersion,
+			sqlInsertSchemaMigration: fmt.Sprintf("INSERT INTO %s (version, applied_at) VALUES ($1, $2)", schemaMigrationsTableName),
+			sqlDeleteSchemaMigration: fmt.Sprintf("DELETE FROM %s WHERE version=$1", schemaMigrationsTableName),
+			sqlLockMigrations:        fmt.Sprintf("SELECT 1 FROM pg_advisory_lock(%d)", migrationsLockID),
+			sqlUnlockMigrations:      fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationsLockID),
+
+			sqlWidenTOTPSecretColumn:  fmt.Sprintf("ALTER TABLE %s ALTER COLUMN secret TYPE TEXT", totpConfigurationsTableName),
+			sqlNarrowTOTPSecretColumn: fmt.Sprintf("ALTER TABLE %s ALTER COLUMN secret TYPE VARCHAR(255)", totpConfigurationsTableName),
+			sqlUpdateEncryptedValue:   "UPDATE %s SET %s=$1 WHERE id=$2",
+
+			sqlCreateAuthenticationLogsTimeIndex:             fmt.Sprintf("CREATE INDEX IF NOT EXISTS time_idx ON %s (time)", authenticationLogsTableName),
+			sqlDropAuthenticationLogsTimeIndex:               "DROP INDEX IF EXISTS time_idx",
+			sqlDropIdentityVerificationTokensExpiresAtColumn: fmt.Sprintf("ALTER TABLE %s DROP COLUMN expires_at", identityVerificationTokensTableName),
+
+			sqlDropU2FDeviceHandlesSignCountColumn: fmt.Sprintf("ALTER TABLE %s DROP COLUMN sign_count", u2fDeviceHandlesTableName),
+
+			sqlCreateUsersTable:      SQLCreateUsersTable,
+			sqlCreateUserGroupsTable: SQLCreateUserGroupsTable,
+
+			sqlGetUserByUsername:       fmt.Sprintf("SELECT password, display_name, email FROM %s WHERE username=$1", usersTableName),
+			sqlGetUserGroupsByUsername: fmt.Sprintf("SELECT group_name FROM %s WHERE username=$1 ORDER BY group_name", userGroupsTableName),
+			sqlUpdateUserPassword:      fmt.Sprintf("UPDATE %s SET password=$1 WHERE username=$2", usersTableName),
+			sqlInsertUser:              fmt.Sprintf("INSERT INTO %s (username, password, display_name, email) VALUES ($1, $2, $3, $4)", usersTableName),
+			sqlInsertUserGroup:         fmt.Sprintf("INSERT INTO %s (username, group_name) VALUES ($1, $2)", userGroupsTableName),
+
+			sqlInsertAuthenticationLog:              fmt.Sprintf("INSERT INTO %s (username, successful, auth_type, remote_ip, time) VALUES ($1, $2, $3, $4, $5)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:          fmt.Sprintf("SELECT successful, auth_type, remote_ip, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogsByNetwork: fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>$1 AND remote_ip BETWEEN $2 AND $3 ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:                fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>$1 ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteAuthenticationLogs:             fmt.Sprintf("DELETE FROM %s WHERE time<$1", authenticationLogsTableName),
 		},
 	}
 	if err := provider.initialize(db); err != nil {
@@ -1,28 +1,126 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"time"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/models"
 )
 
 // Provider is an interface providing storage capabilities for
 // persisting any kind of data related to Authelia.
 type Provider interface {
-	LoadPreferred2FAMethod(username string) (string, error)
-	SavePreferred2FAMethod(username string, method string) error
+	LoadPreferred2FAMethod(ctx context.Context, username string) (string, error)
+	SavePreferred2FAMethod(ctx context.Context, username string, method string) error
 
-	FindIdentityVerificationToken(token string) (bool, error)
-	SaveIdentityVerificationToken(token string) error
-	RemoveIdentityVerificationToken(token string) error
+	FindIdentityVerificationToken(ctx context.Context, token string) (bool, error)
+	SaveIdentityVerificationToken(ctx context.Context, token string, expiresAt time.Time) error
+	RemoveIdentityVerificationToken(ctx context.Context, token string) error
+	PruneExpiredIdentityVerificationTokens(ctx context.Context, expiredBefore time.Time) (int64, error)
 
-	SaveTOTPSecret(username string, secret string) error
-	LoadTOTPSecret(username string) (string, error)
-	DeleteTOTPSecret(username string) error
+	SaveTOTPConfiguration(ctx context.Context, configuration models.TOTPConfiguration) error
+	LoadTOTPConfigurations(ctx context.Context, username string) ([]models.TOTPConfiguration, error)
+	UpdateTOTPConfigurationSignIn(ctx context.Context, id int, step uint64, lastUsedAt time.Time) error
+	DeleteTOTPConfigurations(ctx context.Context, username string) error
 
-	SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error
-	LoadU2FDeviceHandle(username string) (keyHandle []byte, publicKey []byte, err error)
+	SaveU2FDevice(ctx context.Context, device models.U2FDevice) error
+	LoadU2FDevicesByUsername(ctx context.Context, username string) ([]models.U2FDevice, error)
+	UpdateU2FDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error
 
-	AppendAuthenticationLog(attempt models.AuthenticationAttempt) error
-	LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	SaveWebauthnDevice(ctx context.Context, device models.WebauthnDevice) error
+	LoadWebauthnDevicesByUsername(ctx context.Context, username string) ([]models.WebauthnDevice, error)
+	UpdateWebauthnDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error
+
+	SaveRecoveryCodes(ctx context.Context, username string, codes []models.RecoveryCode) error
+	LoadRecoveryCodes(ctx context.Context, username string) ([]models.RecoveryCode, error)
+	ConsumeRecoveryCode(ctx context.Context, id int, usedAt time.Time) error
+
+	AppendAuthenticationLog(ctx context.Context, attempt models.AuthenticationAttempt) error
+	LoadLatestAuthenticationLogs(ctx context.Context, username string, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	LoadLatestAuthenticationLogsByNetwork(ctx context.Context, network *net.IPNet, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	LoadAuthenticationLogs(ctx context.Context, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	PruneAuthenticationLogs(ctx context.Context, before time.Time) (int64, error)
+
+	UserDatabase
+}
+
+var errUnrecognizedStorageBackend = errors.New("Unrecognized storage backend")
+
+// NewProvider creates the storage provider of the configured backend whose schema is migrated to the latest version.
+func NewProvider(configuration schema.StorageConfiguration) (Provider, error) {
+	return newSQLProvider(configuration, true)
+}
+
+// Migrator is an interface providing the management of the versions of the schema of the database.
+type Migrator interface {
+	SchemaMigrations() []SchemaMigration
+	LatestSchemaVersion() int
+	SchemaVersion() (int, error)
+	MigrateSchemaUp(target int) error
+	MigrateSchemaDown(target int) error
+}
+
+// NewMigrator creates a migrator of the schema of the configured backend. Unlike the providers created by
+// NewProvider, the schema is left at its current version.
+func NewMigrator(configuration schema.StorageConfiguration) (Migrator, error) {
+	return newSQLProvider(configuration, false)
+}
+
+// DatasetProvider is an interface providing the export and the import of all the data of a storage backend.
+type DatasetProvider interface {
+	ExportDataset() (*Dataset, error)
+	ImportDataset(dataset *Dataset) error
+}
+
+// NewDatasetProvider creates a dataset provider of the configured backend whose schema is migrated to the
+// latest version.
+func NewDatasetProvider(configuration schema.StorageConfiguration) (DatasetProvider, error) {
+	return newSQLProvider(configuration, true)
+}
+
+// Encryptor is an interface providing the encryption of the secrets already stored in a storage backend.
+type Encryptor interface {
+	EncryptSecrets(previousEncryptionKey string) (int, error)
+}
+
+// NewEncryptor creates an encryptor of the secrets of the configured backend whose schema is migrated to the
+// latest version.
+func NewEncryptor(configuration schema.StorageConfiguration) (Encryptor, error) {
+	return newSQLProvider(configuration, true)
+}
+
+// UserDatabase is an interface providing the users stored in the tables of a storage backend. It is implemented by
+// the storage providers so that the SQL authentication backend shares their connections.
+type UserDatabase interface {
+	LoadUser(ctx context.Context, username string) (*models.User, error)
+	UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error
+}
+
+func newSQLProvider(configuration schema.StorageConfiguration, migrate bool) (*SQLProvider, error) {
+	var provider *SQLProvider
+
+	switch {
+	case configuration.PostgreSQL != nil:
+		provider = &newPostgreSQLProvider(*configuration.PostgreSQL).SQLProvider
+	case configuration.MySQL != nil:
+		provider = &newMySQLProvider(*configuration.MySQL).SQLProvider
+	case configuration.Local != nil:
+		provider = &newSQLiteProvider(configuration.Local.Path).SQLProvider
+	default:
+		return nil, errUnrecognizedStorageBackend
+	}
+
+	provider.encryptionKey = newEncryptionKey(configuration.EncryptionKey)
+
+	if migrate {
+		if err := provider.migrateToLatestSchemaVersion(); err != nil {
+			return nil, fmt.Errorf("Unable to initialize SQL database: %v", err)
+		}
+	}
+
+	return provider, nil
 }
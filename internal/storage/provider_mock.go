@@ -5,6 +5,8 @@
 package storage
 
 import (
+	context "context"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -37,175 +39,429 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 }
 
 // LoadPreferred2FAMethod mocks base method
-func (m *MockProvider) LoadPreferred2FAMethod(username string) (string, error) {
+func (m *MockProvider) LoadPreferred2FAMethod(ctx context.Context, username string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadPreferred2FAMethod", username)
+	ret := m.ctrl.Call(m, "LoadPreferred2FAMethod", ctx, username)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // LoadPreferred2FAMethod indicates an expected call of LoadPreferred2FAMethod
-func (mr *MockProviderMockRecorder) LoadPreferred2FAMethod(username interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) LoadPreferred2FAMethod(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPreferred2FAMethod", reflect.TypeOf((*MockProvider)(nil).LoadPreferred2FAMethod), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPreferred2FAMethod", reflect.TypeOf((*MockProvider)(nil).LoadPreferred2FAMethod), ctx, username)
 }
 
 // SavePreferred2FAMethod mocks base method
-func (m *MockProvider) SavePreferred2FAMethod(username, method string) error {
+func (m *MockProvider) SavePreferred2FAMethod(ctx context.Context, username, method string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SavePreferred2FAMethod", username, method)
+	ret := m.ctrl.Call(m, "SavePreferred2FAMethod", ctx, username, method)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SavePreferred2FAMethod indicates an expected call of SavePreferred2FAMethod
-func (mr *MockProviderMockRecorder) SavePreferred2FAMethod(username, method interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) SavePreferred2FAMethod(ctx, username, method interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePreferred2FAMethod", reflect.TypeOf((*MockProvider)(nil).SavePreferred2FAMethod), username, method)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePreferred2FAMethod", reflect.TypeOf((*MockProvider)(nil).SavePreferred2FAMethod), ctx, username, method)
 }
 
 // FindIdentityVerificationToken mocks base method
-func (m *MockProvider) FindIdentityVerificationToken(token string) (bool, error) {
+func (m *MockProvider) FindIdentityVerificationToken(ctx context.Context, token string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindIdentityVerificationToken", token)
+	ret := m.ctrl.Call(m, "FindIdentityVerificationToken", ctx, token)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindIdentityVerificationToken indicates an expected call of FindIdentityVerificationToken
-func (mr *MockProviderMockRecorder) FindIdentityVerificationToken(token interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) FindIdentityVerificationToken(ctx, token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).FindIdentityVerificationToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).FindIdentityVerificationToken), ctx, token)
 }
 
 // SaveIdentityVerificationToken mocks base method
-func (m *MockProvider) SaveIdentityVerificationToken(token string) error {
+func (m *MockProvider) SaveIdentityVerificationToken(ctx context.Context, token string, expiresAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveIdentityVerificationToken", token)
+	ret := m.ctrl.Call(m, "SaveIdentityVerificationToken", ctx, token, expiresAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveIdentityVerificationToken indicates an expected call of SaveIdentityVerificationToken
-func (mr *MockProviderMockRecorder) SaveIdentityVerificationToken(token interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) SaveIdentityVerificationToken(ctx, token, expiresAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).SaveIdentityVerificationToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).SaveIdentityVerificationToken), ctx, token, expiresAt)
 }
 
 // RemoveIdentityVerificationToken mocks base method
-func (m *MockProvider) RemoveIdentityVerificationToken(token string) error {
+func (m *MockProvider) RemoveIdentityVerificationToken(ctx context.Context, token string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveIdentityVerificationToken", token)
+	ret := m.ctrl.Call(m, "RemoveIdentityVerificationToken", ctx, token)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RemoveIdentityVerificationToken indicates an expected call of RemoveIdentityVerificationToken
-func (mr *MockProviderMockRecorder) RemoveIdentityVerificationToken(token interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) RemoveIdentityVerificationToken(ctx, token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).RemoveIdentityVerificationToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).RemoveIdentityVerificationToken), ctx, token)
 }
 
-// SaveTOTPSecret mocks base method
-func (m *MockProvider) SaveTOTPSecret(username, secret string) error {
+// PruneExpiredIdentityVerificationTokens mocks base method
+func (m *MockProvider) PruneExpiredIdentityVerificationTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveTOTPSecret", username, secret)
+	ret := m.ctrl.Call(m, "PruneExpiredIdentityVerificationTokens", ctx, expiredBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneExpiredIdentityVerificationTokens indicates an expected call of PruneExpiredIdentityVerificationTokens
+func (mr *MockProviderMockRecorder) PruneExpiredIdentityVerificationTokens(ctx, expiredBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneExpiredIdentityVerificationTokens", reflect.TypeOf((*MockProvider)(nil).PruneExpiredIdentityVerificationTokens), ctx, expiredBefore)
+}
+
+// SaveTOTPConfiguration mocks base method
+func (m *MockProvider) SaveTOTPConfiguration(ctx context.Context, configuration models.TOTPConfiguration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPConfiguration", ctx, configuration)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveTOTPSecret indicates an expected call of SaveTOTPSecret
-func (mr *MockProviderMockRecorder) SaveTOTPSecret(username, secret interface{}) *gomock.Call {
+// SaveTOTPConfiguration indicates an expected call of SaveTOTPConfiguration
+func (mr *MockProviderMockRecorder) SaveTOTPConfiguration(ctx, configuration interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockProvider)(nil).SaveTOTPSecret), username, secret)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPConfiguration", reflect.TypeOf((*MockProvider)(nil).SaveTOTPConfiguration), ctx, configuration)
 }
 
-// LoadTOTPSecret mocks base method
-func (m *MockProvider) LoadTOTPSecret(username string) (string, error) {
+// LoadTOTPConfigurations mocks base method
+func (m *MockProvider) LoadTOTPConfigurations(ctx context.Context, username string) ([]models.TOTPConfiguration, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadTOTPSecret", username)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "LoadTOTPConfigurations", ctx, username)
+	ret0, _ := ret[0].([]models.TOTPConfiguration)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// LoadTOTPSecret indicates an expected call of LoadTOTPSecret
-func (mr *MockProviderMockRecorder) LoadTOTPSecret(username interface{}) *gomock.Call {
+// LoadTOTPConfigurations indicates an expected call of LoadTOTPConfigurations
+func (mr *MockProviderMockRecorder) LoadTOTPConfigurations(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadTOTPSecret", reflect.TypeOf((*MockProvider)(nil).LoadTOTPSecret), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadTOTPConfigurations", reflect.TypeOf((*MockProvider)(nil).LoadTOTPConfigurations), ctx, username)
 }
 
-// DeleteTOTPSecret mocks base method
-func (m *MockProvider) DeleteTOTPSecret(username string) error {
+// UpdateTOTPConfigurationSignIn mocks base method
+func (m *MockProvider) UpdateTOTPConfigurationSignIn(ctx context.Context, id int, step uint64, lastUsedAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteTOTPSecret", username)
+	ret := m.ctrl.Call(m, "UpdateTOTPConfigurationSignIn", ctx, id, step, lastUsedAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteTOTPSecret indicates an expected call of DeleteTOTPSecret
-func (mr *MockProviderMockRecorder) DeleteTOTPSecret(username interface{}) *gomock.Call {
+// UpdateTOTPConfigurationSignIn indicates an expected call of UpdateTOTPConfigurationSignIn
+func (mr *MockProviderMockRecorder) UpdateTOTPConfigurationSignIn(ctx, id, step, lastUsedAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTPSecret", reflect.TypeOf((*MockProvider)(nil).DeleteTOTPSecret), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTOTPConfigurationSignIn", reflect.TypeOf((*MockProvider)(nil).UpdateTOTPConfigurationSignIn), ctx, id, step, lastUsedAt)
 }
 
-// SaveU2FDeviceHandle mocks base method
-func (m *MockProvider) SaveU2FDeviceHandle(username string, keyHandle, publicKey []byte) error {
+// DeleteTOTPConfigurations mocks base method
+func (m *MockProvider) DeleteTOTPConfigurations(ctx context.Context, username string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveU2FDeviceHandle", username, keyHandle, publicKey)
+	ret := m.ctrl.Call(m, "DeleteTOTPConfigurations", ctx, username)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveU2FDeviceHandle indicates an expected call of SaveU2FDeviceHandle
-func (mr *MockProviderMockRecorder) SaveU2FDeviceHandle(username, keyHandle, publicKey interface{}) *gomock.Call {
+// DeleteTOTPConfigurations indicates an expected call of DeleteTOTPConfigurations
+func (mr *MockProviderMockRecorder) DeleteTOTPConfigurations(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).SaveU2FDeviceHandle), username, keyHandle, publicKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTPConfigurations", reflect.TypeOf((*MockProvider)(nil).DeleteTOTPConfigurations), ctx, username)
 }
 
-// LoadU2FDeviceHandle mocks base method
-func (m *MockProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, error) {
+// SaveU2FDevice mocks base method
+func (m *MockProvider) SaveU2FDevice(ctx context.Context, device models.U2FDevice) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadU2FDeviceHandle", username)
-	ret0, _ := ret[0].([]byte)
-	ret1, _ := ret[1].([]byte)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "SaveU2FDevice", ctx, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveU2FDevice indicates an expected call of SaveU2FDevice
+func (mr *MockProviderMockRecorder) SaveU2FDevice(ctx, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveU2FDevice", reflect.TypeOf((*MockProvider)(nil).SaveU2FDevice), ctx, device)
+}
+
+// LoadU2FDevicesByUsername mocks base method
+func (m *MockProvider) LoadU2FDevicesByUsername(ctx context.Context, username string) ([]models.U2FDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadU2FDevicesByUsername", ctx, username)
+	ret0, _ := ret[0].([]models.U2FDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// LoadU2FDeviceHandle indicates an expected call of LoadU2FDeviceHandle
-func (mr *MockProviderMockRecorder) LoadU2FDeviceHandle(username interface{}) *gomock.Call {
+// LoadU2FDevicesByUsername indicates an expected call of LoadU2FDevicesByUsername
+func (mr *MockProviderMockRecorder) LoadU2FDevicesByUsername(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).LoadU2FDeviceHandle), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadU2FDevicesByUsername), ctx, username)
+}
+
+// UpdateU2FDeviceSignIn mocks base method
+func (m *MockProvider) UpdateU2FDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateU2FDeviceSignIn", ctx, id, signCount, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateU2FDeviceSignIn indicates an expected call of UpdateU2FDeviceSignIn
+func (mr *MockProviderMockRecorder) UpdateU2FDeviceSignIn(ctx, id, signCount, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateU2FDeviceSignIn", reflect.TypeOf((*MockProvider)(nil).UpdateU2FDeviceSignIn), ctx, id, signCount, lastUsedAt)
+}
+
+// SaveWebauthnDevice mocks base method
+func (m *MockProvider) SaveWebauthnDevice(ctx context.Context, device models.WebauthnDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebauthnDevice", ctx, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebauthnDevice indicates an expected call of SaveWebauthnDevice
+func (mr *MockProviderMockRecorder) SaveWebauthnDevice(ctx, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebauthnDevice", reflect.TypeOf((*MockProvider)(nil).SaveWebauthnDevice), ctx, device)
+}
+
+// LoadWebauthnDevicesByUsername mocks base method
+func (m *MockProvider) LoadWebauthnDevicesByUsername(ctx context.Context, username string) ([]models.WebauthnDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadWebauthnDevicesByUsername", ctx, username)
+	ret0, _ := ret[0].([]models.WebauthnDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadWebauthnDevicesByUsername indicates an expected call of LoadWebauthnDevicesByUsername
+func (mr *MockProviderMockRecorder) LoadWebauthnDevicesByUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadWebauthnDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadWebauthnDevicesByUsername), ctx, username)
+}
+
+// UpdateWebauthnDeviceSignIn mocks base method
+func (m *MockProvider) UpdateWebauthnDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebauthnDeviceSignIn", ctx, id, signCount, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebauthnDeviceSignIn indicates an expected call of UpdateWebauthnDeviceSignIn
+func (mr *MockProviderMockRecorder) UpdateWebauthnDeviceSignIn(ctx, id, signCount, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebauthnDeviceSignIn", reflect.TypeOf((*MockProvider)(nil).UpdateWebauthnDeviceSignIn), ctx, id, signCount, lastUsedAt)
+}
+
+// SaveRecoveryCodes mocks base method
+func (m *MockProvider) SaveRecoveryCodes(ctx context.Context, username string, codes []models.RecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRecoveryCodes", ctx, username, codes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRecoveryCodes indicates an expected call of SaveRecoveryCodes
+func (mr *MockProviderMockRecorder) SaveRecoveryCodes(ctx, username, codes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRecoveryCodes", reflect.TypeOf((*MockProvider)(nil).SaveRecoveryCodes), ctx, username, codes)
+}
+
+// LoadRecoveryCodes mocks base method
+func (m *MockProvider) LoadRecoveryCodes(ctx context.Context, username string) ([]models.RecoveryCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadRecoveryCodes", ctx, username)
+	ret0, _ := ret[0].([]models.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadRecoveryCodes indicates an expected call of LoadRecoveryCodes
+func (mr *MockProviderMockRecorder) LoadRecoveryCodes(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRecoveryCodes", reflect.TypeOf((*MockProvider)(nil).LoadRecoveryCodes), ctx, username)
+}
+
+// ConsumeRecoveryCode mocks base method
+func (m *MockProvider) ConsumeRecoveryCode(ctx context.Context, id int, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRecoveryCode", ctx, id, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeRecoveryCode indicates an expected call of ConsumeRecoveryCode
+func (mr *MockProviderMockRecorder) ConsumeRecoveryCode(ctx, id, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRecoveryCode", reflect.TypeOf((*MockProvider)(nil).ConsumeRecoveryCode), ctx, id, usedAt)
 }
 
 // AppendAuthenticationLog mocks base method
-func (m *MockProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
+func (m *MockProvider) AppendAuthenticationLog(ctx context.Context, attempt models.AuthenticationAttempt) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AppendAuthenticationLog", attempt)
+	ret := m.ctrl.Call(m, "AppendAuthenticationLog", ctx, attempt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AppendAuthenticationLog indicates an expected call of AppendAuthenticationLog
-func (mr *MockProviderMockRecorder) AppendAuthenticationLog(attempt interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) AppendAuthenticationLog(ctx, attempt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAuthenticationLog", reflect.TypeOf((*MockProvider)(nil).AppendAuthenticationLog), attempt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAuthenticationLog", reflect.TypeOf((*MockProvider)(nil).AppendAuthenticationLog), ctx, attempt)
 }
 
 // LoadLatestAuthenticationLogs mocks base method
-func (m *MockProvider) LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+func (m *MockProvider) LoadLatestAuthenticationLogs(ctx context.Context, username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadLatestAuthenticationLogs", username, fromDate)
+	ret := m.ctrl.Call(m, "LoadLatestAuthenticationLogs", ctx, username, fromDate)
 	ret0, _ := ret[0].([]models.AuthenticationAttempt)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // LoadLatestAuthenticationLogs indicates an expected call of LoadLatestAuthenticationLogs
-func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogs(username, fromDate interface{}) *gomock.Call {
+func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogs(ctx, username, fromDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogs), ctx, username, fromDate)
+}
+
+// LoadLatestAuthenticationLogsByNetwork mocks base method
+func (m *MockProvider) LoadLatestAuthenticationLogsByNetwork(ctx context.Context, network *net.IPNet, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLatestAuthenticationLogsByNetwork", ctx, network, fromDate)
+	ret0, _ := ret[0].([]models.AuthenticationAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLatestAuthenticationLogsByNetwork indicates an expected call of LoadLatestAuthenticationLogsByNetwork
+func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogsByNetwork(ctx, network, fromDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogsByNetwork", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogsByNetwork), ctx, network, fromDate)
+}
+
+// LoadAuthenticationLogs mocks base method
+func (m *MockProvider) LoadAuthenticationLogs(ctx context.Context, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadAuthenticationLogs", ctx, fromDate)
+	ret0, _ := ret[0].([]models.AuthenticationAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadAuthenticationLogs indicates an expected call of LoadAuthenticationLogs
+func (mr *MockProviderMockRecorder) LoadAuthenticationLogs(ctx, fromDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadAuthenticationLogs), ctx, fromDate)
+}
+
+// PruneAuthenticationLogs mocks base method
+func (m *MockProvider) PruneAuthenticationLogs(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneAuthenticationLogs", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneAuthenticationLogs indicates an expected call of PruneAuthenticationLogs
+func (mr *MockProviderMockRecorder) PruneAuthenticationLogs(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).PruneAuthenticationLogs), ctx, before)
+}
+
+// LoadUser mocks base method
+func (m *MockProvider) LoadUser(ctx context.Context, username string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadUser", ctx, username)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadUser indicates an expected call of LoadUser
+func (mr *MockProviderMockRecorder) LoadUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadUser", reflect.TypeOf((*MockProvider)(nil).LoadUser), ctx, username)
+}
+
+// UpdateUserPassword mocks base method
+func (m *MockProvider) UpdateUserPassword(ctx context.Context, username, hashedPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, username, hashedPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword
+func (mr *MockProviderMockRecorder) UpdateUserPassword(ctx, username, hashedPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockProvider)(nil).UpdateUserPassword), ctx, username, hashedPassword)
+}
+
+// MockUserDatabase is a mock of UserDatabase interface
+type MockUserDatabase struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserDatabaseMockRecorder
+}
+
+// MockUserDatabaseMockRecorder is the mock recorder for MockUserDatabase
+type MockUserDatabaseMockRecorder struct {
+	mock *MockUserDatabase
+}
+
+// NewMockUserDatabase creates a new mock instance
+func NewMockUserDatabase(ctrl *gomock.Controller) *MockUserDatabase {
+	mock := &MockUserDatabase{ctrl: ctrl}
+	mock.recorder = &MockUserDatabaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUserDatabase) EXPECT() *MockUserDatabaseMockRecorder {
+	return m.recorder
+}
+
+// LoadUser mocks base method
+func (m *MockUserDatabase) LoadUser(ctx context.Context, username string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadUser", ctx, username)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadUser indicates an expected call of LoadUser
+func (mr *MockUserDatabaseMockRecorder) LoadUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadUser", reflect.TypeOf((*MockUserDatabase)(nil).LoadUser), ctx, username)
+}
+
+// UpdateUserPassword mocks base method
+func (m *MockUserDatabase) UpdateUserPassword(ctx context.Context, username, hashedPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, username, hashedPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword
+func (mr *MockUserDatabaseMockRecorder) UpdateUserPassword(ctx, username, hashedPassword interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogs), username, fromDate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserDatabase)(nil).UpdateUserPassword), ctx, username, hashedPassword)
 }
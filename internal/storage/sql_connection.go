@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// configureConnectionPool applies the limits of the configuration to the pool of connections to the database.
+func configureConnectionPool(db *sql.DB, configuration schema.SQLStorageConfiguration) error {
+	if configuration.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(configuration.MaxOpenConnections)
+	}
+
+	// The driver keeps its default number of idle connections unless configured otherwise.
+	if configuration.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(configuration.MaxIdleConnections)
+	}
+
+	lifetime, err := utils.ParseDurationString(configuration.ConnectionMaxLifetime)
+	if err != nil {
+		return err
+	}
+
+	db.SetConnMaxLifetime(lifetime)
+
+	return nil
+}
+
+// newTLSConfig creates the TLS configuration of the connections to the database trusting the configured
+// certificate authorities in addition to the system ones and presenting the client certificate, if any.
+func newTLSConfig(configuration schema.SQLStorageTLSConfiguration, serverName string) (*tls.Config, error) {
+	certPool, err := x509.SystemCertPool()
+	if err != nil || certPool == nil {
+		certPool = x509.NewCertPool()
+	}
+
+	if configuration.TrustedCert != "" {
+		pem, err := ioutil.ReadFile(configuration.TrustedCert)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the trusted certificates: %v", err)
+		}
+
+		if ok := certPool.AppendCertsFromPEM(pem); !ok {
+			return nil, fmt.Errorf("Unable to import the trusted certificates of file %s", configuration.TrustedCert)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if configuration.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(configuration.Cert, configuration.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load the client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// context derives the context of a call to the provider bound by the query timeout, if any, in addition to the
+// deadline of the given context.
+func (p *SQLProvider) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(ctx, p.timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
@@ -1,9 +1,11 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/authelia/authelia/internal/models"
@@ -13,33 +15,111 @@ import (
 type SQLProvider struct {
 	db *sql.DB
 
+	// encryptionKey the key encrypting the secrets at rest, nil if no encryption key is configured.
+	encryptionKey *[32]byte
+
+	// timeout the deadline of each call to the provider, 0 if the calls are only bound by the deadline of their context.
+	timeout time.Duration
+
+	sqlCreateSchemaMigrationsTable           string
 	sqlCreateUserPreferencesTable            string
 	sqlCreateIdentityVerificationTokensTable string
-	sqlCreateTOTPSecretsTable                string
+	sqlCreateLegacyTOTPSecretsTable          string
+	sqlCreateTOTPConfigurationsTable         string
+	sqlCreateTOTPConfigurationsUsernameIndex string
+	sqlCreateLegacyU2FDevicesTable           string
 	sqlCreateU2FDeviceHandlesTable           string
+	sqlCreateU2FDeviceHandlesUsernameIndex   string
 	sqlCreateAuthenticationLogsTable         string
 	sqlCreateAuthenticationLogsUserTimeIndex string
+	sqlCreateAuthenticationLogsIPTimeIndex   string
+	sqlAddAuthenticationLogsRemoteIPColumn   string
+	sqlCreateWebauthnDevicesTable            string
+	sqlCreateWebauthnDevicesUsernameIndex    string
+	sqlCreateRecoveryCodesTable              string
+	sqlCreateRecoveryCodesUsernameIndex      string
 
 	sqlGetPreferencesByUsername     string
 	sqlUpsertSecondFactorPreference string
 
-	sqlTestIdentityVerificationTokenExistence string
-	sqlInsertIdentityVerificationToken        string
-	sqlDeleteIdentityVerificationToken        string
+	sqlTestIdentityVerificationTokenExistence        string
+	sqlInsertIdentityVerificationToken               string
+	sqlDeleteIdentityVerificationToken               string
+	sqlDeleteExpiredIdentityVerificationTokens       string
+	sqlSetLegacyIdentityVerificationTokensExpiration string
+
+	sqlGetTOTPConfigurationsByUsername string
+	sqlInsertTOTPConfiguration         string
+	sqlUpdateTOTPConfigurationSignIn   string
+	sqlDeleteTOTPConfigurations        string
+	sqlMigrateLegacyTOTPSecrets        string
+
+	sqlGetU2FDevicesByUsername string
+	sqlInsertU2FDevice         string
+	sqlUpdateU2FDeviceSignIn   string
+	sqlMigrateLegacyU2FDevices string
+
+	sqlGetWebauthnDevicesByUsername string
+	sqlInsertWebauthnDevice         string
+	sqlUpdateWebauthnDeviceSignIn   string
+
+	sqlGetUnusedRecoveryCodesByUsername string
+	sqlInsertRecoveryCode               string
+	sqlDeleteRecoveryCodes              string
+	sqlConsumeRecoveryCode              string
+
+	sqlImportTOTPConfiguration string
+	sqlImportU2FDevice         string
+	sqlImportWebauthnDevice    string
+	sqlImportRecoveryCode      string
+
+	sqlGetSchemaVersion      string
+	sqlInsertSchemaMigration string
+	sqlDeleteSchemaMigration string
+	sqlLockMigrations        string
+	sqlUnlockMigrations      string
+
+	sqlWidenTOTPSecretColumn  string
+	sqlNarrowTOTPSecretColumn string
+	sqlUpdateEncryptedValue   string
+
+	sqlCreateAuthenticationLogsTimeIndex             string
+	sqlDropAuthenticationLogsTimeIndex               string
+	sqlDropIdentityVerificationTokensExpiresAtColumn string
+
+	sqlDropU2FDeviceHandlesSignCountColumn string
+
+	sqlCreateUsersTable      string
+	sqlCreateUserGroupsTable string
+
+	sqlGetUserByUsername       string
+	sqlGetUserGroupsByUsername string
+	sqlUpdateUserPassword      string
+	sqlInsertUser              string
+	sqlInsertUserGroup         string
+
+	sqlInsertAuthenticationLog              string
+	sqlGetLatestAuthenticationLogs          string
+	sqlGetLatestAuthenticationLogsByNetwork string
+	sqlGetAuthenticationLogs                string
+	sqlDeleteAuthenticationLogs             string
+}
 
-	sqlGetTOTPSecretByUsername string
-	sqlUpsertTOTPSecret        string
-	sqlDeleteTOTPSecret        string
+func (p *SQLProvider) initialize(db *sql.DB) error {
+	p.db = db
 
-	sqlGetU2FDeviceHandleByUsername string
-	sqlUpsertU2FDeviceHandle        string
+	_, err := db.Exec(p.sqlCreateSchemaMigrationsTable)
+	if err != nil {
+		return fmt.Errorf("Unable to create table %s: %v", schemaMigrationsTableName, err)
+	}
 
-	sqlInsertAuthenticationLog     string
-	sqlGetLatestAuthenticationLogs string
+	return nil
 }
 
-func (p *SQLProvider) initialize(db *sql.DB) error {
-	p.db = db
+// createInitialSchema creates the tables of the first version of the schema. The databases created before the
+// schema was versioned are brought to this version as well, hence all the statements are idempotent.
+func (p *SQLProvider) createInitialSchema() error {
+	db := p.db
 
 	_, err := db.Exec(p.sqlCreateUserPreferencesTable)
 	if err != nil {
@@ -51,22 +131,67 @@ func (p *SQLProvider) initialize(db *sql.DB) error {
 		return fmt.Errorf("Unable to create table %s: %v", identityVerificationTokensTableName, err)
 	}
 
-	_, err = db.Exec(p.sqlCreateTOTPSecretsTable)
+	_, err = db.Exec(p.sqlCreateLegacyTOTPSecretsTable)
 	if err != nil {
-		return fmt.Errorf("Unable to create table %s: %v", totpSecretsTableName, err)
+		return fmt.Errorf("Unable to create table %s: %v", legacyTOTPSecretsTableName, err)
+	}
+
+	_, err = db.Exec(p.sqlCreateTOTPConfigurationsTable)
+	if err != nil {
+		return fmt.Errorf("Unable to create table %s: %v", totpConfigurationsTableName, err)
+	}
+
+	if p.sqlCreateTOTPConfigurationsUsernameIndex != "" {
+		_, err = db.Exec(p.sqlCreateTOTPConfigurationsUsernameIndex)
+		if err != nil {
+			return fmt.Errorf("Unable to create table %s: %v", totpConfigurationsTableName, err)
+		}
+	}
+
+	err = p.migrateLegacyTable(p.sqlMigrateLegacyTOTPSecrets, SQLDeleteLegacyTOTPSecrets)
+	if err != nil {
+		return fmt.Errorf("Unable to move the secrets of table %s to table %s: %v", legacyTOTPSecretsTableName, totpConfigurationsTableName, err)
 	}
 
 	// keyHandle and publicKey are stored in base64 format
+	_, err = db.Exec(p.sqlCreateLegacyU2FDevicesTable)
+	if err != nil {
+		return fmt.Errorf("Unable to create table %s: %v", legacyU2FDevicesTableName, err)
+	}
+
+	// key_handle and public_key are stored in base64 format.
 	_, err = db.Exec(p.sqlCreateU2FDeviceHandlesTable)
 	if err != nil {
 		return fmt.Errorf("Unable to create table %s: %v", u2fDeviceHandlesTableName, err)
 	}
 
+	if p.sqlCreateU2FDeviceHandlesUsernameIndex != "" {
+		_, err = db.Exec(p.sqlCreateU2FDeviceHandlesUsernameIndex)
+		if err != nil {
+			return fmt.Errorf("Unable to create table %s: %v", u2fDeviceHandlesTableName, err)
+		}
+	}
+
+	err = p.migrateLegacyTable(p.sqlMigrateLegacyU2FDevices, SQLDeleteLegacyU2FDevices)
+	if err != nil {
+		return fmt.Errorf("Unable to move the devices of table %s to table %s: %v", legacyU2FDevicesTableName, u2fDeviceHandlesTableName, err)
+	}
+
 	_, err = db.Exec(p.sqlCreateAuthenticationLogsTable)
 	if err != nil {
 		return fmt.Errorf("Unable to create table %s: %v", authenticationLogsTableName, err)
 	}
 
+	err = p.addColumnIfMissing(authenticationLogsTableName, "auth_type", SQLAddAuthenticationLogsAuthTypeColumn)
+	if err != nil {
+		return fmt.Errorf("Unable to add column auth_type to table %s: %v", authenticationLogsTableName, err)
+	}
+
+	err = p.addColumnIfMissing(authenticationLogsTableName, "remote_ip", p.sqlAddAuthenticationLogsRemoteIPColumn)
+	if err != nil {
+		return fmt.Errorf("Unable to add column remote_ip to table %s: %v", authenticationLogsTableName, err)
+	}
+
 	// Create an index on (username, time) because this couple is highly used by the regulation module
 	// to check whether a user is banned.
 	if p.sqlCreateAuthenticationLogsUserTimeIndex != "" {
@@ -76,14 +201,50 @@ func (p *SQLProvider) initialize(db *sql.DB) error {
 		}
 	}
 
+	// Create an index on (remote_ip, time) used by the regulation module to check whether a network is banned.
+	if p.sqlCreateAuthenticationLogsIPTimeIndex != "" {
+		_, err = db.Exec(p.sqlCreateAuthenticationLogsIPTimeIndex)
+		if err != nil {
+			return fmt.Errorf("Unable to create table %s: %v", authenticationLogsTableName, err)
+		}
+	}
+
+	// kid, publicKey and aaguid are stored in base64 format.
+	_, err = db.Exec(p.sqlCreateWebauthnDevicesTable)
+	if err != nil {
+		return fmt.Errorf("Unable to create table %s: %v", webauthnDevicesTableName, err)
+	}
+
+	if p.sqlCreateWebauthnDevicesUsernameIndex != "" {
+		_, err = db.Exec(p.sqlCreateWebauthnDevicesUsernameIndex)
+		if err != nil {
+			return fmt.Errorf("Unable to create table %s: %v", webauthnDevicesTableName, err)
+		}
+	}
+
+	_, err = db.Exec(p.sqlCreateRecoveryCodesTable)
+	if err != nil {
+		return fmt.Errorf("Unable to create table %s: %v", recoveryCodesTableName, err)
+	}
+
+	if p.sqlCreateRecoveryCodesUsernameIndex != "" {
+		_, err = db.Exec(p.sqlCreateRecoveryCodesUsernameIndex)
+		if err != nil {
+			return fmt.Errorf("Unable to create table %s: %v", recoveryCodesTableName, err)
+		}
+	}
+
 	return nil
 }
 
 // LoadPreferred2FAMethod load the preferred method for 2FA from sqlite db.
-func (p *SQLProvider) LoadPreferred2FAMethod(username string) (string, error) {
+func (p *SQLProvider) LoadPreferred2FAMethod(ctx context.Context, username string) (string, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
 	var method string
 
-	rows, err := p.db.Query(p.sqlGetPreferencesByUsername, username)
+	rows, err := p.db.QueryContext(ctx, p.sqlGetPreferencesByUsername, username)
 	if err != nil {
 		return "", err
 	}
@@ -99,16 +260,22 @@ func (p *SQLProvider) LoadPreferred2FAMethod(username string) (string, error) {
 }
 
 // SavePreferred2FAMethod save the preferred method for 2FA in sqlite db.
-func (p *SQLProvider) SavePreferred2FAMethod(username string, method string) error {
-	_, err := p.db.Exec(p.sqlUpsertSecondFactorPreference, username, method)
+func (p *SQLProvider) SavePreferred2FAMethod(ctx context.Context, username string, method string) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlUpsertSecondFactorPreference, username, method)
 	return err
 }
 
 // FindIdentityVerificationToken look for an identity verification token in DB.
-func (p *SQLProvider) FindIdentityVerificationToken(token string) (bool, error) {
+func (p *SQLProvider) FindIdentityVerificationToken(ctx context.Context, token string) (bool, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
 	var found bool
 
-	err := p.db.QueryRow(p.sqlTestIdentityVerificationTokenExistence, token).Scan(&found)
+	err := p.db.QueryRowContext(ctx, p.sqlTestIdentityVerificationTokenExistence, token).Scan(&found)
 	if err != nil {
 		return false, err
 	}
@@ -116,104 +283,494 @@ func (p *SQLProvider) FindIdentityVerificationToken(token string) (bool, error)
 	return found, nil
 }
 
-// SaveIdentityVerificationToken save an identity verification token in DB.
-func (p *SQLProvider) SaveIdentityVerificationToken(token string) error {
-	_, err := p.db.Exec(p.sqlInsertIdentityVerificationToken, token)
+// SaveIdentityVerificationToken save an identity verification token in DB along with its expiration time.
+func (p *SQLProvider) SaveIdentityVerificationToken(ctx context.Context, token string, expiresAt time.Time) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlInsertIdentityVerificationToken, token, expiresAt.Unix())
 	return err
 }
 
 // RemoveIdentityVerificationToken remove an identity verification token from the DB.
-func (p *SQLProvider) RemoveIdentityVerificationToken(token string) error {
-	_, err := p.db.Exec(p.sqlDeleteIdentityVerificationToken, token)
+func (p *SQLProvider) RemoveIdentityVerificationToken(ctx context.Context, token string) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlDeleteIdentityVerificationToken, token)
 	return err
 }
 
-// SaveTOTPSecret save a TOTP secret of a given user.
-func (p *SQLProvider) SaveTOTPSecret(username string, secret string) error {
-	_, err := p.db.Exec(p.sqlUpsertTOTPSecret, username, secret)
+// SaveTOTPConfiguration save a TOTP configuration of a given user.
+func (p *SQLProvider) SaveTOTPConfiguration(ctx context.Context, configuration models.TOTPConfiguration) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	secret, err := p.encrypt(configuration.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, p.sqlInsertTOTPConfiguration,
+		configuration.Username,
+		configuration.Description,
+		secret,
+		configuration.Algorithm,
+		configuration.Digits,
+		configuration.Period,
+		configuration.CreatedAt.Unix())
+
 	return err
 }
 
-// LoadTOTPSecret load a TOTP secret given a username.
-func (p *SQLProvider) LoadTOTPSecret(username string) (string, error) {
-	var secret string
-	if err := p.db.QueryRow(p.sqlGetTOTPSecretByUsername, username).Scan(&secret); err != nil {
-		if err == sql.ErrNoRows {
-			return "", ErrNoTOTPSecret
+// LoadTOTPConfigurations load all the TOTP configurations of a given user.
+func (p *SQLProvider) LoadTOTPConfigurations(ctx context.Context, username string) ([]models.TOTPConfiguration, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetTOTPConfigurationsByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configurations := make([]models.TOTPConfiguration, 0, 2)
+
+	for rows.Next() {
+		var (
+			createdAt  int64
+			lastUsedAt sql.NullInt64
+		)
+
+		configuration := models.TOTPConfiguration{
+			Username: username,
 		}
 
-		return "", err
+		err = rows.Scan(&configuration.ID, &configuration.Description, &configuration.Secret,
+			&configuration.Algorithm, &configuration.Digits, &configuration.Period, &createdAt, &lastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if configuration.Secret, err = p.decrypt(configuration.Secret); err != nil {
+			return nil, err
+		}
+
+		configuration.CreatedAt = time.Unix(createdAt, 0)
+
+		if lastUsedAt.Valid {
+			t := time.Unix(lastUsedAt.Int64, 0)
+			configuration.LastUsedAt = &t
+		}
+
+		configurations = append(configurations, configuration)
+	}
+
+	if len(configurations) == 0 {
+		return nil, ErrNoTOTPSecret
+	}
+
+	return configurations, nil
+}
+
+// UpdateTOTPConfigurationSignIn record the time-step and the time of the latest sign in with a TOTP configuration.
+// The time-step is only recorded if it is later than the last accepted one so that concurrent requests, possibly
+// handled by different instances, cannot use the same passcode twice.
+func (p *SQLProvider) UpdateTOTPConfigurationSignIn(ctx context.Context, id int, step uint64, lastUsedAt time.Time) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, p.sqlUpdateTOTPConfigurationSignIn, int64(step), lastUsedAt.Unix(), id, int64(step))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrTOTPStepAlreadyUsed
+	}
+
+	return nil
+}
+
+// PruneExpiredIdentityVerificationTokens delete the identity verification tokens which expired before the given
+// time and return the number of deleted tokens.
+func (p *SQLProvider) PruneExpiredIdentityVerificationTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, p.sqlDeleteExpiredIdentityVerificationTokens, expiredBefore.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteTOTPConfigurations delete all the TOTP configurations of a given username.
+func (p *SQLProvider) DeleteTOTPConfigurations(ctx context.Context, username string) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlDeleteTOTPConfigurations, username)
+	return err
+}
+
+// migrateLegacyTable move the rows of a table used when a single device per user was supported to the table
+// replacing it and empty the legacy table within a single transaction.
+func (p *SQLProvider) migrateLegacyTable(migrateQuery, deleteQuery string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(migrateQuery, time.Now().Unix())
+	if err != nil {
+		tx.Rollback() //nolint:errcheck // The error of the migration is more relevant.
+		return err
+	}
+
+	_, err = tx.Exec(deleteQuery)
+	if err != nil {
+		tx.Rollback() //nolint:errcheck // The error of the migration is more relevant.
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addColumnIfMissing add a column to a table created by a previous version of Authelia with the given alteration.
+// Several instances might try to add the column concurrently, hence the column is looked up again when the
+// alteration fails.
+func (p *SQLProvider) addColumnIfMissing(table, column, alterQuery string) error {
+	query := fmt.Sprintf("SELECT %s FROM %s LIMIT 1", column, table)
+
+	rows, err := p.db.Query(query)
+	if err == nil {
+		return rows.Close()
+	}
+
+	_, err = p.db.Exec(alterQuery)
+	if err == nil {
+		return nil
+	}
+
+	rows, lookupErr := p.db.Query(query)
+	if lookupErr != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// SaveU2FDevice save a registered U2F device.
+func (p *SQLProvider) SaveU2FDevice(ctx context.Context, device models.U2FDevice) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	publicKey, err := p.encrypt(base64.StdEncoding.EncodeToString(device.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, p.sqlInsertU2FDevice,
+		device.Username,
+		device.Description,
+		base64.StdEncoding.EncodeToString(device.KeyHandle),
+		publicKey,
+		device.CreatedAt.Unix())
+
+	return err
+}
+
+// LoadU2FDevicesByUsername load all the U2F devices registered by a given username.
+func (p *SQLProvider) LoadU2FDevicesByUsername(ctx context.Context, username string) ([]models.U2FDevice, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetU2FDevicesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.U2FDevice, 0, 2)
+
+	for rows.Next() {
+		var (
+			keyHandleBase64, publicKeyBase64 string
+			createdAt                        int64
+			lastUsedAt                       sql.NullInt64
+		)
+
+		device := models.U2FDevice{
+			Username: username,
+		}
+
+		err = rows.Scan(&device.ID, &device.Description, &keyHandleBase64, &publicKeyBase64, &device.SignCount, &createdAt, &lastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if device.KeyHandle, err = base64.StdEncoding.DecodeString(keyHandleBase64); err != nil {
+			return nil, err
+		}
+
+		if publicKeyBase64, err = p.decrypt(publicKeyBase64); err != nil {
+			return nil, err
+		}
+
+		if device.PublicKey, err = base64.StdEncoding.DecodeString(publicKeyBase64); err != nil {
+			return nil, err
+		}
+
+		device.CreatedAt = time.Unix(createdAt, 0)
+
+		if lastUsedAt.Valid {
+			t := time.Unix(lastUsedAt.Int64, 0)
+			device.LastUsedAt = &t
+		}
+
+		devices = append(devices, device)
 	}
 
-	return secret, nil
+	if len(devices) == 0 {
+		return nil, ErrNoU2FDeviceHandle
+	}
+
+	return devices, nil
 }
 
-// DeleteTOTPSecret delete a TOTP secret given a username.
-func (p *SQLProvider) DeleteTOTPSecret(username string) error {
-	_, err := p.db.Exec(p.sqlDeleteTOTPSecret, username)
+// UpdateU2FDeviceSignIn record the signature counter and the time of the latest sign in of a U2F device.
+func (p *SQLProvider) UpdateU2FDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlUpdateU2FDeviceSignIn, signCount, lastUsedAt.Unix(), id)
 	return err
 }
 
-// SaveU2FDeviceHandle save a registered U2F device registration blob.
-func (p *SQLProvider) SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error {
-	_, err := p.db.Exec(p.sqlUpsertU2FDeviceHandle,
-		username,
-		base64.StdEncoding.EncodeToString(keyHandle),
-		base64.StdEncoding.EncodeToString(publicKey))
+// SaveWebauthnDevice save a registered WebAuthn device.
+func (p *SQLProvider) SaveWebauthnDevice(ctx context.Context, device models.WebauthnDevice) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	publicKey, err := p.encrypt(base64.StdEncoding.EncodeToString(device.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, p.sqlInsertWebauthnDevice,
+		device.Username,
+		device.Description,
+		base64.StdEncoding.EncodeToString(device.KID),
+		publicKey,
+		device.AttestationType,
+		base64.StdEncoding.EncodeToString(device.AAGUID),
+		device.SignCount,
+		device.CreatedAt.Unix())
 
 	return err
 }
 
-// LoadU2FDeviceHandle load a U2F device registration blob for a given username.
-func (p *SQLProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, error) {
-	var keyHandleBase64, publicKeyBase64 string
-	if err := p.db.QueryRow(p.sqlGetU2FDeviceHandleByUsername, username).Scan(&keyHandleBase64, &publicKeyBase64); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil, ErrNoU2FDeviceHandle
+// LoadWebauthnDevicesByUsername load all the WebAuthn devices registered by a given username.
+func (p *SQLProvider) LoadWebauthnDevicesByUsername(ctx context.Context, username string) ([]models.WebauthnDevice, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetWebauthnDevicesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.WebauthnDevice, 0, 2)
+
+	for rows.Next() {
+		var (
+			kidBase64, publicKeyBase64, aaguidBase64 string
+			createdAt                                int64
+			lastUsedAt                               sql.NullInt64
+		)
+
+		device := models.WebauthnDevice{
+			Username: username,
+		}
+
+		err = rows.Scan(&device.ID, &device.Description, &kidBase64, &publicKeyBase64,
+			&device.AttestationType, &aaguidBase64, &device.SignCount, &createdAt, &lastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if device.KID, err = base64.StdEncoding.DecodeString(kidBase64); err != nil {
+			return nil, err
+		}
+
+		if publicKeyBase64, err = p.decrypt(publicKeyBase64); err != nil {
+			return nil, err
+		}
+
+		if device.PublicKey, err = base64.StdEncoding.DecodeString(publicKeyBase64); err != nil {
+			return nil, err
+		}
+
+		if device.AAGUID, err = base64.StdEncoding.DecodeString(aaguidBase64); err != nil {
+			return nil, err
 		}
 
-		return nil, nil, err
+		device.CreatedAt = time.Unix(createdAt, 0)
+
+		if lastUsedAt.Valid {
+			t := time.Unix(lastUsedAt.Int64, 0)
+			device.LastUsedAt = &t
+		}
+
+		devices = append(devices, device)
+	}
+
+	if len(devices) == 0 {
+		return nil, ErrNoWebauthnDevice
 	}
 
-	keyHandle, err := base64.StdEncoding.DecodeString(keyHandleBase64)
+	return devices, nil
+}
+
+// UpdateWebauthnDeviceSignIn record the signature counter and the time of the latest sign in of a WebAuthn device.
+func (p *SQLProvider) UpdateWebauthnDeviceSignIn(ctx context.Context, id int, signCount uint32, lastUsedAt time.Time) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlUpdateWebauthnDeviceSignIn, signCount, lastUsedAt.Unix(), id)
+	return err
+}
+
+// SaveRecoveryCodes replace the recovery codes of a given user.
+func (p *SQLProvider) SaveRecoveryCodes(ctx context.Context, username string, codes []models.RecoveryCode) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
 
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
 
-	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	_, err = tx.ExecContext(ctx, p.sqlDeleteRecoveryCodes, username)
+	if err != nil {
+		tx.Rollback() //nolint:errcheck // The error of the query is more relevant.
+		return err
+	}
 
+	for _, code := range codes {
+		_, err = tx.ExecContext(ctx, p.sqlInsertRecoveryCode, username, code.Hash, code.CreatedAt.Unix())
+		if err != nil {
+			tx.Rollback() //nolint:errcheck // The error of the query is more relevant.
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadRecoveryCodes load the recovery codes of a given user which have not been used yet.
+func (p *SQLProvider) LoadRecoveryCodes(ctx context.Context, username string) ([]models.RecoveryCode, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetUnusedRecoveryCodesByUsername, username)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	defer rows.Close()
+
+	codes := make([]models.RecoveryCode, 0, 10)
+
+	for rows.Next() {
+		var createdAt int64
+
+		code := models.RecoveryCode{
+			Username: username,
+		}
 
-	return keyHandle, publicKey, nil
+		err = rows.Scan(&code.ID, &code.Hash, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		code.CreatedAt = time.Unix(createdAt, 0)
+		codes = append(codes, code)
+	}
+
+	if len(codes) == 0 {
+		return nil, ErrNoRecoveryCode
+	}
+
+	return codes, nil
+}
+
+// ConsumeRecoveryCode mark a recovery code as used. The code is only consumed if it has not been used
+// yet so that concurrent requests cannot use the same code twice.
+func (p *SQLProvider) ConsumeRecoveryCode(ctx context.Context, id int, usedAt time.Time) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, p.sqlConsumeRecoveryCode, usedAt.Unix(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrRecoveryCodeAlreadyUsed
+	}
+
+	return nil
 }
 
 // AppendAuthenticationLog append a mark to the authentication log.
-func (p *SQLProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
-	_, err := p.db.Exec(p.sqlInsertAuthenticationLog, attempt.Username, attempt.Successful, attempt.Time.Unix())
+func (p *SQLProvider) AppendAuthenticationLog(ctx context.Context, attempt models.AuthenticationAttempt) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, p.sqlInsertAuthenticationLog, attempt.Username, attempt.Successful, attempt.Type,
+		encodeIP(attempt.RemoteIP), attempt.Time.Unix())
+
 	return err
 }
 
 // LoadLatestAuthenticationLogs retrieve the latest marks from the authentication log.
-func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+func (p *SQLProvider) LoadLatestAuthenticationLogs(ctx context.Context, username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
 	var t int64
 
-	rows, err := p.db.Query(p.sqlGetLatestAuthenticationLogs, fromDate.Unix(), username)
+	rows, err := p.db.QueryContext(ctx, p.sqlGetLatestAuthenticationLogs, fromDate.Unix(), username)
 
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	attempts := make([]models.AuthenticationAttempt, 0, 10)
 
 	for rows.Next() {
+		var remoteIP sql.NullString
+
 		attempt := models.AuthenticationAttempt{
 			Username: username,
 		}
-		err = rows.Scan(&attempt.Successful, &t)
+		err = rows.Scan(&attempt.Successful, &attempt.Type, &remoteIP, &t)
 		attempt.Time = time.Unix(t, 0)
+		attempt.RemoteIP = decodeIP(remoteIP)
 
 		if err != nil {
 			return nil, err
@@ -222,5 +779,73 @@ func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate tim
 		attempts = append(attempts, attempt)
 	}
 
-	return attempts, nil
+	return attempts, rows.Err()
+}
+
+// LoadLatestAuthenticationLogsByNetwork retrieve the latest marks of the attempts made from a network.
+func (p *SQLProvider) LoadLatestAuthenticationLogsByNetwork(ctx context.Context, network *net.IPNet, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	first, last := encodeNetwork(network)
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetLatestAuthenticationLogsByNetwork, fromDate.Unix(), first, last)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuthenticationLogs(rows)
+}
+
+// LoadAuthenticationLogs retrieve the marks of all the attempts made since the given date.
+func (p *SQLProvider) LoadAuthenticationLogs(ctx context.Context, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetAuthenticationLogs, fromDate.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuthenticationLogs(rows)
+}
+
+// PruneAuthenticationLogs delete the marks of the attempts made before the given date and return the number of
+// deleted marks.
+func (p *SQLProvider) PruneAuthenticationLogs(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, p.sqlDeleteAuthenticationLogs, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func scanAuthenticationLogs(rows *sql.Rows) ([]models.AuthenticationAttempt, error) {
+	attempts := make([]models.AuthenticationAttempt, 0, 10)
+
+	for rows.Next() {
+		var (
+			attempt  models.AuthenticationAttempt
+			remoteIP sql.NullString
+			t        int64
+		)
+
+		err := rows.Scan(&attempt.Username, &attempt.Successful, &attempt.Type, &remoteIP, &t)
+		if err != nil {
+			return nil, err
+		}
+
+		attempt.RemoteIP = decodeIP(remoteIP)
+		attempt.Time = time.Unix(t, 0)
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
 }
@@ -14,8 +14,18 @@ type SQLiteProvider struct {
 	SQLProvider
 }
 
-// NewSQLiteProvider constructs a SQLite provider.
+// NewSQLiteProvider constructs a SQLite provider whose schema is migrated to the latest version.
 func NewSQLiteProvider(path string) *SQLiteProvider {
+	provider := newSQLiteProvider(path)
+
+	if err := provider.migrateToLatestSchemaVersion(); err != nil {
+		logging.Logger().Fatalf("Unable to initialize SQLite database %s: %s", path, err)
+	}
+
+	return provider
+}
+
+func newSQLiteProvider(path string) *SQLiteProvider {
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		logging.Logger().Fatalf("Unable to create SQLite database %s: %s", path, err)
@@ -23,29 +33,86 @@ func NewSQLiteProvider(path string) *SQLiteProvider {
 
 	provider := SQLiteProvider{
 		SQLProvider{
+			sqlCreateSchemaMigrationsTable:           SQLCreateSchemaMigrationsTable,
 			sqlCreateUserPreferencesTable:            SQLCreateUserPreferencesTable,
 			sqlCreateIdentityVerificationTokensTable: SQLCreateIdentityVerificationTokensTable,
-			sqlCreateTOTPSecretsTable:                SQLCreateTOTPSecretsTable,
-			sqlCreateU2FDeviceHandlesTable:           SQLCreateU2FDeviceHandlesTable,
-			sqlCreateAuthenticationLogsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100), successful BOOL, time INTEGER)", authenticationLogsTableName),
+			sqlCreateLegacyTOTPSecretsTable:          SQLCreateLegacyTOTPSecretsTable,
+			sqlCreateTOTPConfigurationsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, secret VARCHAR(255) NOT NULL, algorithm VARCHAR(6) NOT NULL, digits INTEGER NOT NULL, period INTEGER NOT NULL, last_step BIGINT, created_at INTEGER NOT NULL, last_used_at INTEGER)", totpConfigurationsTableName),
+			sqlCreateTOTPConfigurationsUsernameIndex: fmt.Sprintf("CREATE INDEX IF NOT EXISTS totp_usr_idx ON %s (username)", totpConfigurationsTableName),
+			sqlCreateLegacyU2FDevicesTable:           SQLCreateLegacyU2FDevicesTable,
+			sqlCreateU2FDeviceHandlesTable:           fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, key_handle TEXT NOT NULL, public_key TEXT NOT NULL, created_at INTEGER NOT NULL, last_used_at INTEGER)", u2fDeviceHandlesTableName),
+			sqlCreateU2FDeviceHandlesUsernameIndex:   fmt.Sprintf("CREATE INDEX IF NOT EXISTS u2f_usr_idx ON %s (username)", u2fDeviceHandlesTableName),
+			sqlCreateAuthenticationLogsTable:         fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100), successful BOOL, auth_type VARCHAR(32) NOT NULL DEFAULT '1FA', remote_ip VARCHAR(32), time INTEGER)", authenticationLogsTableName),
 			sqlCreateAuthenticationLogsUserTimeIndex: fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s (username, time)", authenticationLogsTableName),
+			sqlCreateAuthenticationLogsIPTimeIndex:   fmt.Sprintf("CREATE INDEX IF NOT EXISTS ip_time_idx ON %s (remote_ip, time)", authenticationLogsTableName),
+			sqlAddAuthenticationLogsRemoteIPColumn:   SQLAddAuthenticationLogsRemoteIPColumn,
+			sqlCreateWebauthnDevicesTable:            fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(100) NOT NULL, description VARCHAR(30) NOT NULL, kid VARCHAR(512) NOT NULL, public_key TEXT NOT NULL, attestation_type VARCHAR(32) NOT NULL, aaguid VARCHAR(64) NOT NULL, sign_count BIGINT NOT NULL, created_at INTEGER NOT NULL, last_used_at INTEGER)", webauthnDevicesTableName),
+			sqlCreateWebauthnDevicesUsernameIndex:    fmt.Sprintf("CREATE INDEX IF NOT EXISTS webauthn_usr_idx ON %s (username)", webauthnDevicesTableName),
+			sqlCreateRecoveryCodesTable:              fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(100) NOT NULL, code_hash VARCHAR(255) NOT NULL, created_at INTEGER NOT NULL, used_at INTEGER)", recoveryCodesTableName),
+			sqlCreateRecoveryCodesUsernameIndex:      fmt.Sprintf("CREATE INDEX IF NOT EXISTS recovery_usr_idx ON %s (username)", recoveryCodesTableName),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", preferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", preferencesTableName),
 
-			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
-			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
-			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
+			sqlTestIdentityVerificationTokenExistence:        fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
+			sqlInsertIdentityVerificationToken:               fmt.Sprintf("INSERT INTO %s (token, expires_at) VALUES (?, ?)", identityVerificationTokensTableName),
+			sqlDeleteIdentityVerificationToken:               fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
+			sqlDeleteExpiredIdentityVerificationTokens:       fmt.Sprintf("DELETE FROM %s WHERE expires_at<?", identityVerificationTokensTableName),
+			sqlSetLegacyIdentityVerificationTokensExpiration: fmt.Sprintf("UPDATE %s SET expires_at=? WHERE expires_at IS NULL", identityVerificationTokensTableName),
+
+			sqlGetTOTPConfigurationsByUsername: fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", totpConfigurationsTableName),
+			sqlInsertTOTPConfiguration:         fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)", totpConfigurationsTableName),
+			sqlUpdateTOTPConfigurationSignIn:   fmt.Sprintf("UPDATE %s SET last_step=?, last_used_at=? WHERE id=? AND (last_step IS NULL OR last_step < ?)", totpConfigurationsTableName),
+			sqlDeleteTOTPConfigurations:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpConfigurationsTableName),
+			sqlMigrateLegacyTOTPSecrets:        fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, created_at) SELECT username, 'Mobile', secret, 'sha1', 6, 0, ? FROM %s", totpConfigurationsTableName, legacyTOTPSecretsTableName),
+
+			sqlGetU2FDevicesByUsername: fmt.Sprintf("SELECT id, description, key_handle, public_key, sign_count, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", u2fDeviceHandlesTableName),
+			sqlInsertU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) VALUES (?, ?, ?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlUpdateU2FDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=?, last_used_at=? WHERE id=?", u2fDeviceHandlesTableName),
+			sqlMigrateLegacyU2FDevices: fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, created_at) SELECT username, 'Security Key', keyHandle, publicKey, ? FROM %s", u2fDeviceHandlesTableName, legacyU2FDevicesTableName),
+
+			sqlGetWebauthnDevicesByUsername: fmt.Sprintf("SELECT id, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at FROM %s WHERE username=? ORDER BY id", webauthnDevicesTableName),
+			sqlInsertWebauthnDevice:         fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", webauthnDevicesTableName),
+			sqlUpdateWebauthnDeviceSignIn:   fmt.Sprintf("UPDATE %s SET sign_count=?, last_used_at=? WHERE id=?", webauthnDevicesTableName),
+
+			sqlGetUnusedRecoveryCodesByUsername: fmt.Sprintf("SELECT id, code_hash, created_at FROM %s WHERE username=? AND used_at IS NULL ORDER BY id", recoveryCodesTableName),
+			sqlInsertRecoveryCode:               fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at) VALUES (?, ?, ?)", recoveryCodesTableName),
+			sqlDeleteRecoveryCodes:              fmt.Sprintf("DELETE FROM %s WHERE username=?", recoveryCodesTableName),
+			sqlConsumeRecoveryCode:              fmt.Sprintf("UPDATE %s SET used_at=? WHERE id=? AND used_at IS NULL", recoveryCodesTableName),
+
+			sqlImportTOTPConfiguration: fmt.Sprintf("INSERT INTO %s (username, description, secret, algorithm, digits, period, last_step, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", totpConfigurationsTableName),
+			sqlImportU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, sign_count, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlImportWebauthnDevice:    fmt.Sprintf("INSERT INTO %s (username, description, kid, public_key, attestation_type, aaguid, sign_count, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", webauthnDevicesTableName),
+			sqlImportRecoveryCode:      fmt.Sprintf("INSERT INTO %s (username, code_hash, created_at, used_at) VALUES (?, ?, ?, ?)", recoveryCodesTableName),
+
+			sqlGetSchemaVersion:      SQLGetSchemaVersion,
+			sqlInsertSchemaMigration: fmt.Sprintf("INSERT INTO %s (version, applied_at) VALUES (?, ?)", schemaMigrationsTableName),
+			sqlDeleteSchemaMigration: fmt.Sprintf("DELETE FROM %s WHERE version=?", schemaMigrationsTableName),
+			// The SQLite database is local to a single instance, hence the migrations are not locked.
+
+			// SQLite does not enforce the length of the columns, hence the column of the TOTP secrets is not widened.
+			sqlUpdateEncryptedValue: "UPDATE %s SET %s=? WHERE id=?",
+
+			sqlCreateAuthenticationLogsTimeIndex: fmt.Sprintf("CREATE INDEX IF NOT EXISTS time_idx ON %s (time)", authenticationLogsTableName),
+			sqlDropAuthenticationLogsTimeIndex:   "DROP INDEX IF EXISTS time_idx",
+			// The version of SQLite bundled with the driver does not support dropping columns, hence the expires_at
+			// column of the identity verification tokens is left in place when the migration is reverted, as is the
+			// sign_count column of the U2F devices.
 
-			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
-			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
-			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
+			sqlCreateUsersTable:      SQLCreateUsersTable,
+			sqlCreateUserGroupsTable: SQLCreateUserGroupsTable,
 
-			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
-			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlGetUserByUsername:       fmt.Sprintf("SELECT password, display_name, email FROM %s WHERE username=?", usersTableName),
+			sqlGetUserGroupsByUsername: fmt.Sprintf("SELECT group_name FROM %s WHERE username=? ORDER BY group_name", userGroupsTableName),
+			sqlUpdateUserPassword:      fmt.Sprintf("UPDATE %s SET password=? WHERE username=?", usersTableName),
+			sqlInsertUser:              fmt.Sprintf("INSERT INTO %s (username, password, display_name, email) VALUES (?, ?, ?, ?)", usersTableName),
+			sqlInsertUserGroup:         fmt.Sprintf("INSERT INTO %s (username, group_name) VALUES (?, ?)", userGroupsTableName),
 
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlInsertAuthenticationLog:              fmt.Sprintf("INSERT INTO %s (username, successful, auth_type, remote_ip, time) VALUES (?, ?, ?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:          fmt.Sprintf("SELECT successful, auth_type, remote_ip, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogsByNetwork: fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>? AND remote_ip BETWEEN ? AND ? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:                fmt.Sprintf("SELECT username, successful, auth_type, remote_ip, time FROM %s WHERE time>? ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteAuthenticationLogs:             fmt.Sprintf("DELETE FROM %s WHERE time<?", authenticationLogsTableName),
 		},
 	}
 	if err := provider.initialize(db); err != nil {
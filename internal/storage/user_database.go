@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/authelia/authelia/internal/models"
+)
+
+// LoadUser load a user of the SQL authentication backend along with the groups the user belongs to.
+func (p *SQLProvider) LoadUser(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	var email sql.NullString
+
+	user := &models.User{
+		Username: username,
+	}
+
+	err := p.db.QueryRowContext(ctx, p.sqlGetUserByUsername, username).Scan(&user.HashedPassword, &user.DisplayName, &email)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoUser
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Email = email.String
+
+	rows, err := p.db.QueryContext(ctx, p.sqlGetUserGroupsByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group string
+
+		if err := rows.Scan(&group); err != nil {
+			return nil, err
+		}
+
+		user.Groups = append(user.Groups, group)
+	}
+
+	return user, rows.Err()
+}
+
+// UpdateUserPassword replace the hash of the password of a user of the SQL authentication backend.
+func (p *SQLProvider) UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error {
+	ctx, cancel := p.context(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, p.sqlUpdateUserPassword, hashedPassword, username)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoUser
+	}
+
+	return nil
+}
@@ -30,8 +30,19 @@ func (wds *WebDriverSession) doEnterOTP(ctx context.Context, t *testing.T, code
 	}
 }
 
+// lastTOTPSteps records the time-step of the last passcode entered for each secret since
+// Authelia rejects passcodes of a time-step which has already been used.
+var lastTOTPSteps = map[string]int64{}
+
 func (wds *WebDriverSession) doValidateTOTP(ctx context.Context, t *testing.T, secret string) {
-	code, err := totp.GenerateCode(secret, time.Now())
+	if last, ok := lastTOTPSteps[secret]; ok && time.Now().Unix()/30 <= last {
+		time.Sleep(time.Until(time.Unix((last+1)*30, 0)))
+	}
+
+	now := time.Now()
+	lastTOTPSteps[secret] = now.Unix() / 30
+
+	code, err := totp.GenerateCode(secret, now)
 	assert.NoError(t, err)
 	wds.doEnterOTP(ctx, t, code)
 }
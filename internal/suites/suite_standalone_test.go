@@ -73,9 +73,9 @@ func (s *StandaloneWebDriverSuite) TestShouldCheckUserIsAskedToRegisterDevice()
 	username := "john"
 	password := "password"
 
-	// Clean up any TOTP secret already in DB.
+	// Clean up any TOTP configuration already in DB.
 	provider := storage.NewSQLiteProvider("/tmp/db.sqlite3")
-	require.NoError(s.T(), provider.DeleteTOTPSecret(username))
+	require.NoError(s.T(), provider.DeleteTOTPConfigurations(username))
 
 	// Login one factor.
 	s.doLoginOneFactor(ctx, s.T(), username, password, false, "")
@@ -6,10 +6,11 @@ import ResetPasswordStep1 from './views/ResetPassword/ResetPasswordStep1';
 import ResetPasswordStep2 from './views/ResetPassword/ResetPasswordStep2';
 import RegisterSecurityKey from './views/DeviceRegistration/RegisterSecurityKey';
 import RegisterOneTimePassword from './views/DeviceRegistration/RegisterOneTimePassword';
+import GenerateRecoveryCodes from './views/DeviceRegistration/GenerateRecoveryCodes';
 import {
     FirstFactorRoute, ResetPasswordStep2Route,
     ResetPasswordStep1Route, RegisterSecurityKeyRoute,
-    RegisterOneTimePasswordRoute,
+    RegisterOneTimePasswordRoute, GenerateRecoveryCodesRoute,
     LogoutRoute,
 } from "./Routes";
 import LoginPortal from './views/LoginPortal/LoginPortal';
@@ -44,6 +45,9 @@ const App: React.FC = () => {
                     <Route path={RegisterOneTimePasswordRoute} exact>
                         <RegisterOneTimePassword />
                     </Route>
+                    <Route path={GenerateRecoveryCodesRoute} exact>
+                        <GenerateRecoveryCodes />
+                    </Route>
                     <Route path={LogoutRoute} exact>
                         <SignOut />
                     </Route>
@@ -6,9 +6,11 @@ export const SecondFactorRoute = "/2fa";
 export const SecondFactorU2FRoute = "/2fa/security-key";
 export const SecondFactorTOTPRoute = "/2fa/one-time-password";
 export const SecondFactorPushRoute = "/2fa/push-notification";
+export const SecondFactorRecoveryCodeRoute = "/2fa/recovery-code";
 
 export const ResetPasswordStep1Route = "/reset-password/step1";
 export const ResetPasswordStep2Route = "/reset-password/step2";
-export const RegisterSecurityKeyRoute = "/security-key/register";
+export const RegisterSecurityKeyRoute = "/webauthn/register";
 export const RegisterOneTimePasswordRoute = "/one-time-password/register";
+export const GenerateRecoveryCodesRoute = "/recovery-codes/generate";
 export const LogoutRoute = "/logout";
\ No newline at end of file
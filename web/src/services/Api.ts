@@ -7,12 +7,16 @@ export const FirstFactorPath = basePath + "/api/firstfactor";
 export const InitiateTOTPRegistrationPath = basePath + "/api/secondfactor/totp/identity/start";
 export const CompleteTOTPRegistrationPath = basePath + "/api/secondfactor/totp/identity/finish";
 
-export const InitiateU2FRegistrationPath = basePath + "/api/secondfactor/u2f/identity/start";
-export const CompleteU2FRegistrationStep1Path = basePath + "/api/secondfactor/u2f/identity/finish";
-export const CompleteU2FRegistrationStep2Path = basePath + "/api/secondfactor/u2f/register";
+export const InitiateWebauthnRegistrationPath = basePath + "/api/secondfactor/webauthn/identity/start";
+export const CompleteWebauthnRegistrationStep1Path = basePath + "/api/secondfactor/webauthn/identity/finish";
+export const CompleteWebauthnRegistrationStep2Path = basePath + "/api/secondfactor/webauthn/attestation";
 
-export const InitiateU2FSignInPath = basePath + "/api/secondfactor/u2f/sign_request";
-export const CompleteU2FSignInPath = basePath + "/api/secondfactor/u2f/sign";
+// The challenge is requested with a GET and the assertion is sent back with a POST.
+export const WebauthnAssertionPath = basePath + "/api/secondfactor/webauthn/assertion";
+
+export const InitiateRecoveryCodesGenerationPath = basePath + "/api/secondfactor/recovery_codes/identity/start";
+export const CompleteRecoveryCodesGenerationPath = basePath + "/api/secondfactor/recovery_codes/identity/finish";
+export const CompleteRecoveryCodeSignInPath = basePath + "/api/secondfactor/recovery_code";
 
 export const CompletePushNotificationSignInPath = basePath + "/api/secondfactor/duo"
 export const CompleteTOTPSignInPath = basePath + "/api/secondfactor/totp"
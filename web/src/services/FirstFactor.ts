@@ -22,4 +22,12 @@ export async function postFirstFactor(
     }
     const res = await PostWithOptionalResponse<SignInResponse>(FirstFactorPath, data);
     return res ? res : {} as SignInResponse;
-}
\ No newline at end of file
+}
+
+// PasswordExpiredMessage is the message replied when the password is correct but must be reset.
+const PasswordExpiredMessage = "Your password has expired, please reset it.";
+
+export function isPasswordExpiredError(err: any) {
+    return !!err && !!err.response && !!err.response.data &&
+        err.response.data.message === PasswordExpiredMessage;
+}
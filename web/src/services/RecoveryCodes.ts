@@ -0,0 +1,33 @@
+import { Post, PostWithOptionalResponse } from "./Client";
+import {
+    InitiateRecoveryCodesGenerationPath, CompleteRecoveryCodesGenerationPath,
+    CompleteRecoveryCodeSignInPath
+} from "./Api";
+import { SignInResponse } from "./SignIn";
+
+export async function initiateRecoveryCodesGenerationProcess() {
+    await PostWithOptionalResponse(InitiateRecoveryCodesGenerationPath);
+}
+
+interface CompleteRecoveryCodesGenerationResponse {
+    codes: string[];
+}
+
+// completeRecoveryCodesGenerationProcess generates new recovery codes, the codes generated previously are revoked.
+export async function completeRecoveryCodesGenerationProcess(processToken: string) {
+    return Post<CompleteRecoveryCodesGenerationResponse>(
+        CompleteRecoveryCodesGenerationPath, { token: processToken });
+}
+
+interface CompleteRecoveryCodeSignInBody {
+    code: string;
+    targetURL?: string;
+}
+
+export function completeRecoveryCodeSignIn(code: string, targetURL: string | undefined) {
+    const body: CompleteRecoveryCodeSignInBody = { code };
+    if (targetURL) {
+        body.targetURL = targetURL;
+    }
+    return PostWithOptionalResponse<SignInResponse>(CompleteRecoveryCodeSignInPath, body);
+}
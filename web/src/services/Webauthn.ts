@@ -0,0 +1,197 @@
+import { Get, Post, PostWithOptionalResponse } from "./Client";
+import {
+    InitiateWebauthnRegistrationPath, CompleteWebauthnRegistrationStep1Path,
+    CompleteWebauthnRegistrationStep2Path, WebauthnAssertionPath
+} from "./Api";
+import { SignInResponse } from "./SignIn";
+
+// The binary fields of the options are encoded in base64, with either the standard or the URL alphabet.
+interface CredentialDescriptorJSON {
+    type: PublicKeyCredentialType;
+    id: string;
+    transports?: AuthenticatorTransport[];
+}
+
+interface PublicKeyCredentialCreationOptionsJSON {
+    rp: PublicKeyCredentialRpEntity;
+    user: {
+        id: string;
+        name: string;
+        displayName: string;
+    };
+    challenge: string;
+    pubKeyCredParams: PublicKeyCredentialParameters[];
+    timeout?: number;
+    excludeCredentials?: CredentialDescriptorJSON[];
+    authenticatorSelection?: AuthenticatorSelectionCriteria;
+    attestation?: AttestationConveyancePreference;
+    extensions?: AuthenticationExtensionsClientInputs;
+}
+
+interface PublicKeyCredentialRequestOptionsJSON {
+    challenge: string;
+    timeout?: number;
+    rpId?: string;
+    allowCredentials?: CredentialDescriptorJSON[];
+    userVerification?: UserVerificationRequirement;
+    extensions?: AuthenticationExtensionsClientInputs;
+}
+
+interface CredentialCreationResponse {
+    publicKey: PublicKeyCredentialCreationOptionsJSON;
+}
+
+interface CredentialAssertionResponse {
+    publicKey: PublicKeyCredentialRequestOptionsJSON;
+}
+
+export function isWebauthnSupported() {
+    return window.PublicKeyCredential !== undefined
+        && navigator.credentials !== undefined
+        && typeof navigator.credentials.create === "function";
+}
+
+function decodeBase64(value: string) {
+    let base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+    while (base64.length % 4 !== 0) {
+        base64 += "=";
+    }
+
+    const binary = atob(base64);
+    const bytes = new Uint8Array(binary.length);
+    for (let i = 0; i < binary.length; i++) {
+        bytes[i] = binary.charCodeAt(i);
+    }
+    return bytes.buffer;
+}
+
+// The server expects the binary fields of the credentials to be encoded in base64 with the URL alphabet and
+// without padding.
+function encodeBase64URL(value: ArrayBuffer) {
+    const bytes = new Uint8Array(value);
+    let binary = "";
+    for (let i = 0; i < bytes.length; i++) {
+        binary += String.fromCharCode(bytes[i]);
+    }
+
+    return btoa(binary).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function decodeCredentialDescriptors(descriptors?: CredentialDescriptorJSON[]) {
+    if (!descriptors) {
+        return undefined;
+    }
+
+    return descriptors.map(d => ({ ...d, id: decodeBase64(d.id) } as PublicKeyCredentialDescriptor));
+}
+
+function decodeCreationOptions(options: PublicKeyCredentialCreationOptionsJSON): PublicKeyCredentialCreationOptions {
+    return {
+        ...options,
+        challenge: decodeBase64(options.challenge),
+        user: { ...options.user, id: decodeBase64(options.user.id) },
+        excludeCredentials: decodeCredentialDescriptors(options.excludeCredentials),
+    };
+}
+
+function decodeRequestOptions(options: PublicKeyCredentialRequestOptionsJSON): PublicKeyCredentialRequestOptions {
+    return {
+        ...options,
+        challenge: decodeBase64(options.challenge),
+        allowCredentials: decodeCredentialDescriptors(options.allowCredentials),
+    };
+}
+
+export async function initiateWebauthnRegistrationProcess() {
+    return PostWithOptionalResponse(InitiateWebauthnRegistrationPath);
+}
+
+interface AttestationBody {
+    id: string;
+    rawId: string;
+    type: string;
+    response: {
+        clientDataJSON: string;
+        attestationObject: string;
+    };
+    description?: string;
+}
+
+// performAttestationCeremony registers a new authenticator. The identity verification token proves the user has
+// access to their mailbox.
+export async function performAttestationCeremony(processToken: string, description?: string) {
+    const res = await Post<CredentialCreationResponse>(
+        CompleteWebauthnRegistrationStep1Path, { token: processToken });
+
+    const credential = await navigator.credentials.create({
+        publicKey: decodeCreationOptions(res.publicKey),
+    }) as PublicKeyCredential | null;
+    if (!credential) {
+        throw new Error("No credential has been created by the authenticator");
+    }
+
+    const response = credential.response as AuthenticatorAttestationResponse;
+    const body: AttestationBody = {
+        id: credential.id,
+        rawId: encodeBase64URL(credential.rawId),
+        type: credential.type,
+        response: {
+            clientDataJSON: encodeBase64URL(response.clientDataJSON),
+            attestationObject: encodeBase64URL(response.attestationObject),
+        },
+    };
+    if (description) {
+        body.description = description;
+    }
+    return PostWithOptionalResponse(CompleteWebauthnRegistrationStep2Path, body);
+}
+
+interface AssertionBody {
+    id: string;
+    rawId: string;
+    type: string;
+    response: {
+        clientDataJSON: string;
+        authenticatorData: string;
+        signature: string;
+        userHandle?: string;
+    };
+    clientExtensionResults: AuthenticationExtensionsClientOutputs;
+    targetURL?: string;
+}
+
+// initiateWebauthnSignIn asks one of the authenticators of the user to sign the challenge of the server. The keys
+// registered with the legacy U2F endpoints are found thanks to the appid extension requested by the server.
+export async function initiateWebauthnSignIn() {
+    const res = await Get<CredentialAssertionResponse>(WebauthnAssertionPath);
+
+    const credential = await navigator.credentials.get({
+        publicKey: decodeRequestOptions(res.publicKey),
+    }) as PublicKeyCredential | null;
+    if (!credential) {
+        throw new Error("No credential has been returned by the authenticator");
+    }
+    return credential;
+}
+
+export function completeWebauthnSignIn(credential: PublicKeyCredential, targetURL: string | undefined) {
+    const response = credential.response as AuthenticatorAssertionResponse;
+    const body: AssertionBody = {
+        id: credential.id,
+        rawId: encodeBase64URL(credential.rawId),
+        type: credential.type,
+        response: {
+            clientDataJSON: encodeBase64URL(response.clientDataJSON),
+            authenticatorData: encodeBase64URL(response.authenticatorData),
+            signature: encodeBase64URL(response.signature),
+        },
+        clientExtensionResults: credential.getClientExtensionResults(),
+    };
+    if (response.userHandle) {
+        body.response.userHandle = encodeBase64URL(response.userHandle);
+    }
+    if (targetURL) {
+        body.targetURL = targetURL;
+    }
+    return PostWithOptionalResponse<SignInResponse>(WebauthnAssertionPath, body);
+}
@@ -0,0 +1,93 @@
+import React, { useEffect, useCallback, useState } from "react";
+import LoginLayout from "../../layouts/LoginLayout";
+import { makeStyles, Typography, Button, CircularProgress, Grid } from "@material-ui/core";
+import { useHistory, useLocation } from "react-router";
+import { completeRecoveryCodesGenerationProcess } from "../../services/RecoveryCodes";
+import { useNotifications } from "../../hooks/NotificationsContext";
+import { extractIdentityToken } from "../../utils/IdentityToken";
+import { FirstFactorRoute } from "../../Routes";
+
+export default function () {
+    const style = useStyles();
+    const history = useHistory();
+    const location = useLocation();
+
+    // The codes are only displayed once, the server only keeps a hash of each of them.
+    const [codes, setCodes] = useState([] as string[]);
+    const { createErrorNotification } = useNotifications();
+    const [hasErrored, setHasErrored] = useState(false);
+    const [isLoading, setIsLoading] = useState(false);
+
+    const processToken = extractIdentityToken(location.search);
+
+    const handleDoneClick = () => {
+        history.push(FirstFactorRoute);
+    }
+
+    const completeGenerationProcess = useCallback(async () => {
+        if (!processToken) {
+            return;
+        }
+
+        setIsLoading(true);
+        try {
+            const res = await completeRecoveryCodesGenerationProcess(processToken);
+            setCodes(res.codes);
+        } catch (err) {
+            console.error(err);
+            createErrorNotification("Failed to generate your recovery codes. " +
+                "The identity verification process might have timed out.", 10000);
+            setHasErrored(true);
+        }
+        setIsLoading(false);
+    }, [processToken, createErrorNotification]);
+
+    useEffect(() => { completeGenerationProcess() }, [completeGenerationProcess]);
+
+    return (
+        <LoginLayout title="Recovery Codes" id="generate-recovery-codes-stage">
+            <div className={style.root}>
+                {isLoading ? <CircularProgress size={64} /> : null}
+                {!isLoading && !hasErrored
+                    ? <Typography className={style.instruction}>
+                        Keep these codes in a safe place, they will not be displayed again.
+                        Each code can be used once to pass the second factor.
+                    </Typography>
+                    : null}
+                <Grid container spacing={1} id="recovery-codes">
+                    {codes.map(code => (
+                        <Grid item xs={6} key={code}>
+                            <Typography className={style.code}>{code}</Typography>
+                        </Grid>
+                    ))}
+                </Grid>
+                <Button
+                    id="done-button"
+                    variant="contained"
+                    color="primary"
+                    className={style.doneButton}
+                    onClick={handleDoneClick}>
+                    Done
+                </Button>
+            </div>
+        </LoginLayout>
+    )
+}
+
+const useStyles = makeStyles(theme => ({
+    root: {
+        paddingTop: theme.spacing(4),
+        paddingBottom: theme.spacing(4),
+    },
+    instruction: {
+        paddingBottom: theme.spacing(2),
+    },
+    code: {
+        fontFamily: "monospace",
+        fontSize: "1.1em",
+    },
+    doneButton: {
+        marginTop: theme.spacing(4),
+        width: "120px",
+    },
+}))
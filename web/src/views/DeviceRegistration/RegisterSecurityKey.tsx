@@ -5,9 +5,8 @@ import { makeStyles, Typography, Button } from "@material-ui/core";
 import { useHistory, useLocation } from "react-router";
 import { FirstFactorPath } from "../../services/Api";
 import { extractIdentityToken } from "../../utils/IdentityToken";
-import { completeU2FRegistrationProcessStep1, completeU2FRegistrationProcessStep2 } from "../../services/RegisterDevice";
+import { performAttestationCeremony } from "../../services/Webauthn";
 import { useNotifications } from "../../hooks/NotificationsContext";
-import u2fApi from "u2f-api";
 
 export default function () {
     const style = useStyles();
@@ -29,18 +28,7 @@ export default function () {
         }
         try {
             setRegistrationInProgress(true);
-            const res = await completeU2FRegistrationProcessStep1(processToken);
-            const registerRequests: u2fApi.RegisterRequest[] = [];
-            for (var i in res.registerRequests) {
-                const r = res.registerRequests[i];
-                registerRequests.push({
-                    appId: res.appId,
-                    challenge: r.challenge,
-                    version: r.version,
-                })
-            }
-            const registerResponse = await u2fApi.register(registerRequests, [], 60);
-            await completeU2FRegistrationProcessStep2(registerResponse);
+            await performAttestationCeremony(processToken);
             setRegistrationInProgress(false);
             history.push(FirstFactorPath);
         } catch (err) {
@@ -4,7 +4,7 @@ import { makeStyles, Grid, Button, FormControlLabel, Checkbox, Link } from "@mat
 import { useHistory } from "react-router";
 import LoginLayout from "../../../layouts/LoginLayout";
 import { useNotifications } from "../../../hooks/NotificationsContext";
-import { postFirstFactor } from "../../../services/FirstFactor";
+import { postFirstFactor, isPasswordExpiredError } from "../../../services/FirstFactor";
 import { ResetPasswordStep1Route } from "../../../Routes";
 import { useRedirectionURL } from "../../../hooks/RedirectionURL";
 import FixedTextField from "../../../components/FixedTextField";
@@ -62,6 +62,17 @@ export default function (props: Props) {
             props.onAuthenticationSuccess(res ? res.redirect : undefined);
         } catch (err) {
             console.error(err);
+            if (isPasswordExpiredError(err)) {
+                props.onAuthenticationFailure();
+                setPassword("");
+                if (props.resetPassword) {
+                    createErrorNotification("Your password has expired, please reset it.");
+                    history.push(ResetPasswordStep1Route);
+                } else {
+                    createErrorNotification("Your password has expired, please contact your administrator.");
+                }
+                return;
+            }
             createErrorNotification(
                 "Incorrect username or password.");
             props.onAuthenticationFailure();
@@ -17,6 +17,8 @@ export interface Props {
     state: State;
     children: ReactNode;
 
+    // The text of the link initiating the registration, "Not registered yet?" by default.
+    registerLinkText?: string;
     onRegisterClick?: () => void;
 }
 
@@ -55,7 +57,7 @@ export default function (props: Props) {
                 ? <Link component="button"
                     id="register-link"
                     onClick={props.onRegisterClick}>
-                    Not registered yet?
+                    {props.registerLinkText ? props.registerLinkText : "Not registered yet?"}
                 </Link>
                 : null}
         </div>
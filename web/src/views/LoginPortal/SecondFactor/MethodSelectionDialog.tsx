@@ -8,7 +8,7 @@ import FingerTouchIcon from "../../../components/FingerTouchIcon";
 export interface Props {
     open: boolean;
     methods: Set<SecondFactorMethod>;
-    u2fSupported: boolean;
+    webauthnSupported: boolean;
 
     onClose: () => void;
     onClick: (method: SecondFactorMethod) => void;
@@ -35,7 +35,7 @@ export default function (props: Props) {
                             icon={pieChartIcon}
                             onClick={() => props.onClick(SecondFactorMethod.TOTP)} />
                         : null}
-                    {props.methods.has(SecondFactorMethod.U2F) && props.u2fSupported
+                    {props.methods.has(SecondFactorMethod.U2F) && props.webauthnSupported
                         ? <MethodItem
                             id="security-key-option"
                             method="Security Key"
@@ -0,0 +1,107 @@
+import React, { useState, useCallback } from "react";
+import MethodContainer, { State as MethodContainerState } from "./MethodContainer";
+import { makeStyles, Button } from "@material-ui/core";
+import FixedTextField from "../../../components/FixedTextField";
+import { completeRecoveryCodeSignIn } from "../../../services/RecoveryCodes";
+import { useRedirectionURL } from "../../../hooks/RedirectionURL";
+import { AuthenticationLevel } from "../../../services/State";
+
+export interface Props {
+    id: string;
+    authenticationLevel: AuthenticationLevel;
+
+    onGenerateClick: () => void;
+    onSignInError: (err: Error) => void;
+    onSignInSuccess: (redirectURL: string | undefined) => void;
+}
+
+export default function (props: Props) {
+    const style = useStyles();
+    const [code, setCode] = useState("");
+    const [error, setError] = useState(false);
+    const [inProgress, setInProgress] = useState(false);
+    const redirectionURL = useRedirectionURL();
+
+    const { onSignInSuccess, onSignInError } = props;
+    const onSignInErrorCallback = useCallback(onSignInError, []);
+    const onSignInSuccessCallback = useCallback(onSignInSuccess, []);
+
+    const signIn = async () => {
+        if (inProgress || props.authenticationLevel === AuthenticationLevel.TwoFactor) {
+            return;
+        }
+
+        const trimmedCode = code.trim();
+        if (trimmedCode === "") {
+            setError(true);
+            return;
+        }
+
+        setInProgress(true);
+        try {
+            const res = await completeRecoveryCodeSignIn(trimmedCode, redirectionURL);
+            onSignInSuccessCallback(res ? res.redirect : undefined);
+        } catch (err) {
+            console.error(err);
+            onSignInErrorCallback(new Error("The recovery code might be wrong or already used"));
+            setError(true);
+        }
+        setCode("");
+        setInProgress(false);
+    }
+
+    const methodState = props.authenticationLevel === AuthenticationLevel.TwoFactor
+        ? MethodContainerState.ALREADY_AUTHENTICATED
+        : MethodContainerState.METHOD;
+
+    return (
+        <MethodContainer
+            id={props.id}
+            title="Recovery Code"
+            explanation="Enter one of your unused recovery codes"
+            state={methodState}
+            registerLinkText="Generate new recovery codes"
+            onRegisterClick={props.onGenerateClick}>
+            <div className={style.form}>
+                <FixedTextField
+                    id="recovery-code-textfield"
+                    label="Recovery code"
+                    variant="outlined"
+                    fullWidth
+                    autoComplete="off"
+                    error={error}
+                    disabled={inProgress}
+                    value={code}
+                    onChange={(e) => {
+                        setCode(e.target.value);
+                        setError(false);
+                    }}
+                    onKeyPress={(ev) => {
+                        if (ev.key === 'Enter') {
+                            signIn();
+                            ev.preventDefault();
+                        }
+                    }} />
+                <Button
+                    id="recovery-code-sign-in-button"
+                    variant="contained"
+                    color="primary"
+                    fullWidth
+                    className={style.button}
+                    disabled={inProgress}
+                    onClick={signIn}>
+                    Sign in
+                </Button>
+            </div>
+        </MethodContainer>
+    )
+}
+
+const useStyles = makeStyles(theme => ({
+    form: {
+        width: "100%",
+    },
+    button: {
+        marginTop: theme.spacing(2),
+    },
+}))
@@ -1,25 +1,25 @@
-import React, { useState, useEffect } from "react";
-import { Grid, makeStyles, Button } from "@material-ui/core";
+import React, { useState } from "react";
+import { Grid, makeStyles, Button, Link } from "@material-ui/core";
 import MethodSelectionDialog from "./MethodSelectionDialog";
 import { SecondFactorMethod } from "../../../models/Methods";
-import { useHistory, Switch, Route, Redirect } from "react-router";
+import { useHistory, useLocation, Switch, Route, Redirect } from "react-router";
 import LoginLayout from "../../../layouts/LoginLayout";
 import { useNotifications } from "../../../hooks/NotificationsContext";
-import {
-    initiateTOTPRegistrationProcess,
-    initiateU2FRegistrationProcess
-} from "../../../services/RegisterDevice";
+import { initiateTOTPRegistrationProcess } from "../../../services/RegisterDevice";
+import { initiateWebauthnRegistrationProcess, isWebauthnSupported } from "../../../services/Webauthn";
+import { initiateRecoveryCodesGenerationProcess } from "../../../services/RecoveryCodes";
 import SecurityKeyMethod from "./SecurityKeyMethod";
 import OneTimePasswordMethod from "./OneTimePasswordMethod";
 import PushNotificationMethod from "./PushNotificationMethod";
+import RecoveryCodeMethod from "./RecoveryCodeMethod";
 import {
     LogoutRoute as SignOutRoute, SecondFactorTOTPRoute,
-    SecondFactorPushRoute, SecondFactorU2FRoute, SecondFactorRoute
+    SecondFactorPushRoute, SecondFactorU2FRoute, SecondFactorRecoveryCodeRoute,
+    SecondFactorRoute
 } from "../../../Routes";
 import { setPreferred2FAMethod } from "../../../services/UserPreferences";
 import { UserInfo } from "../../../models/UserInfo";
 import { Configuration } from "../../../models/Configuration";
-import u2fApi from "u2f-api";
 import { AuthenticationLevel } from "../../../services/State";
 
 const EMAIL_SENT_NOTIFICATION = "An email has been sent to your address to complete the process.";
@@ -37,17 +37,11 @@ export interface Props {
 export default function (props: Props) {
     const style = useStyles();
     const history = useHistory();
+    const location = useLocation();
     const [methodSelectionOpen, setMethodSelectionOpen] = useState(false);
     const { createInfoNotification, createErrorNotification } = useNotifications();
     const [registrationInProgress, setRegistrationInProgress] = useState(false);
-    const [u2fSupported, setU2fSupported] = useState(false);
-
-    // Check that U2F is supported.
-    useEffect(() => {
-        u2fApi.ensureSupport().then(
-            () => setU2fSupported(true),
-            () => console.error("U2F not supported"));
-    }, [setU2fSupported]);
+    const webauthnSupported = isWebauthnSupported();
 
     const initiateRegistration = (initiateRegistrationFunc: () => Promise<void>) => {
         return async () => {
@@ -81,6 +75,11 @@ export default function (props: Props) {
         }
     }
 
+    // The redirection URL is kept so that the user is redirected once authenticated with a recovery code.
+    const handleRecoveryCodeClick = () => {
+        history.push(`${SecondFactorRecoveryCodeRoute}${location.search}`);
+    }
+
     const handleLogoutClick = () => {
         history.push(SignOutRoute);
     }
@@ -93,7 +92,7 @@ export default function (props: Props) {
             <MethodSelectionDialog
                 open={methodSelectionOpen}
                 methods={props.configuration.available_methods}
-                u2fSupported={u2fSupported}
+                webauthnSupported={webauthnSupported}
                 onClose={() => setMethodSelectionOpen(false)}
                 onClick={handleMethodSelected} />
             <Grid container>
@@ -123,9 +122,10 @@ export default function (props: Props) {
                             <SecurityKeyMethod
                                 id="security-key-method"
                                 authenticationLevel={props.authenticationLevel}
-                                // Whether the user has a U2F device registered already
-                                registered={props.userInfo.has_u2f}
-                                onRegisterClick={initiateRegistration(initiateU2FRegistrationProcess)}
+                                // Whether the user has a security key registered already, the keys registered
+                                // with U2F are used with Webauthn as well
+                                registered={props.userInfo.has_webauthn || props.userInfo.has_u2f}
+                                onRegisterClick={initiateRegistration(initiateWebauthnRegistrationProcess)}
                                 onSignInError={err => createErrorNotification(err.message)}
                                 onSignInSuccess={props.onAuthenticationSuccess} />
                         </Route>
@@ -136,11 +136,26 @@ export default function (props: Props) {
                                 onSignInError={err => createErrorNotification(err.message)}
                                 onSignInSuccess={props.onAuthenticationSuccess} />
                         </Route>
+                        <Route path={SecondFactorRecoveryCodeRoute} exact>
+                            <RecoveryCodeMethod
+                                id="recovery-code-method"
+                                authenticationLevel={props.authenticationLevel}
+                                onGenerateClick={initiateRegistration(initiateRecoveryCodesGenerationProcess)}
+                                onSignInError={err => createErrorNotification(err.message)}
+                                onSignInSuccess={props.onAuthenticationSuccess} />
+                        </Route>
                         <Route path={SecondFactorRoute}>
                             <Redirect to={SecondFactorTOTPRoute} />
                         </Route>
                     </Switch>
                 </Grid>
+                {location.pathname !== SecondFactorRecoveryCodeRoute
+                    ? <Grid item xs={12}>
+                        <Link component="button" id="recovery-code-link" onClick={handleRecoveryCodeClick}>
+                            Lost your devices? Use a recovery code
+                        </Link>
+                    </Grid>
+                    : null}
             </Grid>
         </LoginLayout>
     )
@@ -1,8 +1,7 @@
 import React, { useCallback, useEffect, useState, Fragment } from "react";
 import MethodContainer, { State as MethodContainerState } from "./MethodContainer";
 import { makeStyles, Button, useTheme } from "@material-ui/core";
-import { initiateU2FSignin, completeU2FSignin } from "../../../services/SecurityKey";
-import u2fApi from "u2f-api";
+import { initiateWebauthnSignIn, completeWebauthnSignIn } from "../../../services/Webauthn";
 import { useRedirectionURL } from "../../../hooks/RedirectionURL";
 import { useIsMountedRef } from "../../../hooks/Mounted";
 import { useTimer } from "../../../hooks/Timer";
@@ -50,24 +49,13 @@ export default function (props: Props) {
         try {
             triggerTimer();
             setState(State.WaitTouch);
-            const signRequest = await initiateU2FSignin();
-            const signRequests: u2fApi.SignRequest[] = [];
-            for (var i in signRequest.registeredKeys) {
-                const r = signRequest.registeredKeys[i];
-                signRequests.push({
-                    appId: signRequest.appId,
-                    challenge: signRequest.challenge,
-                    keyHandle: r.keyHandle,
-                    version: r.version,
-                })
-            }
-            const signResponse = await u2fApi.sign(signRequests, signInTimeout);
+            const credential = await initiateWebauthnSignIn();
             // If the request was initiated and the user changed 2FA method in the meantime,
             // the process is interrupted to avoid updating state of unmounted component.
             if (!mounted.current) return;
 
             setState(State.SigninInProgress);
-            const res = await completeU2FSignin(signResponse, redirectionURL);
+            const res = await completeWebauthnSignIn(credential, redirectionURL);
             onSignInSuccessCallback(res ? res.redirect : undefined);
         } catch (err) {
             // If the request was initiated and the user changed 2FA method in the meantime,